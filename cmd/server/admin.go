@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+	"github.com/charmbracelet/keygen"
+)
+
+const adminUsage = `usage: server admin [--config file] <command> [args]
+
+commands:
+  list [--json]             list every user with level, streak, quest count and last active day
+  audit <user> [n]          print the last n audit events for user (default 20)
+  reset-password <user>     set a temporary password; user must change it at next login
+  edit <user>               edit the user's record as JSON in $EDITOR; saved only if valid
+                            (stop the server first, or its next save may overwrite the edit)
+  export-leaderboard <file> [n]
+                            write the top n hunters (default 100) to file as JSON for a web page
+  rotate-host-key [type]    replace the SSH host key (ed25519, rsa or ecdsa; default ed25519)
+                            with a new one, keeping a backup of the old key`
+
+// runAdmin handles "server admin ..." maintenance commands against DataDir
+func runAdmin(args []string) error {
+	// Same data directory and settings as the server: --config (or
+	// SYSTEM_CONFIG) and the environment
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configPath := fs.String("config", os.Getenv("SYSTEM_CONFIG"), "")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%v\n\n%s", err, adminUsage)
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("%s", adminUsage)
+	}
+	cfg, err := loadConfig(*configPath, os.Getenv)
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	cfg.apply()
+	// Admin commands may run before the server ever has; find users either way
+	if _, err := store.MigrateFlatLayout(); err != nil {
+		return fmt.Errorf("migrating data directory: %w", err)
+	}
+	if _, err := store.MigrateUsernames(); err != nil {
+		return fmt.Errorf("normalizing usernames: %w", err)
+	}
+	switch args[0] {
+	case "list":
+		return adminList(args[1:])
+	case "audit":
+		return adminAudit(args[1:])
+	case "reset-password":
+		return adminResetPassword(args[1:])
+	case "edit":
+		return adminEdit(args[1:])
+	case "export-leaderboard":
+		return adminExportLeaderboard(args[1:])
+	case "rotate-host-key":
+		return adminRotateHostKey(args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(adminUsage)
+		return nil
+	default:
+		return fmt.Errorf("unknown admin command %q\n\n%s", args[0], adminUsage)
+	}
+}
+
+// adminList prints a summary of every user, as a table or with --json as a
+// JSON array for scripts
+func adminList(args []string) error {
+	asJSON := false
+	for _, arg := range args {
+		if arg != "--json" {
+			return fmt.Errorf("usage: server admin list [--json]")
+		}
+		asJSON = true
+	}
+	users, err := store.UserSummaries()
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(users)
+	}
+	if len(users) == 0 {
+		fmt.Fprintln(os.Stderr, "no users")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "USER\tLEVEL\tSTREAK\tQUESTS\tDONE TODAY\tLAST ACTIVE")
+	for _, u := range users {
+		last := u.LastActive
+		if last == "" {
+			last = "never"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\n", u.Username, u.Level, u.CurrentStreak, u.Habits, u.QuestsToday, last)
+	}
+	return tw.Flush()
+}
+
+// adminExportLeaderboard writes the published leaderboard file once, for a
+// cron job or a first deploy
+func adminExportLeaderboard(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: server admin export-leaderboard <file> [n]")
+	}
+	n := leaderboardFileTop
+	if len(args) == 2 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 1 {
+			return fmt.Errorf("n must be a positive number, got %q", args[1])
+		}
+		n = v
+	}
+	if err := store.WriteLeaderboard(args[0], n); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote leaderboard to %s\n", args[0])
+	return nil
+}
+
+func adminAudit(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: server admin audit <user> [n]")
+	}
+	n := 20
+	if len(args) == 2 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 1 {
+			return fmt.Errorf("n must be a positive number, got %q", args[1])
+		}
+		n = v
+	}
+	if !store.UserExists(store.NormalizeUsername(args[0])) {
+		return fmt.Errorf("unknown user %q", args[0])
+	}
+	events, err := store.ReadAudit(args[0], n)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		fmt.Fprintln(os.Stderr, "no audit events")
+		return nil
+	}
+	for _, ev := range events {
+		fmt.Println(formatAuditEvent(ev))
+	}
+	return nil
+}
+
+func adminResetPassword(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: server admin reset-password <user>")
+	}
+	if !store.UserExists(store.NormalizeUsername(args[0])) {
+		return fmt.Errorf("unknown user %q", args[0])
+	}
+	temp, err := store.ResetPassword(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("temporary password for %s: %s\n", store.NormalizeUsername(args[0]), temp)
+	fmt.Fprintln(os.Stderr, "it is shown only once; the user must choose a new password at next login")
+	return nil
+}
+
+// adminEdit opens the user's record in $EDITOR (vi if unset) and writes it
+// back only if it still parses and validates and the username is unchanged
+func adminEdit(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: server admin edit <user>")
+	}
+	username := store.NormalizeUsername(args[0])
+	u, err := store.LoadUser(username)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("unknown user %q", args[0])
+		}
+		return err
+	}
+	original, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", "system-"+username+"-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(original)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor: %w", err)
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(bytes.TrimSpace(edited), bytes.TrimSpace(original)) {
+		fmt.Fprintln(os.Stderr, "no changes")
+		return nil
+	}
+	nu, err := store.ParseUser(edited)
+	if err != nil {
+		return fmt.Errorf("edit rejected, %s left unchanged:\n%w", username, err)
+	}
+	if nu.Username != u.Username {
+		return fmt.Errorf("edit rejected, %s left unchanged: username cannot be changed", username)
+	}
+	if err := store.ReplaceUser(nu); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "saved %s\n", username)
+	return nil
+}
+
+// adminRotateHostKey replaces a host key and explains what clients will see
+func adminRotateHostKey(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: server admin rotate-host-key [ed25519|rsa|ecdsa]")
+	}
+	t := keygen.Ed25519
+	if len(args) == 1 {
+		types, err := parseHostKeyTypes(args[0])
+		if err != nil {
+			return err
+		}
+		t = types[0]
+	}
+	if l, err := net.Listen("tcp", sshAddr); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s is in use, so the server is probably running; it keeps serving the old key until it restarts\n", sshAddr)
+	} else {
+		l.Close()
+	}
+	res, err := rotateHostKey("", t, time.Now())
+	if err != nil {
+		return err
+	}
+	if res.Backup != "" && res.OldFingerprint == "" {
+		fmt.Printf("old %s host key (unreadable) backed up to %s\n", t, res.Backup)
+	} else if res.Backup != "" {
+		fmt.Printf("old %s host key %s backed up to %s\n", t, res.OldFingerprint, res.Backup)
+	}
+	fmt.Printf("new %s host key %s written to %s\n", t, res.NewFingerprint, res.Path)
+	fmt.Fprintln(os.Stderr, "restart the server to use it; clients that connected before will get a")
+	fmt.Fprintln(os.Stderr, "\"REMOTE HOST IDENTIFICATION HAS CHANGED\" warning until they remove the old")
+	fmt.Fprintln(os.Stderr, "key, e.g. with: ssh-keygen -R '[host]:port'")
+	return nil
+}
+
+// formatAuditEvent renders an event as one human-readable line
+func formatAuditEvent(ev store.AuditEvent) string {
+	parts := []string{ev.Time.Format("2006-01-02 15:04:05"), fmt.Sprintf("%-13s", ev.Type)}
+	if ev.Habit != "" {
+		parts = append(parts, fmt.Sprintf("%q", ev.Habit))
+	}
+	if ev.Day != "" {
+		parts = append(parts, "day="+ev.Day)
+	}
+	if ev.EXP != 0 {
+		parts = append(parts, fmt.Sprintf("exp=%d", ev.EXP))
+	}
+	if ev.Level != 0 {
+		parts = append(parts, fmt.Sprintf("level=%d", ev.Level))
+	}
+	if ev.Type == store.AuditStreak {
+		parts = append(parts, fmt.Sprintf("streak=%d", ev.Streak))
+	}
+	if ev.Detail != "" {
+		parts = append(parts, ev.Detail)
+	}
+	return strings.Join(parts, "  ")
+}
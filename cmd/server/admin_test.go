@@ -0,0 +1,220 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// adminConfig writes a config file pointing data_dir at a new directory and
+// restores the server's data directory when the test ends
+func adminConfig(t *testing.T) (path, dataDir string) {
+	t.Helper()
+	waitForAudit()
+	old := store.DataDir
+	t.Cleanup(func() {
+		waitForAudit()
+		store.DataDir = old
+	})
+	dir := t.TempDir()
+	dataDir = filepath.Join(dir, "data")
+	path = filepath.Join(dir, "system.yaml")
+	if err := os.WriteFile(path, []byte("data_dir: "+dataDir+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, dataDir
+}
+
+// waitForAudit returns once every queued audit event is written; ReadAudit
+// flushes the writer first. The writer reads store.DataDir, so tests that
+// change it wait on both sides.
+func waitForAudit() {
+	_, _ = store.ReadAudit("nobody", 1)
+}
+
+// adminUser saves a hunter with a valid record in the data directory
+func adminUser(t *testing.T, username string) *store.UserData {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("Correct-horse-9"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &store.UserData{
+		Username:         username,
+		PasswordHash:     string(hash),
+		Level:            store.DefaultLevel,
+		DailyCompletions: make(map[string]map[string]bool),
+		Keymap:           store.KeymapDefault,
+		Theme:            store.ThemeSystemBlue,
+		SortMode:         store.SortManual,
+		CompleteKey:      store.CompleteKeySpace,
+		Habits:           []store.Habit{{ID: "h_1", Name: "read", Icon: "📖"}},
+	}
+	if err := store.SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// captureStdout returns what f prints to standard output
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+	ferr := f()
+	os.Stdout = old
+	w.Close()
+	return <-out, ferr
+}
+
+func TestRunAdminConfig(t *testing.T) {
+	path, dataDir := adminConfig(t)
+	tests := []struct {
+		name string
+		args []string
+		env  string
+		err  string // substring of the error; "" for none
+	}{
+		{name: "flag", args: []string{"--config", path, "help"}},
+		{name: "flag with =", args: []string{"--config=" + path, "help"}},
+		{name: "environment", args: []string{"help"}, env: path},
+		{name: "no command", args: []string{"--config", path}, err: "usage:"},
+		{name: "unknown flag", args: []string{"--data", "x", "help"}, err: "usage:"},
+		{name: "unknown command", args: []string{"--config", path, "frobnicate"}, err: "unknown admin command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SYSTEM_CONFIG", tt.env)
+			store.DataDir = "unset"
+			err := runAdmin(tt.args)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("runAdmin(%q): %v", tt.args, err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("runAdmin(%q) error = %v, want one mentioning %q", tt.args, err, tt.err)
+			case tt.err == "" && store.DataDir != dataDir:
+				t.Errorf("data dir = %q, want %q from the config file", store.DataDir, dataDir)
+			}
+		})
+	}
+}
+
+func TestRunAdminMigratesUsernames(t *testing.T) {
+	path, dataDir := adminConfig(t)
+	// "café" with a combining accent, saved where versions before NFC
+	// usernames put it: the shard named by the hash of the raw name
+	raw := "cafe\u0301"
+	sum := sha256.Sum256([]byte(raw))
+	dir := filepath.Join(dataDir, hex.EncodeToString(sum[:])[:2])
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	record := `{"username":"` + raw + `","level":1,"daily_completions":{}}`
+	if err := os.WriteFile(filepath.Join(dir, raw+".json"), []byte(record), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runAdmin([]string{"--config", path, "help"}); err != nil {
+		t.Fatal(err)
+	}
+	if !store.UserExists("caf\u00e9") {
+		t.Error("user saved under a non-NFC name not found after an admin command")
+	}
+}
+
+func TestAdminList(t *testing.T) {
+	path, dataDir := adminConfig(t)
+	store.DataDir = dataDir
+	adminUser(t, "lister")
+	tests := []struct {
+		name string
+		args []string
+		want string // substring of the output
+		err  bool
+	}{
+		{name: "table", args: []string{"list"}, want: "lister"},
+		{name: "json", args: []string{"list", "--json"}, want: `"username": "lister"`},
+		{name: "unknown flag", args: []string{"list", "--yaml"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := captureStdout(t, func() error { return runAdmin(append([]string{"--config", path}, tt.args...)) })
+			if (err != nil) != tt.err {
+				t.Fatalf("runAdmin(%q) error = %v, want error %v", tt.args, err, tt.err)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("runAdmin(%q) printed %q, want %q", tt.args, out, tt.want)
+			}
+		})
+	}
+
+	out, err := captureStdout(t, func() error { return runAdmin([]string{"--config", path, "list", "--json"}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users []store.UserSummary
+	if err := json.Unmarshal([]byte(out), &users); err != nil || len(users) != 1 || users[0].Habits != 1 {
+		t.Errorf("list --json = %+v, %v", users, err)
+	}
+}
+
+func TestAdminEdit(t *testing.T) {
+	path, dataDir := adminConfig(t)
+	tests := []struct {
+		name   string
+		editor string // run on the record file, split at spaces like $EDITOR
+		err    string // substring of the error; "" for none
+		hour   int    // the saved day_reset_hour afterwards
+	}{
+		{name: "unchanged", editor: "true"},
+		{name: "valid", editor: `sed -i s/"day_reset_hour":\s0/"day_reset_hour":6/`, hour: 6},
+		{name: "invalid", editor: `sed -i s/"day_reset_hour":\s0/"day_reset_hour":30/`, err: "day_reset_hour 30"},
+		{name: "level without the EXP", editor: `sed -i s/"level":\s1,/"level":9,/`, err: "level 9"},
+		{name: "renamed", editor: `sed -i s/"username":\s"editee"/"username":"other"/`, err: "username cannot be changed"},
+		{name: "not JSON", editor: "sed -i s/{/[/", err: "edit rejected"},
+		{name: "unknown field", editor: `sed -i s/"level":/"lvl":/`, err: "lvl"},
+		{name: "editor failed", editor: "false", err: "editor:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("EDITOR", tt.editor)
+			store.DataDir = dataDir
+			adminUser(t, "editee")
+			err := runAdmin([]string{"--config", path, "edit", "editee"})
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatal(err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("edit error = %v, want one mentioning %q", err, tt.err)
+			}
+			saved, err := store.LoadUser("editee")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved.DayResetHour != tt.hour || saved.Level != store.DefaultLevel {
+				t.Errorf("saved reset hour %d level %d, want %d and %d", saved.DayResetHour, saved.Level, tt.hour, store.DefaultLevel)
+			}
+		})
+	}
+	if err := runAdmin([]string{"--config", path, "edit", "nobody"}); err == nil || !strings.Contains(err.Error(), "unknown user") {
+		t.Errorf("editing a missing user: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// showLoginBanner puts server-wide stats on the login screen. Set by
+// SYSTEM_LOGIN_BANNER; off by default.
+var showLoginBanner = false
+
+// bannerMaxAge is how long the banner's stats are reused before a rescan
+const bannerMaxAge = time.Minute
+
+// loginBanner caches the banner text. Scans run in the background, so a
+// login screen never waits on one; it shows the last result, or nothing
+// before the first scan finishes.
+var loginBanner = &bannerCache{}
+
+type bannerCache struct {
+	mu       sync.Mutex
+	text     string
+	at       time.Time
+	scanning bool
+}
+
+// get returns the cached banner and starts a rescan if it's stale
+func (c *bannerCache) get() string {
+	if !showLoginBanner {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.at) > bannerMaxAge && !c.scanning {
+		c.scanning = true
+		go c.refresh()
+	}
+	return c.text
+}
+
+func (c *bannerCache) refresh() {
+	st, err := store.ComputeServerStats()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scanning = false
+	c.at = time.Now() // on failure too, so a broken data dir isn't rescanned per connection
+	if err != nil {
+		log.Printf("login banner: %v", err)
+		return
+	}
+	c.text = bannerText(st)
+}
+
+// bannerText formats st as "142 hunters • 3,201 quests completed today • top level 58"
+func bannerText(st store.ServerStats) string {
+	if st.Hunters == 0 {
+		return ""
+	}
+	hunters := "hunters"
+	if st.Hunters == 1 {
+		hunters = "hunter"
+	}
+	quests := "quests"
+	if st.QuestsToday == 1 {
+		quests = "quest"
+	}
+	return fmt.Sprintf("%s %s • %s %s completed today • top level %d",
+		thousands(st.Hunters), hunters, thousands(st.QuestsToday), quests, st.TopLevel)
+}
+
+// thousands formats n with comma separators, e.g. 3201 as "3,201"
+func thousands(n int) string {
+	if n < 0 {
+		return "-" + thousands(-n)
+	}
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+func TestBannerText(t *testing.T) {
+	tests := []struct {
+		st   store.ServerStats
+		want string
+	}{
+		{store.ServerStats{}, ""},
+		{store.ServerStats{Hunters: 1, QuestsToday: 1, TopLevel: 3}, "1 hunter • 1 quest completed today • top level 3"},
+		{store.ServerStats{Hunters: 142, QuestsToday: 3201, TopLevel: 58}, "142 hunters • 3,201 quests completed today • top level 58"},
+		{store.ServerStats{Hunters: 1234567, TopLevel: 1}, "1,234,567 hunters • 0 quests completed today • top level 1"},
+	}
+	for _, tt := range tests {
+		if got := bannerText(tt.st); got != tt.want {
+			t.Errorf("bannerText(%+v) = %q, want %q", tt.st, got, tt.want)
+		}
+	}
+	if got := thousands(-4500); got != "-4,500" {
+		t.Errorf("thousands(-4500) = %q", got)
+	}
+}
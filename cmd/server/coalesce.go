@@ -0,0 +1,70 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// navCoalesceWindow is roughly one frame. A held navigation key repeats
+// slower than this, so repeats only arrive closer together when they queued
+// up behind a slow render or a laggy link; those are dropped so the cursor
+// stops where the key was released instead of running on.
+const navCoalesceWindow = 16 * time.Millisecond
+
+// navCoalescer is a tea.WithFilter filter that drops a navigation key when
+// it repeats the previous one within navCoalesceWindow. Each session gets
+// its own; the filter only runs on the program's event loop.
+type navCoalescer struct {
+	last string    // direction of the last navigation key let through
+	at   time.Time // when it was let through
+	now  func() time.Time
+}
+
+func newNavCoalescer() *navCoalescer {
+	return &navCoalescer{now: time.Now}
+}
+
+// filter passes every message through except a queued-up repeat of the
+// last navigation key. Keys typed into a text field are never dropped.
+func (c *navCoalescer) filter(tm tea.Model, msg tea.Msg) tea.Msg {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return msg
+	}
+	m, ok := tm.(model)
+	if !ok || m.typing() {
+		c.last = ""
+		return msg
+	}
+	dir := navKey(m.keymap, key.String())
+	switch dir {
+	case "up", "down", "left", "right":
+	default:
+		c.last = ""
+		return msg
+	}
+	now := c.now()
+	if dir == c.last && now.Sub(c.at) < navCoalesceWindow {
+		return nil
+	}
+	c.last, c.at = dir, now
+	return msg
+}
+
+// typing reports whether keys go into a text field, where every key counts
+func (m model) typing() bool {
+	switch m.authState {
+	case authLogin, authRegister, authNewPass:
+		return true
+	case authMain:
+		return m.addingHabit != nil
+	case authCompare:
+		return m.compareWith == nil
+	case authTokens:
+		return m.tokenLabel != nil
+	case authReset:
+		return true
+	}
+	return false
+}
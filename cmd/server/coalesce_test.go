@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+func TestNavCoalescer(t *testing.T) {
+	mainScreen := model{authState: authMain, keymap: store.KeymapDefault}
+	form := mainScreen
+	name := ""
+	form.addingHabit = &name
+
+	type keyAt struct {
+		key string
+		at  time.Duration // since the first key
+	}
+	tests := []struct {
+		name string
+		m    model
+		keys []keyAt
+		want []bool // whether each key is let through
+	}{
+		{"repeat in a frame", mainScreen, []keyAt{{"down", 0}, {"down", 5 * time.Millisecond}}, []bool{true, false}},
+		{"repeat after a frame", mainScreen, []keyAt{{"down", 0}, {"down", 30 * time.Millisecond}}, []bool{true, true}},
+		{"vim and arrow are one direction", mainScreen, []keyAt{{"j", 0}, {"down", time.Millisecond}}, []bool{true, false}},
+		{"direction change", mainScreen, []keyAt{{"down", 0}, {"up", time.Millisecond}}, []bool{true, true}},
+		{"other key in between", mainScreen, []keyAt{{"down", 0}, {" ", time.Millisecond}, {"down", 2 * time.Millisecond}}, []bool{true, true, true}},
+		{"typing", form, []keyAt{{"j", 0}, {"j", time.Millisecond}}, []bool{true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			var now time.Time
+			c := newNavCoalescer()
+			c.now = func() time.Time { return now }
+			for i, k := range tt.keys {
+				now = start.Add(k.at)
+				passed := c.filter(tt.m, keyMsg(k.key)) != nil
+				if passed != tt.want[i] {
+					t.Errorf("key %d (%q) let through = %v, want %v", i, k.key, passed, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if msg := newNavCoalescer().filter(mainScreen, tea.WindowSizeMsg{}); msg == nil {
+		t.Error("a non-key message was dropped")
+	}
+}
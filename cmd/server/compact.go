@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// compactHeight is the terminal height below which the main screen drops
+// the status box for a single status line and a bare quest list
+const compactHeight = 20
+
+// compact reports whether the main screen should use the compact layout.
+// An unknown height (0) keeps the full layout.
+func (m model) compact() bool {
+	return m.height > 0 && m.height < compactHeight
+}
+
+// compactStatus formats the one-line status, e.g. "Lv7 E-Rank 3/5 ✔ 12🔥"
+func (m model) compactStatus() string {
+	u := m.userData
+	rank, _ := hunterRank(u.Level)
+	done := 0
+	for _, h := range u.Habits {
+		if u.CompletedToday(h.ID) {
+			done++
+		}
+	}
+	s := fmt.Sprintf("Lv%d %s %d/%d ✔", u.Level, rank, done, len(u.Habits))
+	if u.CurrentStreak > 0 {
+		s += fmt.Sprintf(" %d🔥", u.CurrentStreak)
+	}
+	return s
+}
+
+// compactHeader is how many lines the compact layout shows above the quest
+// list: the status line and, if there is one, the toast or prompt
+func (m model) compactHeader() int {
+	if m.confirmPrestige || m.lastToast != "" {
+		return 2
+	}
+	return 1
+}
+
+// compactWindow returns the range of count quests that the compact layout
+// shows around the cursor below header lines
+func (m model) compactWindow(header, count int) (start, end int) {
+	rows := max(m.height-header-1, 1)
+	if m.cursor >= rows {
+		start = m.cursor - rows + 1
+	}
+	return start, min(start+rows, count)
+}
+
+// renderCompact draws the main screen for short terminals: a status line,
+// as many quests as fit around the cursor, and a one-line key hint. Every
+// line is cut to the terminal width so nothing wraps.
+func (m model) renderCompact(accent, dim, reward, errStyle, toastStyle lipgloss.Style) string {
+	u := m.userData
+	fit := func(s string) string {
+		if m.width <= 0 {
+			return s
+		}
+		return truncateToWidth(s, m.width)
+	}
+
+	toastStyle = toastStyle.UnsetPadding() // the padding would push a full-width toast past the edge
+	lines := []string{accent.Render(fit(m.compactStatus()))}
+	if m.confirmPrestige {
+		lines = append(lines, toastStyle.Render(fit("▶ PRESTIGE? [y/n]")))
+	} else if m.lastToast != "" {
+		lines = append(lines, toastStyle.Render(fit("▶ "+m.lastToast)))
+	}
+
+	habits := u.SortedHabits()
+	start, end := m.compactWindow(len(lines), len(habits))
+	if len(habits) == 0 {
+		lines = append(lines, dim.Render(fit("No quests. Press [a] to add.")))
+	}
+	timeUntil := u.TimeUntilReset()
+	quiet := u.InQuietHours(time.Now())
+	spot, _ := u.Spotlight()
+	for i := start; i < end; i++ {
+		h := habits[i]
+		arrow := "  "
+		if m.cursor == i {
+			arrow = "▸ "
+		}
+		mark, style := "·", dim
+		switch classifyQuest(u.CompletedToday(h.ID), u.WeeklyOnTrack(h.ID), timeUntil, quiet) {
+		case questDone:
+			mark, style = "✓", reward
+		case questUrgent:
+			mark, style = "!", errStyle
+		}
+		name := h.Name
+		if u.ChainLocked(h.ID, u.TodayKey()) {
+			name = "🔒 " + name
+		}
+		if h.ID == spot.ID {
+			name = "⭐ " + name
+		}
+		if h.ID == m.focusHabitID {
+			name = "⏱ " + focusClock(m.focusLeft(time.Now())) + " " + name
+		}
+		line := fit(arrow + mark + " " + h.Icon + " " + name)
+		if m.cursor == i {
+			style = accent
+		}
+		lines = append(lines, style.Render(line))
+	}
+
+	complete, _ := questKeyHint(u.CompleteKey)
+	lines = append(lines, dim.Render(fit(fmt.Sprintf("[%s] done [↑/↓] move [s] settings [q] quit", complete))))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestCompactWindow(t *testing.T) {
+	tests := []struct {
+		height, cursor, header, count int
+		start, end                    int
+	}{
+		{height: 10, cursor: 0, header: 1, count: 3, start: 0, end: 3},
+		{height: 10, cursor: 0, header: 1, count: 20, start: 0, end: 8},
+		{height: 10, cursor: 7, header: 1, count: 20, start: 0, end: 8},
+		{height: 10, cursor: 8, header: 1, count: 20, start: 1, end: 9},
+		{height: 10, cursor: 19, header: 2, count: 20, start: 13, end: 20},
+		{height: 2, cursor: 5, header: 2, count: 20, start: 5, end: 6}, // always one row
+	}
+	for _, tt := range tests {
+		m := model{height: tt.height, cursor: tt.cursor}
+		if start, end := m.compactWindow(tt.header, tt.count); start != tt.start || end != tt.end {
+			t.Errorf("height %d cursor %d: compactWindow(%d, %d) = %d, %d; want %d, %d",
+				tt.height, tt.cursor, tt.header, tt.count, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestRenderCompact(t *testing.T) {
+	u := newTestUser(t, "read", "run", "write a very long quest name that will not fit", "stretch")
+	tests := []struct {
+		name   string
+		width  int
+		height int
+	}{
+		{"short", 40, 6},
+		{"narrow", 24, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(u)
+			m.width, m.height = tt.width, tt.height
+			if !m.compact() {
+				t.Fatalf("%dx%d not compact", tt.width, tt.height)
+			}
+			s := lipgloss.NewStyle()
+			out := m.renderCompact(s, s, s, s, s)
+			lines := strings.Split(out, "\n")
+			if len(lines) > tt.height {
+				t.Errorf("%d lines on a %d-line terminal:\n%s", len(lines), tt.height, out)
+			}
+			for _, line := range lines {
+				if w := lipgloss.Width(line); w > tt.width {
+					t.Errorf("line %q is %d cells on a %d-cell terminal", line, w, tt.width)
+				}
+			}
+		})
+	}
+}
+
+func TestCompactUnknownHeight(t *testing.T) {
+	m := newTestModel(newTestUser(t, "read"))
+	m.width, m.height = 80, 0
+	if m.compact() {
+		t.Error("an unknown height switched to the compact layout")
+	}
+}
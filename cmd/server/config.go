@@ -0,0 +1,381 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/abhigyan-mohanta/system/internal/gemini"
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// Config is everything the server can be configured with. Values come from,
+// lowest priority first: the defaults, a YAML file (--config or
+// SYSTEM_CONFIG), environment variables, then explicitly set flags. AI API
+// keys stay in the environment and aren't part of it.
+type Config struct {
+	SSHAddr    string `yaml:"ssh_addr"`    // SYSTEM_SSH_ADDR
+	HTTPAddr   string `yaml:"http_addr"`   // -http; empty disables the JSON API
+	HealthAddr string `yaml:"health_addr"` // -health; empty disables the health check
+	HostKeys   string `yaml:"host_keys"`   // -host-keys, comma-separated
+	DataDir    string `yaml:"data_dir"`    // SYSTEM_DATA_DIR
+	DataRoot   string `yaml:"data_root"`   // SYSTEM_DATA_ROOT; empty allows any location
+
+	RegenerateBadHostKeys bool `yaml:"regenerate_bad_host_keys"` // SYSTEM_REGENERATE_BAD_HOST_KEYS or -regenerate-bad-host-keys
+
+	LevelCap          int    `yaml:"level_cap"`           // SYSTEM_LEVEL_CAP; 0 for none
+	MaxHabits         int    `yaml:"max_habits"`          // SYSTEM_MAX_HABITS; 0 for none
+	AllowRegister     bool   `yaml:"allow_register"`      // SYSTEM_ALLOW_REGISTER
+	RevealLoginErrors bool   `yaml:"reveal_login_errors"` // SYSTEM_REVEAL_LOGIN_ERRORS
+	LoginBanner       bool   `yaml:"login_banner"`        // SYSTEM_LOGIN_BANNER
+	WelcomeQuest      bool   `yaml:"welcome_quest"`       // SYSTEM_WELCOME_QUEST
+	DemoUser          string `yaml:"demo_user"`           // SYSTEM_DEMO_USER
+	HelpFile          string `yaml:"help_file"`           // SYSTEM_HELP_FILE; empty shows the built-in help
+	UncheckPolicy     string `yaml:"uncheck_policy"`      // SYSTEM_UNCHECK_POLICY: refund, no-refund or locked
+	NoBell            bool   `yaml:"no_bell"`             // SYSTEM_NO_BELL
+	SSHAuth           bool   `yaml:"ssh_auth"`            // SYSTEM_SSH_AUTH
+
+	SaveDebounce    time.Duration `yaml:"save_debounce"`     // SYSTEM_SAVE_DEBOUNCE
+	Snooze          time.Duration `yaml:"snooze"`            // SYSTEM_SNOOZE
+	Focus           time.Duration `yaml:"focus"`             // SYSTEM_FOCUS
+	Toast           time.Duration `yaml:"toast"`             // SYSTEM_TOAST; 0 keeps toasts until a key
+	UncheckGrace    time.Duration `yaml:"uncheck_grace"`     // SYSTEM_UNCHECK_GRACE; 0 = no grace
+	AutoArchiveDays int           `yaml:"auto_archive_days"` // SYSTEM_AUTO_ARCHIVE_DAYS; 0 is off
+
+	Passwords   PasswordConfig    `yaml:"passwords"`
+	Lockout     LockoutConfig     `yaml:"lockout"`
+	Leaderboard LeaderboardConfig `yaml:"leaderboard"`
+	Season      SeasonConfig      `yaml:"season"`
+	AI          AIConfig          `yaml:"ai"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+}
+
+// PasswordConfig is the policy for new and changed passwords
+type PasswordConfig struct {
+	MinLength   int  `yaml:"min_length"`   // SYSTEM_PASSWORD_MIN_LENGTH
+	MinClasses  int  `yaml:"min_classes"`  // SYSTEM_PASSWORD_MIN_CLASSES
+	BlockCommon bool `yaml:"block_common"` // SYSTEM_PASSWORD_BLOCK_COMMON
+}
+
+// LockoutConfig locks a username after repeated failed logins
+type LockoutConfig struct {
+	Attempts int           `yaml:"attempts"` // SYSTEM_LOCKOUT_ATTEMPTS; 0 is off
+	Window   time.Duration `yaml:"window"`   // SYSTEM_LOCKOUT_WINDOW
+	Duration time.Duration `yaml:"duration"` // SYSTEM_LOCKOUT_FOR
+}
+
+// LeaderboardConfig controls ranking and the published leaderboard file
+type LeaderboardConfig struct {
+	Integrity bool          `yaml:"integrity"` // SYSTEM_LEADERBOARD_INTEGRITY
+	File      string        `yaml:"file"`      // SYSTEM_LEADERBOARD_FILE; empty publishes nothing
+	Every     time.Duration `yaml:"every"`     // SYSTEM_LEADERBOARD_EVERY
+}
+
+// SeasonConfig splits the leaderboard into seasons; levels and EXP start
+// over at each boundary
+type SeasonConfig struct {
+	Start string `yaml:"start"` // SYSTEM_SEASON_START, a YYYY-MM-DD date; empty is off
+	Days  int    `yaml:"days"`  // SYSTEM_SEASON_DAYS; 0 makes start a one-off boundary
+}
+
+// AIConfig picks who allocates stats on level-up
+type AIConfig struct {
+	Provider string        `yaml:"provider"` // SYSTEM_AI_PROVIDER
+	Timeout  time.Duration `yaml:"timeout"`  // GEMINI_TIMEOUT
+}
+
+// WebhooksConfig POSTs a small JSON payload to each target when one of its
+// events happens: level_up, milestone or day_complete
+type WebhooksConfig struct {
+	Timeout time.Duration   `yaml:"timeout"` // SYSTEM_WEBHOOK_TIMEOUT, per attempt
+	Targets []WebhookTarget `yaml:"targets"` // config file only
+}
+
+// WebhookTarget is one webhook URL and the events it's sent
+type WebhookTarget struct {
+	URL    string   `yaml:"url"`
+	Events []string `yaml:"events"` // empty sends every event
+}
+
+// defaultConfig is the zero-config setup: the package defaults, unchanged
+func defaultConfig() Config {
+	return Config{
+		SSHAddr:           sshAddr,
+		HostKeys:          "ed25519",
+		DataDir:           store.DataDir,
+		DataRoot:          store.DataRoot,
+		LevelCap:          store.LevelCap,
+		MaxHabits:         store.MaxHabits,
+		AllowRegister:     store.AllowRegister,
+		RevealLoginErrors: store.RevealLoginErrors,
+		LoginBanner:       showLoginBanner,
+		WelcomeQuest:      store.WelcomeQuestEnabled,
+		DemoUser:          store.DemoUser,
+		HelpFile:          helpFile,
+		UncheckPolicy:     store.UncheckPolicy,
+		NoBell:            noBell,
+		SSHAuth:           sshAuth,
+		SaveDebounce:      saveDebounce,
+		Snooze:            snoozeFor,
+		Focus:             focusFor,
+		Toast:             toastFor,
+		UncheckGrace:      store.UncheckGrace,
+		AutoArchiveDays:   autoArchiveDays,
+		Passwords: PasswordConfig{
+			MinLength:   store.PasswordRules.MinLength,
+			MinClasses:  max(store.PasswordRules.MinClasses, 1), // 0 and 1 both ask for one class
+			BlockCommon: store.PasswordRules.BlockCommon,
+		},
+		Lockout: LockoutConfig{
+			Attempts: store.LockoutAttempts,
+			Window:   store.LockoutWindow,
+			Duration: store.LockoutFor,
+		},
+		Leaderboard: LeaderboardConfig{
+			Integrity: store.LeaderboardIntegrity,
+			File:      leaderboardFile,
+			Every:     leaderboardEvery,
+		},
+		Season:   SeasonConfig{Days: store.SeasonDays},
+		AI:       AIConfig{Provider: "gemini", Timeout: gemini.Timeout},
+		Webhooks: WebhooksConfig{Timeout: webhookTimeout},
+	}
+}
+
+// loadConfig builds the config from the defaults, the YAML file at path (if
+// any) and the environment, and validates the result. Flags are applied by
+// the caller with applyFlags, which validates again.
+func loadConfig(path string, getenv func(string) string) (Config, error) {
+	cfg := defaultConfig()
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return cfg, fmt.Errorf("config: %w", err)
+		}
+		defer f.Close()
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true) // a misspelled key is an error, not a silent default
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	env := envReader{getenv: getenv}
+	env.str("SYSTEM_SSH_ADDR", &cfg.SSHAddr)
+	env.str("SYSTEM_DATA_DIR", &cfg.DataDir)
+	env.str("SYSTEM_DATA_ROOT", &cfg.DataRoot)
+	env.boolean("SYSTEM_REGENERATE_BAD_HOST_KEYS", &cfg.RegenerateBadHostKeys)
+	env.integer("SYSTEM_LEVEL_CAP", &cfg.LevelCap)
+	env.integer("SYSTEM_MAX_HABITS", &cfg.MaxHabits)
+	env.boolean("SYSTEM_ALLOW_REGISTER", &cfg.AllowRegister)
+	env.boolean("SYSTEM_REVEAL_LOGIN_ERRORS", &cfg.RevealLoginErrors)
+	env.boolean("SYSTEM_LOGIN_BANNER", &cfg.LoginBanner)
+	env.boolean("SYSTEM_WELCOME_QUEST", &cfg.WelcomeQuest)
+	env.str("SYSTEM_DEMO_USER", &cfg.DemoUser)
+	env.str("SYSTEM_HELP_FILE", &cfg.HelpFile)
+	env.set("SYSTEM_NO_BELL", &cfg.NoBell)
+	env.boolean("SYSTEM_SSH_AUTH", &cfg.SSHAuth)
+	env.duration("SYSTEM_SAVE_DEBOUNCE", &cfg.SaveDebounce)
+	env.duration("SYSTEM_SNOOZE", &cfg.Snooze)
+	env.duration("SYSTEM_FOCUS", &cfg.Focus)
+	env.duration("SYSTEM_TOAST", &cfg.Toast)
+	env.duration("SYSTEM_UNCHECK_GRACE", &cfg.UncheckGrace)
+	env.str("SYSTEM_UNCHECK_POLICY", &cfg.UncheckPolicy)
+	env.integer("SYSTEM_AUTO_ARCHIVE_DAYS", &cfg.AutoArchiveDays)
+	env.integer("SYSTEM_PASSWORD_MIN_LENGTH", &cfg.Passwords.MinLength)
+	env.integer("SYSTEM_PASSWORD_MIN_CLASSES", &cfg.Passwords.MinClasses)
+	env.set("SYSTEM_PASSWORD_BLOCK_COMMON", &cfg.Passwords.BlockCommon)
+	env.integer("SYSTEM_LOCKOUT_ATTEMPTS", &cfg.Lockout.Attempts)
+	env.duration("SYSTEM_LOCKOUT_WINDOW", &cfg.Lockout.Window)
+	env.duration("SYSTEM_LOCKOUT_FOR", &cfg.Lockout.Duration)
+	env.boolean("SYSTEM_LEADERBOARD_INTEGRITY", &cfg.Leaderboard.Integrity)
+	env.str("SYSTEM_LEADERBOARD_FILE", &cfg.Leaderboard.File)
+	env.duration("SYSTEM_LEADERBOARD_EVERY", &cfg.Leaderboard.Every)
+	env.str("SYSTEM_SEASON_START", &cfg.Season.Start)
+	env.integer("SYSTEM_SEASON_DAYS", &cfg.Season.Days)
+	env.str("SYSTEM_AI_PROVIDER", &cfg.AI.Provider)
+	env.duration("GEMINI_TIMEOUT", &cfg.AI.Timeout)
+	env.duration("SYSTEM_WEBHOOK_TIMEOUT", &cfg.Webhooks.Timeout)
+	if err := errors.Join(env.errs...); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// applyFlags overrides cfg with the flags set on the command line and
+// validates the result
+func (cfg *Config) applyFlags(fs *flag.FlagSet) error {
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "http":
+			cfg.HTTPAddr = f.Value.String()
+		case "health":
+			cfg.HealthAddr = f.Value.String()
+		case "host-keys":
+			cfg.HostKeys = f.Value.String()
+		case "regenerate-bad-host-keys":
+			cfg.RegenerateBadHostKeys = f.Value.String() == "true"
+		}
+	})
+	return cfg.validate()
+}
+
+// validate reports every out-of-range field, by its config key and
+// environment variable
+func (cfg Config) validate() error {
+	var errs []error
+	check := func(ok bool, field, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{field}, args...)...))
+		}
+	}
+	check(cfg.SSHAddr != "", "ssh_addr (SYSTEM_SSH_ADDR)", "must not be empty")
+	check(cfg.DataDir != "", "data_dir (SYSTEM_DATA_DIR)", "must not be empty")
+	if _, err := parseHostKeyTypes(cfg.HostKeys); err != nil {
+		check(false, "host_keys (-host-keys)", "%v", err)
+	}
+	check(cfg.LevelCap == 0 || cfg.LevelCap >= 2, "level_cap (SYSTEM_LEVEL_CAP)", "must be 0 (no cap) or a level of at least 2, got %d", cfg.LevelCap)
+	check(cfg.MaxHabits >= 0, "max_habits (SYSTEM_MAX_HABITS)", "must not be negative (0 = no limit), got %d", cfg.MaxHabits)
+	check(cfg.SaveDebounce >= 0, "save_debounce (SYSTEM_SAVE_DEBOUNCE)", "must not be negative, got %s", cfg.SaveDebounce)
+	check(cfg.Snooze >= time.Minute, "snooze (SYSTEM_SNOOZE)", "must be at least 1m, got %s", cfg.Snooze)
+	check(cfg.Focus >= time.Minute, "focus (SYSTEM_FOCUS)", "must be at least 1m, got %s", cfg.Focus)
+	check(cfg.Toast == 0 || cfg.Toast >= time.Second, "toast (SYSTEM_TOAST)", "must be 0 (until a key) or at least 1s, got %s", cfg.Toast)
+	check(cfg.UncheckGrace >= 0, "uncheck_grace (SYSTEM_UNCHECK_GRACE)", "must not be negative (0 = no grace), got %s", cfg.UncheckGrace)
+	check(slices.Contains(store.UncheckPolicies, cfg.UncheckPolicy), "uncheck_policy (SYSTEM_UNCHECK_POLICY)", "must be one of %s, got %q", strings.Join(store.UncheckPolicies, ", "), cfg.UncheckPolicy)
+	check(cfg.AutoArchiveDays >= 0, "auto_archive_days (SYSTEM_AUTO_ARCHIVE_DAYS)", "must be a number of days (0 = off), got %d", cfg.AutoArchiveDays)
+	check(cfg.Passwords.MinLength >= 1, "passwords.min_length (SYSTEM_PASSWORD_MIN_LENGTH)", "must be a positive number, got %d", cfg.Passwords.MinLength)
+	check(cfg.Passwords.MinClasses >= 1 && cfg.Passwords.MinClasses <= 4, "passwords.min_classes (SYSTEM_PASSWORD_MIN_CLASSES)", "must be 1-4, got %d", cfg.Passwords.MinClasses)
+	check(cfg.Lockout.Attempts >= 0, "lockout.attempts (SYSTEM_LOCKOUT_ATTEMPTS)", "must not be negative (0 = off), got %d", cfg.Lockout.Attempts)
+	check(cfg.Lockout.Window >= time.Second, "lockout.window (SYSTEM_LOCKOUT_WINDOW)", "must be at least 1s, got %s", cfg.Lockout.Window)
+	check(cfg.Lockout.Duration >= time.Second, "lockout.duration (SYSTEM_LOCKOUT_FOR)", "must be at least 1s, got %s", cfg.Lockout.Duration)
+	check(cfg.Leaderboard.Every >= 10*time.Second, "leaderboard.every (SYSTEM_LEADERBOARD_EVERY)", "must be at least 10s, got %s", cfg.Leaderboard.Every)
+	if _, err := parseSeasonStart(cfg.Season.Start); err != nil {
+		check(false, "season.start (SYSTEM_SEASON_START)", "must be a date like 2026-01-01, got %q", cfg.Season.Start)
+	}
+	check(cfg.Season.Days >= 0, "season.days (SYSTEM_SEASON_DAYS)", "must not be negative (0 = one season boundary), got %d", cfg.Season.Days)
+	if _, err := gemini.NewAllocator(cfg.AI.Provider); err != nil {
+		check(false, "ai.provider (SYSTEM_AI_PROVIDER)", "%v", err)
+	}
+	check(cfg.AI.Timeout > 0, "ai.timeout (GEMINI_TIMEOUT)", "must be positive, got %s", cfg.AI.Timeout)
+	check(cfg.Webhooks.Timeout >= time.Second, "webhooks.timeout (SYSTEM_WEBHOOK_TIMEOUT)", "must be at least 1s, got %s", cfg.Webhooks.Timeout)
+	for i, t := range cfg.Webhooks.Targets {
+		field := fmt.Sprintf("webhooks.targets[%d]", i)
+		u, err := url.Parse(t.URL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", field+".url", "must be an http or https URL")
+		for _, ev := range t.Events {
+			check(slices.Contains(webhookEvents, ev), field+".events", "must be from %s, got %q", strings.Join(webhookEvents, ", "), ev)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// apply sets the package-level settings the server and store read.
+// cfg must be valid.
+func (cfg Config) apply() {
+	sshAddr = cfg.SSHAddr
+	store.DataDir = cfg.DataDir
+	store.DataRoot = cfg.DataRoot
+	regenerateBadHostKeys = cfg.RegenerateBadHostKeys
+	store.LevelCap = cfg.LevelCap
+	store.MaxHabits = cfg.MaxHabits
+	store.AllowRegister = cfg.AllowRegister
+	store.RevealLoginErrors = cfg.RevealLoginErrors
+	showLoginBanner = cfg.LoginBanner
+	store.WelcomeQuestEnabled = cfg.WelcomeQuest
+	store.DemoUser = store.NormalizeUsername(cfg.DemoUser)
+	helpFile = cfg.HelpFile
+	noBell = cfg.NoBell
+	sshAuth = cfg.SSHAuth
+	saveDebounce = cfg.SaveDebounce
+	snoozeFor = cfg.Snooze
+	focusFor = cfg.Focus
+	toastFor = cfg.Toast
+	store.UncheckGrace = cfg.UncheckGrace
+	store.UncheckPolicy = cfg.UncheckPolicy
+	autoArchiveDays = cfg.AutoArchiveDays
+	store.PasswordRules.MinLength = cfg.Passwords.MinLength
+	store.PasswordRules.MinClasses = cfg.Passwords.MinClasses
+	store.PasswordRules.BlockCommon = cfg.Passwords.BlockCommon
+	store.LockoutAttempts = cfg.Lockout.Attempts
+	store.LockoutWindow = cfg.Lockout.Window
+	store.LockoutFor = cfg.Lockout.Duration
+	store.LeaderboardIntegrity = cfg.Leaderboard.Integrity
+	leaderboardFile = cfg.Leaderboard.File
+	leaderboardEvery = cfg.Leaderboard.Every
+	store.SeasonStart, _ = parseSeasonStart(cfg.Season.Start)
+	store.SeasonDays = cfg.Season.Days
+	gemini.Allocator, _ = gemini.NewAllocator(cfg.AI.Provider)
+	gemini.Timeout = cfg.AI.Timeout
+	webhookTimeout = cfg.Webhooks.Timeout
+	webhookTargets = cfg.Webhooks.Targets
+}
+
+// parseSeasonStart reads a season start date as local midnight; "" is the
+// zero time, turning seasons off
+func parseSeasonStart(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(time.DateOnly, s, time.Local)
+}
+
+// envReader overrides config values from environment variables, collecting
+// a parse error per bad variable
+type envReader struct {
+	getenv func(string) string
+	errs   []error
+}
+
+func (e *envReader) str(key string, dst *string) {
+	if v := e.getenv(key); v != "" {
+		*dst = v
+	}
+}
+
+func (e *envReader) boolean(key string, dst *bool) {
+	if v := e.getenv(key); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be true or false, got %q", key, v))
+			return
+		}
+		*dst = b
+	}
+}
+
+// set turns dst on when key has any value, for the "set to enable" variables
+func (e *envReader) set(key string, dst *bool) {
+	if e.getenv(key) != "" {
+		*dst = true
+	}
+}
+
+func (e *envReader) integer(key string, dst *int) {
+	if v := e.getenv(key); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be a whole number, got %q", key, v))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	if v := e.getenv(key); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be a duration like 30s or 5m, got %q", key, v))
+			return
+		}
+		*dst = d
+	}
+}
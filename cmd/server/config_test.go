@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a YAML config file for the test and returns its path
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "system.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// noEnv is a getenv with nothing set
+func noEnv(string) string { return "" }
+
+// serverFlags parses args with the server's flags that override the config
+func serverFlags(t *testing.T, args ...string) *flag.FlagSet {
+	t.Helper()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.String("http", "", "")
+	fs.String("health", "", "")
+	fs.String("host-keys", "ed25519", "")
+	fs.Bool("regenerate-bad-host-keys", false, "")
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, err := loadConfig("", noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SSHAddr != sshAddr || cfg.SaveDebounce != saveDebounce || cfg.AI.Provider != "gemini" {
+		t.Errorf("loadConfig with no file = %+v", cfg)
+	}
+}
+
+func TestLoadConfigEmptyFile(t *testing.T) {
+	cfg, err := loadConfig(writeConfig(t, "\n"), noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SSHAddr != sshAddr {
+		t.Errorf("ssh_addr = %q, want the default %q", cfg.SSHAddr, sshAddr)
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := writeConfig(t, "ssh_addr: :2222\nsave_debounce: 2s\nai:\n  provider: local\n  timeout: 3s\n")
+	cfg, err := loadConfig(path, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SSHAddr != ":2222" || cfg.SaveDebounce != 2*time.Second || cfg.AI.Provider != "local" || cfg.AI.Timeout != 3*time.Second {
+		t.Errorf("loadConfig = %+v", cfg)
+	}
+}
+
+func TestLoadConfigEnvOverridesFile(t *testing.T) {
+	path := writeConfig(t, "ssh_addr: :2222\nlevel_cap: 50\n")
+	env := map[string]string{"SYSTEM_SSH_ADDR": ":3333", "GEMINI_TIMEOUT": "45s", "SYSTEM_AI_PROVIDER": "openai", "SYSTEM_NO_BELL": "1"}
+	cfg, err := loadConfig(path, func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SSHAddr != ":3333" || cfg.AI.Timeout != 45*time.Second || cfg.AI.Provider != "openai" || !cfg.NoBell {
+		t.Errorf("environment didn't override the file: %+v", cfg)
+	}
+	if cfg.LevelCap != 50 {
+		t.Errorf("level_cap = %d, want 50 from the file", cfg.LevelCap)
+	}
+}
+
+func TestLoadConfigWebhooks(t *testing.T) {
+	path := writeConfig(t, "webhooks:\n  targets:\n    - url: https://example.com/hook\n      events: [level_up]\n")
+	cfg, err := loadConfig(path, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Webhooks.Targets) != 1 || cfg.Webhooks.Targets[0].URL != "https://example.com/hook" {
+		t.Errorf("webhooks = %+v", cfg.Webhooks)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string // "" loads no file
+		env  map[string]string
+		err  string // substring of the error
+	}{
+		{name: "misspelled key", yaml: "ssh_adr: :2222\n", err: "ssh_adr"},
+		{name: "bad variable", env: map[string]string{"SYSTEM_SAVE_DEBOUNCE": "soon"}, err: "SYSTEM_SAVE_DEBOUNCE"},
+		{name: "out of range", yaml: "level_cap: 1\n", err: "level_cap"},
+		{name: "negative debounce", env: map[string]string{"SYSTEM_SAVE_DEBOUNCE": "-1s"}, err: "save_debounce"},
+		{name: "unknown provider", env: map[string]string{"SYSTEM_AI_PROVIDER": "oracle"}, err: "ai.provider"},
+		{name: "zero AI timeout", yaml: "ai:\n  timeout: 0s\n", err: "ai.timeout"},
+		{name: "negative quest limit", yaml: "max_habits: -1\n", err: "max_habits"},
+		{name: "webhook URL", yaml: "webhooks:\n  targets:\n    - url: ftp://example.com\n", err: "webhooks.targets[0].url"},
+		{name: "webhook event", yaml: "webhooks:\n  targets:\n    - url: https://example.com\n      events: [login]\n", err: "webhooks.targets[0].events"},
+		{name: "errors after the first", yaml: "level_cap: 1\nsave_debounce: -1s\n", err: "save_debounce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			if tt.yaml != "" {
+				path = writeConfig(t, tt.yaml)
+			}
+			_, err := loadConfig(path, func(key string) string { return tt.env[key] })
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("loadConfig error = %v, want one mentioning %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestApplyFlagsDefaults(t *testing.T) {
+	cfg := defaultConfig()
+	if err := cfg.applyFlags(serverFlags(t)); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HostKeys != "ed25519" || cfg.HTTPAddr != "" {
+		t.Errorf("applyFlags with no flags = %+v", cfg)
+	}
+}
+
+func TestApplyFlagsSet(t *testing.T) {
+	cfg := defaultConfig()
+	if err := cfg.applyFlags(serverFlags(t, "-http", ":8080", "-host-keys", "rsa,ed25519", "-regenerate-bad-host-keys")); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HTTPAddr != ":8080" || cfg.HostKeys != "rsa,ed25519" || !cfg.RegenerateBadHostKeys {
+		t.Errorf("applyFlags = %+v", cfg)
+	}
+}
+
+func TestApplyFlagsBadHostKeyType(t *testing.T) {
+	cfg := defaultConfig()
+	if err := cfg.applyFlags(serverFlags(t, "-host-keys", "dsa")); err == nil {
+		t.Error("applyFlags accepted a dsa host key")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// dateLayout is how one of store.DateFormats writes dates and times
+type dateLayout struct {
+	date  string // a full date, e.g. a report's week
+	day   string // a recent day with its weekday, e.g. in quest history
+	stamp string // a moment in the last few months, e.g. in the activity log
+	clock string // a time of day
+}
+
+var dateLayouts = map[string]dateLayout{
+	store.DateFormatISO: {date: "2006-01-02", day: "Mon 01-02", stamp: "01-02 15:04", clock: "15:04"},
+	store.DateFormatUS:  {date: "01/02/2006", day: "Mon Jan 2", stamp: "Jan 2 3:04 PM", clock: "3:04 PM"},
+	store.DateFormatEU:  {date: "02/01/2006", day: "Mon 2 Jan", stamp: "2 Jan 15:04", clock: "15:04"},
+}
+
+// layoutFor returns the layouts for format, ISO for "" or an unknown one
+func layoutFor(format string) dateLayout {
+	if l, ok := dateLayouts[format]; ok {
+		return l
+	}
+	return dateLayouts[store.DateFormatISO]
+}
+
+// formatDayKey shows a DayKeyLayout day key with layout, e.g. "2026-10-18"
+// as "10/18/2026". A key that doesn't parse is shown as it is.
+func formatDayKey(key, layout string) string {
+	t, err := time.Parse(store.DayKeyLayout, key)
+	if err != nil {
+		return key
+	}
+	return t.Format(layout)
+}
+
+// dates returns the logged-in user's date layouts
+func (m model) dates() dateLayout {
+	if m.userData == nil {
+		return layoutFor("")
+	}
+	return layoutFor(m.userData.DateFormat)
+}
+
+// layoutName is the store.DateFormats entry format stands for; "" is ISO
+func layoutName(format string) string {
+	if _, ok := dateLayouts[format]; ok {
+		return format
+	}
+	return store.DateFormatISO
+}
+
+// hourLabel shows the start of hour in dates' clock layout, e.g. "05:00" or
+// "5:00 AM"
+func hourLabel(hour int, dates dateLayout) string {
+	return time.Date(2000, 1, 1, hour, 0, 0, 0, time.UTC).Format(dates.clock)
+}
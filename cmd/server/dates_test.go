@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+func TestDateLayouts(t *testing.T) {
+	tests := []struct {
+		format string
+		date   string
+		hour   string
+	}{
+		{store.DateFormatISO, "2026-10-18", "17:00"},
+		{store.DateFormatUS, "10/18/2026", "5:00 PM"},
+		{store.DateFormatEU, "18/10/2026", "17:00"},
+		{"", "2026-10-18", "17:00"},
+		{"klingon", "2026-10-18", "17:00"},
+	}
+	for _, tt := range tests {
+		l := layoutFor(tt.format)
+		if got := formatDayKey("2026-10-18", l.date); got != tt.date {
+			t.Errorf("%q: formatDayKey = %q, want %q", tt.format, got, tt.date)
+		}
+		if got := hourLabel(17, l); got != tt.hour {
+			t.Errorf("%q: hourLabel(17) = %q, want %q", tt.format, got, tt.hour)
+		}
+	}
+	if got := formatDayKey("someday", "2006-01-02"); got != "someday" {
+		t.Errorf("formatDayKey of a bad key = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// focusFor is how long [f] counts down before completing the quest. Set by
+// SYSTEM_FOCUS.
+var focusFor = 25 * time.Minute
+
+// focusTickMsg redraws a running focus timer once a second. gen ties it to
+// one run of the timer, so ticks from a paused or cancelled run stop.
+type focusTickMsg struct{ gen int }
+
+func tickFocus(gen int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return focusTickMsg{gen: gen} })
+}
+
+// focusLeft returns how long the focus timer has left at now
+func (m model) focusLeft(now time.Time) time.Duration {
+	if m.focusPaused {
+		return m.focusRemain
+	}
+	return max(m.focusEnd.Sub(now), 0)
+}
+
+// toggleFocus starts a focus timer on h, or pauses or resumes the one
+// already running on it
+func (m model) toggleFocus(h store.Habit, now time.Time) (model, tea.Cmd) {
+	name := truncateQuestName(h.Name, maxQuestNameRunes)
+	switch {
+	case m.focusHabitID != "" && m.focusHabitID != h.ID:
+		m.lastToast = "Another quest has a focus timer. [F] cancels it."
+		return m, nil
+	case m.focusHabitID == h.ID && m.focusPaused:
+		m.focusPaused = false
+		m.focusEnd = now.Add(m.focusRemain)
+		m.focusGen++
+		m.lastToast = fmt.Sprintf("Focus on '%s' resumed.", name)
+		return m, tickFocus(m.focusGen)
+	case m.focusHabitID == h.ID:
+		m.focusPaused = true
+		m.focusRemain = m.focusLeft(now)
+		m.focusGen++
+		m.lastToast = fmt.Sprintf("Focus on '%s' paused. [f] resumes.", name)
+		return m, nil
+	case m.userData.CompletedToday(h.ID):
+		m.lastToast = "That quest is already complete today."
+		return m, nil
+	case m.userData.ChainLocked(h.ID, m.userData.TodayKey()):
+		m.lastToast = m.chainedToast(h)
+		return m, nil
+	}
+	m.focusHabitID = h.ID
+	m.focusPaused = false
+	m.focusEnd = now.Add(focusFor)
+	m.focusGen++
+	m.lastToast = fmt.Sprintf("Focus on '%s' for %s. [f] pauses, [F] cancels.", name, shortDuration(focusFor))
+	return m, tickFocus(m.focusGen)
+}
+
+// cancelFocus drops the focus timer; its pending tick is ignored
+func (m *model) cancelFocus() {
+	m.focusHabitID = ""
+	m.focusPaused = false
+	m.focusGen++
+}
+
+// onFocusTick keeps a running timer ticking and completes its quest when it
+// runs out. A quest deleted or archived meanwhile is just reported; one
+// already completed some other way isn't toggled back.
+func (m model) onFocusTick(tick focusTickMsg) (model, tea.Cmd) {
+	if tick.gen != m.focusGen || m.focusHabitID == "" || m.focusPaused {
+		return m, nil
+	}
+	if m.focusLeft(time.Now()) > 0 {
+		return m, tickFocus(m.focusGen)
+	}
+	id := m.focusHabitID
+	m.cancelFocus()
+	if m.userData == nil {
+		return m, nil
+	}
+	h, ok := m.userData.HabitByID(id)
+	switch {
+	case !ok:
+		m.lastToast = "Focus timer done, but its quest is gone."
+		return m, m.bell()
+	case m.userData.CompletedToday(h.ID):
+		m.lastToast = fmt.Sprintf("Focus timer done for '%s'.", truncateQuestName(h.Name, maxQuestNameRunes))
+		return m, m.bell()
+	}
+	next, cmd := m.applyToggle(h)
+	if !next.pendingLevelUp {
+		// the level-up flow rings on its own
+		cmd = tea.Batch(cmd, next.bell())
+	}
+	return next, cmd
+}
+
+// focusClock formats a countdown as m:ss
+func focusClock(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
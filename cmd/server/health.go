@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+)
+
+// activeSessions counts open SSH sessions for the health endpoint
+var activeSessions atomic.Int64
+
+// sessionCounter tracks activeSessions for the life of each session
+func sessionCounter() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			activeSessions.Add(1)
+			defer activeSessions.Add(-1)
+			next(sess)
+		}
+	}
+}
+
+// healthCheckEvery is how long a data directory check is reused, so probes
+// stay cheap however often they come
+const healthCheckEvery = 5 * time.Second
+
+// healthStatus is the JSON body of the health endpoint
+type healthStatus struct {
+	Status          string `json:"status"` // "ok" or "degraded"
+	UptimeSeconds   int64  `json:"uptime_seconds"`
+	Sessions        int64  `json:"sessions"`
+	DataDirWritable bool   `json:"data_dir_writable"`
+	Error           string `json:"error,omitempty"`
+}
+
+// healthHandler serves GET /healthz: 200 when the data directory is
+// writable, 503 otherwise. checkDir is store.CheckDataDir outside tests.
+func healthHandler(started time.Time, checkDir func() error) http.Handler {
+	var (
+		mu      sync.Mutex
+		checked time.Time
+		lastErr error
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if time.Since(checked) > healthCheckEvery {
+			lastErr = checkDir()
+			checked = time.Now()
+		}
+		err := lastErr
+		mu.Unlock()
+
+		st := healthStatus{
+			Status:          "ok",
+			UptimeSeconds:   int64(time.Since(started).Seconds()),
+			Sessions:        activeSessions.Load(),
+			DataDirWritable: err == nil,
+		}
+		code := http.StatusOK
+		if err != nil {
+			st.Status = "degraded"
+			st.Error = "data directory: " + err.Error()
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(st)
+	})
+	return mux
+}
+
+// serveHealth starts the health endpoint on addr. A failure to bind is
+// logged, not fatal: SSH keeps serving without it.
+func serveHealth(addr string, started time.Time) {
+	srv := &http.Server{Addr: addr, Handler: healthHandler(started, store.CheckDataDir), ReadHeaderTimeout: 5 * time.Second}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Printf("warning: health endpoint disabled: %v", err)
+		return
+	}
+	log.Println("⚔ SYSTEM — health check on", addr+"/healthz")
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			log.Println("health endpoint stopped:", err)
+		}
+	}()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		checkErr error
+		code     int
+		status   string
+	}{
+		{name: "writable", method: http.MethodGet, code: http.StatusOK, status: "ok"},
+		{name: "read-only", method: http.MethodGet, checkErr: errors.New("read-only file system"), code: http.StatusServiceUnavailable, status: "degraded"},
+		{name: "wrong method", method: http.MethodPost, code: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := 0
+			h := healthHandler(time.Now().Add(-time.Minute), func() error {
+				checks++
+				return tt.checkErr
+			})
+			for range 2 {
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, httptest.NewRequest(tt.method, "/healthz", nil))
+				if rec.Code != tt.code {
+					t.Fatalf("status code = %d, want %d", rec.Code, tt.code)
+				}
+				if tt.status == "" {
+					return
+				}
+				var st healthStatus
+				if err := json.NewDecoder(rec.Body).Decode(&st); err != nil {
+					t.Fatal(err)
+				}
+				if st.Status != tt.status || st.DataDirWritable != (tt.checkErr == nil) || st.UptimeSeconds < 60 {
+					t.Errorf("health = %+v", st)
+				}
+			}
+			if checks != 1 {
+				t.Errorf("data directory checked %d times for two probes, want 1", checks)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// helpFile is a Markdown file shown by the [?] help page instead of the
+// built-in text, so a server can add its own tips, rules or lore. Set by
+// SYSTEM_HELP_FILE; it is read each time the page opens.
+var helpFile = ""
+
+// helpWidth is the widest a help line is wrapped to
+const helpWidth = 64
+
+// builtinHelp is shown when no help file is set or it can't be read
+const builtinHelp = `# Welcome, Hunter
+
+The SYSTEM turns your daily habits into quests. Complete them before the
+daily reset to earn EXP, level up and climb the rankings.
+
+## Quests
+- **a** adds a quest, **e** edits it and **d** deletes it
+- **Space** completes the selected quest (swap it with **Enter** in settings)
+- **1**-**9** complete the quest in that position without moving the cursor
+- **C** completes every open quest at once
+- Press * on a quest to make it this week's ⭐ spotlight, worth bonus EXP
+- **Enter** opens a quest's detail view and history
+- **f** starts a focus timer on the selected quest
+
+## Progress
+- Each completed quest pays EXP; every 100 EXP is a level
+- Complete every quest in a day to grow your streak
+- **t** shows your stats, **w** the weekly report and **r** the rankings
+
+## More
+- **s** opens settings: reset time, keys, theme and more
+- **L** lists your recent activity
+- **q** quits; your progress is saved as you go, and **ctrl+s** saves right away
+`
+
+// loadHelp returns the help page source: helpFile if one is set and
+// readable, else builtinHelp
+func loadHelp() string {
+	if helpFile == "" {
+		return builtinHelp
+	}
+	data, err := os.ReadFile(helpFile)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("help file %s: %v", helpFile, err)
+		}
+		return builtinHelp
+	}
+	return string(data)
+}
+
+// renderMarkdown styles a small subset of Markdown, one output line per
+// display line: "#" and "##" headings, "-" or "*" bullets, and **bold**
+// within a line. Other lines are text; as in Markdown, consecutive ones
+// form one paragraph, wrapped to width.
+func renderMarkdown(src string, width int, heading, subheading, text, bold lipgloss.Style) []string {
+	var lines, para []string
+	flush := func() {
+		if len(para) > 0 {
+			lines = append(lines, styleInline(wrapText(strings.Join(para, " "), width), text, bold)...)
+			para = nil
+		}
+	}
+	for _, raw := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		if line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "- ") && !strings.HasPrefix(line, "* ") {
+			para = append(para, line)
+			continue
+		}
+		flush()
+		switch {
+		case strings.HasPrefix(line, "## "):
+			lines = append(lines, subheading.Render(strings.TrimSpace(line[3:])))
+		case strings.HasPrefix(line, "# "):
+			lines = append(lines, heading.Render(strings.ToUpper(strings.TrimSpace(line[2:]))))
+		case strings.HasPrefix(line, "- "), strings.HasPrefix(line, "* "):
+			for i, part := range styleInline(wrapText(strings.TrimSpace(line[2:]), width-2), text, bold) {
+				prefix := "  "
+				if i == 0 {
+					prefix = "• "
+				}
+				lines = append(lines, text.Render(prefix)+part)
+			}
+		case strings.HasPrefix(line, "#"):
+			lines = append(lines, text.Render(line)) // a deeper heading, shown as written
+		default:
+			lines = append(lines, "")
+		}
+	}
+	flush()
+	// Drop trailing blank lines so the box ends at the last text
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// styleInline renders each line with text, switching to bold between
+// "**" markers. A bold run may continue across wrapped lines.
+func styleInline(lines []string, text, bold lipgloss.Style) []string {
+	out := make([]string, 0, len(lines))
+	on := false
+	for _, line := range lines {
+		if line == "" {
+			out = append(out, "")
+			continue
+		}
+		var b strings.Builder
+		for i, part := range strings.Split(line, "**") {
+			if i > 0 {
+				on = !on
+			}
+			if part == "" {
+				continue
+			}
+			if on {
+				b.WriteString(bold.Render(part))
+			} else {
+				b.WriteString(text.Render(part))
+			}
+		}
+		out = append(out, b.String())
+	}
+	return out
+}
+
+// wrapText breaks s at spaces into lines at most width cells wide, not
+// counting "**" markers. A word longer than width gets a line to itself.
+func wrapText(s string, width int) []string {
+	if s == "" {
+		return []string{""}
+	}
+	var lines []string
+	var cur string
+	for _, word := range strings.Fields(s) {
+		if cur != "" && cellWidth(cur+" "+word) > width {
+			lines = append(lines, cur)
+			cur = ""
+		}
+		if cur != "" {
+			cur += " "
+		}
+		cur += word
+	}
+	return append(lines, cur)
+}
+
+// cellWidth is the display width of s without its bold markers
+func cellWidth(s string) int {
+	return lipgloss.Width(strings.ReplaceAll(s, "**", ""))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  []string
+	}{
+		{"", 10, []string{""}},
+		{"one two three", 20, []string{"one two three"}},
+		{"one two three", 7, []string{"one two", "three"}},
+		{"**bold** words here", 10, []string{"**bold** words", "here"}}, // markers take no cells
+		{"supercalifragilistic is long", 8, []string{"supercalifragilistic", "is long"}},
+	}
+	for _, tt := range tests {
+		if got := wrapText(tt.s, tt.width); !slices.Equal(got, tt.want) {
+			t.Errorf("wrapText(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"heading", "# Rules", []string{"RULES"}},
+		{"subheading", "## Quests", []string{"Quests"}},
+		{"deeper heading", "### Small", []string{"### Small"}},
+		{"paragraph joined and wrapped", "Complete your\nquests daily to earn EXP", []string{"Complete your quests", "daily to earn EXP"}},
+		{"bullet wrapped under itself", "- Press **a** to add a quest now", []string{"• Press a to add a", "  quest now"}},
+		{"star bullet", "* one", []string{"• one"}},
+		{"blank lines kept between, dropped at the end", "# A\n\ntext\n\n\n", []string{"A", "", "text"}},
+		{"CRLF", "# A\r\ntext", []string{"A", "text"}},
+	}
+	s := lipgloss.NewStyle()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdown(tt.src, 20, s, s, s, s); !slices.Equal(got, tt.want) {
+				t.Errorf("renderMarkdown(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadHelp(t *testing.T) {
+	dir := t.TempDir()
+	custom := filepath.Join(dir, "help.md")
+	if err := os.WriteFile(custom, []byte("# House rules\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"built in", "", builtinHelp},
+		{"file", custom, "# House rules\n"},
+		{"missing file", filepath.Join(dir, "gone.md"), builtinHelp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFor(t, &helpFile, tt.file)
+			if got := loadHelp(); got != tt.want {
+				t.Errorf("loadHelp() = %q", firstLine(got))
+			}
+		})
+	}
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
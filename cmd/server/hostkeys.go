@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/keygen"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// hostKeyPaths maps each supported host key type to its file. Ed25519 keeps
+// the original ssh_host_key path so existing deployments keep their identity.
+var hostKeyPaths = map[keygen.KeyType]string{
+	keygen.Ed25519: "ssh_host_key",
+	keygen.RSA:     "ssh_host_key_rsa",
+	keygen.ECDSA:   "ssh_host_key_ecdsa",
+}
+
+// regenerateBadHostKeys replaces a host key file that exists but can't be
+// parsed, keeping the bad file as a backup, instead of refusing to start.
+// Set by SYSTEM_REGENERATE_BAD_HOST_KEYS or -regenerate-bad-host-keys.
+var regenerateBadHostKeys = false
+
+// parseHostKeyTypes parses a comma-separated list such as "ed25519,rsa"
+func parseHostKeyTypes(list string) ([]keygen.KeyType, error) {
+	var types []keygen.KeyType
+	seen := map[keygen.KeyType]bool{}
+	for _, f := range strings.Split(list, ",") {
+		t := keygen.KeyType(strings.ToLower(strings.TrimSpace(f)))
+		if t == "" {
+			continue
+		}
+		if _, ok := hostKeyPaths[t]; !ok {
+			return nil, fmt.Errorf("unknown host key type %q (want ed25519, rsa or ecdsa)", t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("no host key types given")
+	}
+	return types, nil
+}
+
+// hostKeyOptions loads a host key of each type from dir, generating any that
+// are missing, and returns wish options registering all of them. A key file
+// that exists but doesn't parse (empty, or cut short by a crash) is an error
+// naming the file, or with regenerateBadHostKeys is replaced.
+func hostKeyOptions(dir string, types []keygen.KeyType) ([]ssh.Option, error) {
+	opts := make([]ssh.Option, 0, len(types))
+	for _, t := range types {
+		path := filepath.Join(dir, hostKeyPaths[t])
+		_, statErr := os.Stat(path)
+		existed := statErr == nil
+		if existed {
+			if err := checkHostKey(path); err != nil {
+				if !regenerateBadHostKeys || !canRegenerate(err) {
+					return nil, err
+				}
+				res, rerr := rotateHostKey(dir, t, time.Now())
+				if rerr != nil {
+					return nil, fmt.Errorf("%w; replacing it failed: %w", err, rerr)
+				}
+				log.Printf("%v; moved it to %s and generated a new %s host key (%s)", err, res.Backup, t, res.NewFingerprint)
+			}
+		}
+		kp, err := keygen.New(path, keygen.WithKeyType(t), keygen.WithWrite())
+		if err != nil {
+			return nil, fmt.Errorf("%s host key %s: %w", t, path, err)
+		}
+		if existed {
+			log.Printf("loaded %s host key from %s (%s)", t, path, gossh.FingerprintSHA256(kp.PublicKey()))
+		} else {
+			log.Printf("generated new %s host key at %s (%s)", t, path, gossh.FingerprintSHA256(kp.PublicKey()))
+		}
+		opts = append(opts, wish.WithHostKeyPEM(kp.RawPrivateKey()))
+	}
+	return opts, nil
+}
+
+// checkHostKey reports a host key file that can't be used, saying what to do
+// about it rather than leaving the SSH library's parse error on its own
+func checkHostKey(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("host key %s: %w", path, err)
+	}
+	if _, err := gossh.ParsePrivateKey(data); err != nil {
+		var missing *gossh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return &badHostKeyError{path: path, err: fmt.Errorf("it is passphrase-protected, which the server can't unlock")}
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			err = errors.New("the file is empty")
+		}
+		return &badHostKeyError{path: path, err: err, regenerable: true}
+	}
+	return nil
+}
+
+// badHostKeyError is a host key file that exists but can't be loaded
+type badHostKeyError struct {
+	path        string
+	err         error
+	regenerable bool // corrupt rather than deliberately different; safe to replace
+}
+
+func (e *badHostKeyError) Error() string {
+	msg := fmt.Sprintf("host key %s exists but is invalid (%v)", e.path, e.err)
+	if e.regenerable && !regenerateBadHostKeys {
+		msg += "; restore it from a backup, or start with -regenerate-bad-host-keys (SYSTEM_REGENERATE_BAD_HOST_KEYS=true) to keep it as a .bak file and generate a new one"
+	}
+	return msg
+}
+
+func (e *badHostKeyError) Unwrap() error { return e.err }
+
+// canRegenerate reports whether err is a host key that may be replaced
+func canRegenerate(err error) bool {
+	var bad *badHostKeyError
+	return errors.As(err, &bad) && bad.regenerable
+}
+
+// rotatedHostKey describes a host key replaced by rotateHostKey
+type rotatedHostKey struct {
+	Path           string // the key file, now holding the new key
+	Backup         string // where the old key was copied; "" if there was none
+	OldFingerprint string // "" if there was no old key or it was unreadable
+	NewFingerprint string
+}
+
+// rotateHostKey replaces the host key of type t in dir with a freshly
+// generated one. The new pair is written to a temporary directory next to
+// the key and renamed over it, so the key file is never missing or half
+// written; the old pair is first copied to <key>.bak-<time>, even when it
+// doesn't parse (leaving OldFingerprint empty).
+func rotateHostKey(dir string, t keygen.KeyType, now time.Time) (rotatedHostKey, error) {
+	path := filepath.Join(dir, hostKeyPaths[t])
+	res := rotatedHostKey{Path: path}
+	if _, err := os.Stat(path); err == nil {
+		if checkHostKey(path) == nil {
+			old, err := keygen.New(path, keygen.WithKeyType(t))
+			if err != nil {
+				return res, fmt.Errorf("reading current %s host key %s: %w", t, path, err)
+			}
+			res.OldFingerprint = gossh.FingerprintSHA256(old.PublicKey())
+		}
+		res.Backup = path + ".bak-" + now.Format("20060102-150405")
+		if err := copyFile(path, res.Backup, 0600); err != nil {
+			return res, fmt.Errorf("backing up %s: %w", path, err)
+		}
+		if err := copyFile(path+".pub", res.Backup+".pub", 0644); err != nil && !os.IsNotExist(err) {
+			return res, fmt.Errorf("backing up %s.pub: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return res, err
+	}
+
+	tmpDir, err := os.MkdirTemp(filepath.Dir(path), ".host-key-rotate-*")
+	if err != nil {
+		return res, err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmp := filepath.Join(tmpDir, filepath.Base(path))
+	kp, err := keygen.New(tmp, keygen.WithKeyType(t), keygen.WithWrite())
+	if err != nil {
+		return res, fmt.Errorf("generating %s host key: %w", t, err)
+	}
+	res.NewFingerprint = gossh.FingerprintSHA256(kp.PublicKey())
+	if err := os.Rename(tmp+".pub", path+".pub"); err != nil {
+		return res, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// copyFile copies src to a new file dst with mode perm; it never overwrites
+// an existing dst
+func copyFile(src, dst string, perm os.FileMode) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
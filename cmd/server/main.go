@@ -1,20 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/keygen"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/ssh"
 	"github.com/charmbracelet/wish"
 	"github.com/charmbracelet/wish/bubbletea"
 	"github.com/charmbracelet/wish/logging"
+	"github.com/muesli/termenv"
 
+	"github.com/abhigyan-mohanta/system/internal/api"
 	"github.com/abhigyan-mohanta/system/internal/gemini"
 	"github.com/abhigyan-mohanta/system/internal/store"
 )
@@ -26,11 +36,30 @@ const (
 	authRegister authState = "register"
 	authMain     authState = "main"
 	authSettings authState = "settings"
+	authReport   authState = "report"
+	authDetail   authState = "detail"
+	authRanks    authState = "ranks"
+	authHistory  authState = "history"
+	authNewPass  authState = "new_password"
+	authActivity authState = "activity"
+	authCompare  authState = "compare"
+	authStats    authState = "stats"
+	authArchive  authState = "archive"
+	authTokens   authState = "tokens"
+	authReset    authState = "reset"
+	authHelp     authState = "help"
 )
 
 type model struct {
-	authState authState
-	renderer  *lipgloss.Renderer
+	authState    authState
+	renderer     *lipgloss.Renderer
+	monoRenderer *lipgloss.Renderer // colorless renderer for the monochrome theme
+	saver        *pendingSave       // debounced saves, shared across model copies
+	out          io.Writer          // raw session output, used for the terminal bell
+	ctx          context.Context    // session context, canceled when the client disconnects
+	noBell       bool               // SYSTEM_NO_BELL server override
+	width        int                // terminal width in cells; 0 until the client reports it
+	height       int                // terminal height in rows; 0 until the client reports it
 
 	// Login/register form
 	loginUsername string
@@ -38,50 +67,433 @@ type model struct {
 	loginFocus    int // 0 = username, 1 = password
 	authError     string
 
+	// Forced password change after an admin reset
+	newPassword     string
+	confirmPassword string
+
 	// Main app (when logged in)
-	userData       *store.UserData
-	cursor         int
-	addingHabit    *string
-	lastToast      string // "Quest complete!", "Level Up!", etc. — cleared on next key
-	pendingLevelUp bool   // Waiting for Gemini API response
+	userData        *store.UserData
+	live            *store.Live // userData as shared with the hunter's other sessions and the API; see shareUser
+	keymap          string      // navigation preset, loaded from userData on login
+	cursor          int
+	addingHabit     *string // Quest name being typed; non-nil while the add/edit form is open
+	addingNote      string
+	addingIcon      string
+	addingReminder  int       // Reminder hour in the form; -1 for none
+	addingDependsOn string    // Prerequisite quest ID in the form; "" for none
+	addingColor     string    // Quest color in the form; "" for none
+	addingWeekly    int       // Weekly target in the form; 0 for every day
+	addingFocus     int       // 0 = name, 1 = note, 2 = reminder, 3 = prerequisite, 4 = color, 5 = weekly target
+	editingHabitID  string    // Habit being edited; "" when adding a new one
+	suggesting      bool      // Waiting for a quest suggestion in the add form
+	suggestError    string    // Shown in the add form when the suggestion fell back
+	detailHabitID   string    // Habit shown in the detail view
+	tutorialStep    int       // Current tutorial page; -1 when the tutorial isn't showing
+	historyCursor   int       // Selected day in the history view; 0 = today
+	confirmPrestige bool      // Waiting for y/n on the prestige prompt
+	confirmUncheck  string    // Habit whose uncheck would cost a level, waiting for y/n
+	confirmDay      string    // Day of that uncheck; "" for today
+	lastToast       string    // "Quest complete!", "Level Up!", etc. — cleared on next key or after toastFor
+	pendingLevelUp  bool      // Waiting for Gemini API response
+	confirmQuit     bool      // Quit pressed while pendingLevelUp; waiting for y/n or the stats
+	flashUntil      time.Time // Status box border is gold until then (level-up flash)
+
+	// Reminder snoozes, kept for this session only
+	snoozed map[string]time.Time // Habit ID -> when its snooze ends
+
+	// Focus timer on one quest; it survives navigation and ends with the session
+	focusHabitID string        // quest being timed; "" when there is no timer
+	focusEnd     time.Time     // when a running timer runs out
+	focusPaused  bool          // paused, with focusRemain left
+	focusRemain  time.Duration // time left when paused
+	focusGen     int           // bumped on every start, pause, resume and cancel so stale ticks stop
+
+	// Toast auto-clear; see noteToast
+	toastExpires time.Time // when lastToast clears if no key comes first
+	toastGen     int       // bumped for every new toast so ticks for older ones stop
 
 	// Settings
-	settingsResetHour int  // Temporary value while editing
-	settingsSaved     bool // Show save confirmation
+	settingsFocus     int    // Which settings field up/down adjusts
+	settingsResetHour int    // Temporary value while editing
+	settingsKeymap    string // Temporary value while editing
+	settingsTheme     string // Temporary value while editing, previewed live
+	settingsMuteBell  bool   // Temporary value while editing
+	settingsHardcore  bool   // Temporary value while editing
+	settingsQuietFrom int    // Temporary value while editing
+	settingsQuietTo   int    // Temporary value while editing
+	settingsTotalEXP  bool   // Temporary value while editing
+	settingsNudge     bool   // Temporary value while editing
+	settingsAlmost    bool   // Temporary value while editing
+	settingsComplete  string // Temporary value while editing
+	settingsQuestEXP  int    // Temporary value while editing
+	settingsDates     string // Temporary value while editing
+	settingsSaved     bool   // Show save confirmation
+	settingsErr       string // Why Enter saved nothing; shown until the next save or Esc
+
+	// Weekly report
+	reportWeekOffset int // 0 = this week, -1 = last week, ...
+
+	// Leaderboard, loaded when the view opens
+	standings    store.Standings
+	standingsErr string
+
+	// Activity log, newest first, loaded when the view opens
+	activity       []store.AuditEvent
+	activityErr    string
+	activityScroll int // index of the first event shown
+
+	// Help page source, loaded when the view opens
+	help       string
+	helpScroll int // index of the first line shown
+
+	// Compare with a friend: the name being typed, then their public profile
+	compareName string
+	compareWith *store.PublicUserData
+	compareErr  string
+
+	// Archived quests, restorable from the archive view
+	archiveCursor int
+
+	// API tokens, managed from settings
+	tokenCursor   int
+	tokenLabel    *string // Label being typed; non-nil while creating a token
+	newToken      string  // Plaintext of the token just created, shown once
+	confirmRevoke bool    // Waiting for y/n before revoking the selected token
+	tokenErr      string
+
+	// Reset everything, opened from settings
+	resetConfirm string // Hunter name typed to confirm
+	resetErr     string
 }
 
+// sshAddr is where the SSH server listens. Set by the config (ssh_addr).
+var sshAddr = ":23234"
+
+// noBell never rings the terminal bell, whatever a hunter's setting. Set by
+// the config (no_bell).
+var noBell = false
+
+// leaderboardSize is how many top hunters the leaderboard view lists
+const leaderboardSize = 10
+
+// maxCompareNameRunes caps the name typed into the compare prompt
+const maxCompareNameRunes = 32
+
+// Activity view: how many audit events to load and how many fit on screen
+const (
+	activityLoad   = 100
+	activityWindow = 12
+)
+
+// Settings fields, in the order Tab cycles through them
+const (
+	settingsFieldResetHour = iota
+	settingsFieldKeymap
+	settingsFieldTheme
+	settingsFieldBell
+	settingsFieldHardcore
+	settingsFieldQuietStart
+	settingsFieldQuietEnd
+	settingsFieldEXPDisplay
+	settingsFieldNudge
+	settingsFieldAlmost
+	settingsFieldCompleteKey
+	settingsFieldQuestEXP
+	settingsFieldDateFormat
+	settingsFieldCount
+)
+
+// habitFormFields is how many fields Tab cycles through in the add/edit form
+const habitFormFields = 6
+
+// levelUpFlash is how long the status box stays gold after a level-up
+const levelUpFlash = 1500 * time.Millisecond
+
 // levelUpStatsMsg is received when Gemini API returns stat allocation
 type levelUpStatsMsg struct {
 	stats gemini.StatResponse
 }
 
+// questSuggestionMsg carries a suggested quest name for the add form
+type questSuggestionMsg struct {
+	name string
+	err  error
+}
+
+// flashEndMsg is sent when the level-up flash should end
+type flashEndMsg struct{}
+
+// clockTickMsg re-renders the view so time-based state (the reset countdown,
+// urgent quests) stays current without a key press
+type clockTickMsg struct{}
+
+const clockInterval = time.Minute
+
+func tickClock() tea.Cmd {
+	return tea.Tick(clockInterval, func(time.Time) tea.Msg { return clockTickMsg{} })
+}
+
 func initialModel(sess ssh.Session) model {
 	r := bubbletea.MakeRenderer(sess)
-	return model{
+	if noColor(sess.Environ()) {
+		r.SetColorProfile(termenv.Ascii)
+	}
+	monoRenderer := bubbletea.MakeRenderer(sess)
+	monoRenderer.SetColorProfile(termenv.Ascii)
+	saver := &pendingSave{}
+	pty, _, _ := sess.Pty()
+	go func() {
+		// Don't lose the last debounce window when the client disconnects
+		<-sess.Context().Done()
+		_ = saver.flush()
+	}()
+	m := model{
 		authState:     authLogin,
 		renderer:      r,
+		monoRenderer:  monoRenderer,
+		saver:         saver,
+		out:           sess,
+		ctx:           sess.Context(),
+		noBell:        noBell,
+		width:         pty.Window.Width,
+		height:        pty.Window.Height,
 		loginUsername: "",
 		loginPassword: "",
 		loginFocus:    0,
 		authError:     "",
 		userData:      nil,
 		cursor:        0,
+		tutorialStep:  -1,
 	}
+	if u, ok := sshUser(sess.Context()); ok {
+		// Logged in by the SSH handshake; see keyboardInteractive
+		m.loginUsername = u.Username
+		m.loggedIn(u)
+		if m.live != nil {
+			m.live.Unlock() // as Update does after a login
+		}
+	}
+	return m
+}
+
+// tutorialPages are the first-run tutorial steps: a title and body lines
+var tutorialPages = []struct {
+	title string
+	body  []string
+}{
+	{"Welcome, Hunter", []string{
+		"The SYSTEM has chosen you.",
+		"Complete daily quests to grow stronger.",
+	}},
+	{"Accept Quests", []string{
+		"Press [a] to add a daily quest, like \"Read 20 pages\".",
+		"Quests repeat every day.",
+	}},
+	{"Clear Quests", []string{
+		"Select a quest and press [space] to complete it.",
+		"Each quest grants +10 EXP. Clear all of them to build a 🔥 streak.",
+	}},
+	{"Level Up", []string{
+		"Every 100 EXP you level up and gain stat points.",
+		"Rise from E-Rank to S-Rank.",
+	}},
+	{"Settings", []string{
+		"Press [s] to set when your day resets and other preferences.",
+		"Good luck, Hunter.",
+	}},
+}
+
+// noColor reports whether the client asked for no colors (https://no-color.org)
+func noColor(environ []string) bool {
+	for _, kv := range environ {
+		if v, ok := strings.CutPrefix(kv, "NO_COLOR="); ok && v != "" {
+			return true
+		}
+	}
+	return false
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	if m.lastToast != "" && toastFor > 0 {
+		// a login during the SSH handshake starts with a toast up
+		return tea.Batch(tickClock(), tickToast(toastFor, m.toastGen))
+	}
+	return tickClock()
 }
 
+// Update handles msg with the hunter's shared record locked; see shareUser
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.live != nil {
+		m.live.Lock()
+	}
+	next, cmd := m.handle(msg)
+	if nm, ok := next.(model); ok && nm.live != nil {
+		nm.live.Unlock() // locked since the start, or since a login in this message
+	}
+	return next, cmd
+}
+
+// handle handles msg and, when it left unsaved changes, starts the
+// debounced save
+func (m model) handle(msg tea.Msg) (tea.Model, tea.Cmd) {
+	toast := m.lastToast
+	if _, ok := msg.(saveTickMsg); ok {
+		if err := m.saver.flush(); err != nil {
+			m.lastToast = saveFailedToast
+		}
+		return m, m.noteToast(toast, msg, time.Now())
+	}
+	next, cmd := m.update(msg)
+	if nm, ok := next.(model); ok {
+		nm.rememberCursor()
+		cmd = tea.Batch(cmd, nm.noteToast(toast, msg, time.Now()))
+		next = nm
+	}
+	if nm, ok := next.(model); ok && nm.saver.schedule() {
+		if saveDebounce <= 0 {
+			if err := nm.saver.flush(); err != nil {
+				nm.lastToast = saveFailedToast
+			}
+			return nm, cmd
+		}
+		cmd = tea.Batch(cmd, tea.Tick(saveDebounce, func(time.Time) tea.Msg { return saveTickMsg{} }))
+	}
+	return next, cmd
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if tick, ok := msg.(focusTickMsg); ok {
+		return m.onFocusTick(tick)
+	}
+	if tick, ok := msg.(toastTickMsg); ok {
+		return m.onToastTick(tick, time.Now())
+	}
+	if _, ok := msg.(clockTickMsg); ok {
+		m.expireSnoozes(time.Now())
+		return m, tickClock()
+	}
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width = size.Width
+		m.height = size.Height
+		return m, nil
+	}
 	// Handle async level-up stats response
 	if statsMsg, ok := msg.(levelUpStatsMsg); ok {
 		if m.userData != nil {
 			m.userData.ApplyLevelUpStats(statsMsg.stats.STR, statsMsg.stats.VIT, statsMsg.stats.AGI, statsMsg.stats.INT)
 			m.lastToast = fmt.Sprintf("LEVEL UP! Stats: STR+%d VIT+%d AGI+%d INT+%d", statsMsg.stats.STR, statsMsg.stats.VIT, statsMsg.stats.AGI, statsMsg.stats.INT)
-			_ = store.SaveUser(m.userData)
+			m.save()
 			m.pendingLevelUp = false
 		}
+		if m.confirmQuit {
+			// The user was waiting to leave; the rewards are in, so go
+			return m.forceQuit()
+		}
+		return m, nil
+	}
+	// Confirmation for an uncheck that would cost a level
+	if m.confirmUncheck != "" {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			id, day := m.confirmUncheck, m.confirmDay
+			m.confirmUncheck, m.confirmDay = "", ""
+			m.lastToast = ""
+			h, found := m.userData.HabitByID(id)
+			switch {
+			case msg.String() == "ctrl+c":
+				return m.quit()
+			case msg.String() != "y" || !found:
+				// Anything but y keeps the quest checked
+			case day == "":
+				return m.applyToggle(h)
+			default:
+				return m.applyBackfill(h, day)
+			}
+		}
+		return m, nil
+	}
+	// Quit confirmation while stats are pending
+	if m.confirmQuit {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "y", "ctrl+c":
+				return m.forceQuit()
+			case "n", "esc":
+				m.confirmQuit = false
+			}
+		}
+		return m, nil
+	}
+	if sugg, ok := msg.(questSuggestionMsg); ok {
+		m.suggesting = false
+		// Ignore late answers once the form closed or became an edit
+		if m.addingHabit != nil && m.editingHabitID == "" {
+			m.suggestError = ""
+			if sugg.err != nil {
+				m.suggestError = "The SYSTEM is silent; here's one from the archive."
+			}
+			if sugg.name != "" {
+				name := sugg.name
+				m.addingHabit = &name
+				m.addingFocus = 0
+			}
+		}
+		return m, nil
+	}
+	if _, ok := msg.(flashEndMsg); ok {
+		if !m.flashUntil.IsZero() && !time.Now().Before(m.flashUntil) {
+			m.flashUntil = time.Time{}
+		}
+		return m, nil
+	}
+
+	// Forced password change
+	if m.authState == authNewPass {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			field := &m.newPassword
+			if m.loginFocus == 1 {
+				field = &m.confirmPassword
+			}
+			switch msg.String() {
+			case "ctrl+c":
+				return m.quit()
+			case "esc":
+				// Back out to the login screen; the reset stays in force
+				m.userData = nil
+				m.authState = authLogin
+				m.authError = ""
+				m.loginFocus = 0
+			case "tab":
+				m.loginFocus = 1 - m.loginFocus
+			case "enter":
+				if m.loginFocus == 0 {
+					m.loginFocus = 1
+					return m, nil
+				}
+				if m.newPassword != m.confirmPassword {
+					m.authError = "passwords do not match"
+					m.confirmPassword = ""
+					return m, nil
+				}
+				if err := m.userData.SetPassword(m.newPassword); err != nil {
+					m.authError = authErrorText(err)
+					return m, nil
+				}
+				if err := store.SaveUser(m.userData); err != nil {
+					// enterMain shares the record from disk; it needs the new password there
+					m.authError = authErrorText(err)
+					return m, nil
+				}
+				m.authError = ""
+				m.newPassword, m.confirmPassword = "", ""
+				m.enterMain()
+			case "backspace":
+				*field = dropLastRune(*field)
+			default:
+				if msg.Type == tea.KeyRunes {
+					*field += string(msg.Runes)
+				}
+			}
+		}
 		return m, nil
 	}
 
@@ -99,7 +511,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.loginFocus = 0
 					return m, nil
 				}
-				return m, tea.Quit
+				return m.quit()
 			case "esc":
 				if m.authState == authRegister {
 					m.authState = authLogin
@@ -116,22 +528,20 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if m.authState == authLogin {
 						u, err := store.AuthUser(m.loginUsername, m.loginPassword)
 						if err != nil {
-							m.authError = err.Error()
+							m.authError = authErrorText(err)
 							return m, nil
 						}
-						m.userData = u
-						m.authState = authMain
-						m.loginPassword = ""
+						m.loggedIn(u)
 					} else {
 						u, err := store.CreateUser(m.loginUsername, m.loginPassword)
 						if err != nil {
-							m.authError = err.Error()
+							m.authError = authErrorText(err)
 							return m, nil
 						}
 						m.userData = u
-						m.authState = authMain
 						m.loginUsername = ""
 						m.loginPassword = ""
+						m.enterMain()
 					}
 					return m, nil
 				}
@@ -146,7 +556,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				return m, nil
 			case "r":
-				if m.authState == authLogin {
+				// With registration closed, r is just a letter of the username
+				if m.authState == authLogin && store.AllowRegister {
 					m.authState = authRegister
 					m.authError = ""
 					return m, nil
@@ -170,176 +581,1721 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m.authState == authSettings {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			switch msg.String() {
+			switch navKey(m.keymap, msg.String()) {
 			case "ctrl+c", "q":
-				return m, tea.Quit
+				return m.quit()
 			case "esc":
 				// Cancel and return to main
 				m.authState = authMain
 				m.settingsSaved = false
+				m.settingsErr = ""
 				return m, nil
 			case "enter":
-				// Save and return to main
-				if err := m.userData.UpdateDayResetHour(m.settingsResetHour); err == nil {
-					_ = store.SaveUser(m.userData)
-					m.settingsSaved = true
-					m.lastToast = "Settings saved!"
+				// Save and return to main. Nothing changes unless every
+				// field is valid; otherwise the error stays on screen.
+				err := m.userData.UpdateSettings(store.Settings{
+					DayResetHour:    m.settingsResetHour,
+					Keymap:          m.settingsKeymap,
+					Theme:           m.settingsTheme,
+					MuteBell:        m.settingsMuteBell,
+					HardcoreMode:    m.settingsHardcore,
+					ShowTotalEXP:    m.settingsTotalEXP,
+					HideNudge:       !m.settingsNudge,
+					HideAlmostThere: !m.settingsAlmost,
+					QuietStart:      m.settingsQuietFrom,
+					QuietEnd:        m.settingsQuietTo,
+					CompleteKey:     m.settingsComplete,
+					QuestEXP:        m.settingsQuestEXP,
+					DateFormat:      m.settingsDates,
+				})
+				if err != nil {
+					m.settingsErr = "Settings not saved: " + strings.ReplaceAll(err.Error(), "\n", "; ")
+					return m, nil
 				}
+				m.keymap = m.userData.Keymap
+				m.save()
+				m.settingsSaved = true
+				m.settingsErr = ""
+				m.lastToast = "Settings saved!"
 				m.authState = authMain
 				return m, nil
-			case "up", "k":
-				// Increment hour with wraparound
-				m.settingsResetHour++
-				if m.settingsResetHour > 23 {
-					m.settingsResetHour = 0
-				}
+			case "T":
+				// Manage API tokens; the unsaved settings stay as they are
+				m.tokenCursor = 0
+				m.tokenLabel = nil
+				m.newToken = ""
+				m.confirmRevoke = false
+				m.tokenErr = ""
+				m.authState = authTokens
 				return m, nil
-			case "down", "j":
-				// Decrement hour with wraparound
-				m.settingsResetHour--
-				if m.settingsResetHour < 0 {
-					m.settingsResetHour = 23
-				}
+			case "R":
+				// Reset everything; the unsaved settings stay as they are
+				m.resetConfirm = ""
+				m.resetErr = ""
+				m.authState = authReset
+				return m, nil
+			case "tab":
+				m.settingsFocus = (m.settingsFocus + 1) % settingsFieldCount
+				return m, nil
+			case "shift+tab":
+				m.settingsFocus = (m.settingsFocus + settingsFieldCount - 1) % settingsFieldCount
+				return m, nil
+			case "up":
+				m.adjustSetting(1)
+				return m, nil
+			case "down":
+				m.adjustSetting(-1)
 				return m, nil
 			}
 		}
 		return m, nil
 	}
 
-	// Main app
-	switch msg := msg.(type) {
-	case tea.KeyMsg:
-		if m.addingHabit != nil {
-			switch msg.String() {
-			case "enter":
-				name := strings.TrimSpace(*m.addingHabit)
-				if name != "" {
-					m.userData.AddHabit(name)
-					_ = store.SaveUser(m.userData)
-				}
-				m.addingHabit = nil
+	// Weekly report view
+	if m.authState == authReport {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch navKey(m.keymap, msg.String()) {
+			case "ctrl+c", "q":
+				return m.quit()
+			case "esc", "w":
+				m.authState = authMain
 				return m, nil
-			case "esc":
-				m.addingHabit = nil
+			case "left":
+				m.reportWeekOffset--
 				return m, nil
-			case "backspace":
-				if len(*m.addingHabit) > 0 {
-					s := (*m.addingHabit)[:len(*m.addingHabit)-1]
-					m.addingHabit = &s
+			case "right":
+				if m.reportWeekOffset < 0 {
+					m.reportWeekOffset++
 				}
 				return m, nil
-			default:
-				if len(msg.String()) == 1 && msg.Type == tea.KeyRunes {
-					s := *m.addingHabit + msg.String()
-					m.addingHabit = &s
-				}
+			}
+		}
+		return m, nil
+	}
+
+	// Leaderboard view
+	if m.authState == authRanks {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.String() {
+			case "ctrl+c", "q":
+				return m.quit()
+			case "esc", "r":
+				m.authState = authMain
 				return m, nil
 			}
 		}
+		return m, nil
+	}
 
-		switch msg.String() {
-		case "ctrl+c", "q":
-			return m, tea.Quit
-		case "up", "k":
-			m.lastToast = ""
-			if m.cursor > 0 {
-				m.cursor--
+	// Activity log view
+	if m.authState == authActivity {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch navKey(m.keymap, msg.String()) {
+			case "ctrl+c", "q":
+				return m.quit()
+			case "esc", "L":
+				m.authState = authMain
+			case "up":
+				if m.activityScroll > 0 {
+					m.activityScroll--
+				}
+			case "down":
+				if m.activityScroll < len(m.activity)-activityWindow {
+					m.activityScroll++
+				}
 			}
-		case "down", "j":
-			m.lastToast = ""
-			if m.cursor < len(m.userData.Habits)-1 {
-				m.cursor++
+		}
+		return m, nil
+	}
+
+	// Help page
+	if m.authState == authHelp {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			plain := lipgloss.NewStyle()
+			last := max(len(renderMarkdown(m.help, helpWidth, plain, plain, plain, plain))-m.helpWindow(), 0)
+			switch navKey(m.keymap, msg.String()) {
+			case "ctrl+c", "q":
+				return m.quit()
+			case "esc", "?":
+				m.authState = authMain
+			case "up":
+				m.helpScroll = max(m.helpScroll-1, 0)
+			case "down":
+				m.helpScroll = min(m.helpScroll+1, last)
+			case "pgup":
+				m.helpScroll = max(m.helpScroll-m.helpWindow(), 0)
+			case "pgdown", " ":
+				m.helpScroll = min(m.helpScroll+m.helpWindow(), last)
 			}
-		case " ":
-			if len(m.userData.Habits) > 0 && m.cursor >= 0 && m.cursor < len(m.userData.Habits) {
-				h := m.userData.Habits[m.cursor]
-				gainedEXP, leveledUp := m.userData.ToggleToday(h.ID)
-				m.userData.UpdateStreak() // Update streak after toggling
-				_ = store.SaveUser(m.userData)
-				if leveledUp {
-					// Async call to Gemini API for stat allocation
-					m.lastToast = "LEVEL UP! Allocating stats..."
-					m.pendingLevelUp = true
-					habits := m.userData.GetHabitNames()
-					level := m.userData.Level
-					return m, func() tea.Msg {
-						stats, _ := gemini.GetLevelUpStats(habits, level)
-						return levelUpStatsMsg{stats: stats}
-					}
-				} else if gainedEXP {
-					m.lastToast = "The conditions have been met. +10 EXP"
+		}
+		return m, nil
+	}
+
+	// Stats view
+	if m.authState == authStats {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "ctrl+c", "q":
+				return m.quit()
+			case "esc", "t":
+				m.lastToast = ""
+				m.authState = authMain
+			case "b":
+				if err := m.userData.BuyShield(); err != nil {
+					m.lastToast = err.Error()
 				} else {
-					m.lastToast = ""
+					m.lastToast = fmt.Sprintf("🛡 Shield acquired. -%d EXP", store.ShieldCost)
+					m.save()
 				}
 			}
-		case "a":
-			m.lastToast = ""
-			s := ""
-			m.addingHabit = &s
-		case "d", "x":
-			m.lastToast = ""
-			if len(m.userData.Habits) > 0 && m.cursor >= 0 && m.cursor < len(m.userData.Habits) {
-				m.userData.RemoveHabit(m.cursor)
-				if m.cursor >= len(m.userData.Habits) {
-					m.cursor = len(m.userData.Habits) - 1
-				}
-				if m.cursor < 0 {
+		}
+		return m, nil
+	}
+
+	// API tokens view: create and revoke tokens for the HTTP API
+	if m.authState == authTokens {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return m.updateTokens(msg)
+		}
+		return m, nil
+	}
+
+	// Reset view: type the hunter name to export, then wipe all progress
+	if m.authState == authReset {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "ctrl+c":
+				return m.quit()
+			case "esc":
+				m.authState = authSettings
+			case "enter":
+				switch {
+				case store.IsDemo(m.userData.Username):
+					m.resetErr = "The demo account can't be reset."
+				case m.pendingLevelUp:
+					m.resetErr = "Wait for your level-up stats to arrive first."
+				case strings.TrimSpace(m.resetConfirm) != m.userData.Username:
+					m.resetErr = "Type your hunter name exactly to confirm."
+				default:
+					path, err := store.ResetWithExport(m.userData)
+					if err != nil {
+						log.Printf("reset %s: %v", m.userData.Username, err)
+						m.resetErr = "Could not save a backup, so nothing was reset."
+						return m, nil
+					}
 					m.cursor = 0
+					m.lastToast = "Fresh start. Your old progress was backed up as " + filepath.Base(path) + "."
+					m.authState = authMain
+					m.save()
+				}
+			case "backspace":
+				m.resetConfirm = dropLastRune(m.resetConfirm)
+			default:
+				if msg.Type == tea.KeyRunes && len([]rune(m.resetConfirm)) < maxCompareNameRunes {
+					m.resetConfirm += string(msg.Runes)
 				}
-				_ = store.SaveUser(m.userData)
 			}
-		case "s":
-			// Open settings
-			m.lastToast = ""
-			m.settingsResetHour = m.userData.DayResetHour
-			m.settingsSaved = false
-			m.authState = authSettings
 		}
+		return m, nil
 	}
 
-	return m, nil
-}
-
-// renderTimeBar creates a progress bar showing time until next reset
-func renderTimeBar(timeUntil time.Duration, accent, dim, reward lipgloss.Style) string {
-	totalHours := 24.0
-	hoursLeft := timeUntil.Hours()
-	minutesLeft := int(timeUntil.Minutes()) % 60
-
-	// Calculate progress (0 to 24 blocks)
-	barWidth := 24
-	filledBlocks := int((hoursLeft / totalHours) * float64(barWidth))
-	if filledBlocks < 0 {
-		filledBlocks = 0
+	// Archive view: restore a quest to the active list
+	if m.authState == authArchive {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch navKey(m.keymap, msg.String()) {
+			case "ctrl+c", "q":
+				return m.quit()
+			case "esc", "A":
+				m.authState = authMain
+			case "up":
+				if m.archiveCursor > 0 {
+					m.archiveCursor--
+				}
+			case "down":
+				if m.archiveCursor < len(m.userData.Archived)-1 {
+					m.archiveCursor++
+				}
+			case " ", "enter":
+				if m.archiveCursor < len(m.userData.Archived) {
+					h := m.userData.Archived[m.archiveCursor]
+					if err := m.userData.RestoreHabit(h.ID); err != nil {
+						m.lastToast = err.Error()
+						return m, nil
+					}
+					m.lastToast = fmt.Sprintf("Restored '%s'.", truncateQuestName(h.Name, maxQuestNameRunes))
+					m.archiveCursor = max(min(m.archiveCursor, len(m.userData.Archived)-1), 0)
+					m.save()
+				}
+			}
+		}
+		return m, nil
 	}
-	if filledBlocks > barWidth {
-		filledBlocks = barWidth
+
+	// Compare view: type a hunter's name, then see them side by side
+	if m.authState == authCompare {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			if m.compareWith != nil {
+				switch msg.String() {
+				case "ctrl+c", "q":
+					return m.quit()
+				case "esc", "enter":
+					// Back to the name prompt to compare someone else
+					m.compareWith = nil
+					m.compareName = ""
+				}
+				return m, nil
+			}
+			switch msg.String() {
+			case "ctrl+c":
+				return m.quit()
+			case "esc":
+				m.authState = authMain
+			case "enter":
+				p, err := store.PublicProfile(m.compareName)
+				switch {
+				case err == nil:
+					m.compareWith = p
+					m.compareErr = ""
+				case errors.Is(err, store.ErrUsernameRequired):
+					m.compareErr = "Enter a hunter's name."
+				case errors.Is(err, store.ErrUserNotFound):
+					m.compareErr = fmt.Sprintf("No hunter named %q.", strings.TrimSpace(m.compareName))
+				default:
+					log.Printf("compare %s: %v", m.compareName, err)
+					m.compareErr = "Could not load that hunter."
+				}
+			case "backspace":
+				m.compareName = dropLastRune(m.compareName)
+			default:
+				if msg.Type == tea.KeyRunes && len([]rune(m.compareName)) < maxCompareNameRunes {
+					m.compareName += msg.String()
+				}
+			}
+		}
+		return m, nil
 	}
 
-	bar := strings.Repeat("█", filledBlocks) + strings.Repeat("░", barWidth-filledBlocks)
-	timeStr := fmt.Sprintf("%dh %dm until reset", int(hoursLeft), minutesLeft)
+	// Quest detail view
+	if m.authState == authDetail && m.addingHabit == nil {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			h, ok := m.userData.HabitByID(m.detailHabitID)
+			if !ok {
+				m.authState = authMain
+				return m, nil
+			}
+			switch questKey(m.userData.CompleteKey, msg.String()) {
+			case "ctrl+c", "q":
+				return m.quit()
+			case "esc", "enter":
+				m.authState = authMain
+				return m, nil
+			case "e":
+				m.openHabitForm(h)
+				return m, nil
+			case "c":
+				m.historyCursor = 0
+				m.authState = authHistory
+				return m, nil
+			case " ":
+				return m.toggleQuest(h)
+			}
+		}
+		return m, nil
+	}
 
-	return accent.Render("Time ") + dim.Render("[") + reward.Render(bar) + dim.Render("] ") + dim.Render(timeStr)
-}
-
-// Solo Leveling–inspired colors with enhanced palette
-func soloStyles(r *lipgloss.Renderer) (systemTitle, accent, dim, reward, errStyle, toastStyle lipgloss.Style, boxBorder lipgloss.Style) {
-	systemBlue := lipgloss.Color("63") // purple-blue (Solo Leveling system)
-	dimGray := lipgloss.Color("245")
-	gold := lipgloss.Color("220")
-	red := lipgloss.Color("203")
-	systemTitle = r.NewStyle().Bold(true).Foreground(systemBlue)
-	accent = r.NewStyle().Foreground(systemBlue)
-	dim = r.NewStyle().Foreground(dimGray)
-	reward = r.NewStyle().Bold(true).Foreground(gold)
-	errStyle = r.NewStyle().Foreground(red)
-	toastStyle = r.NewStyle().Bold(true).Foreground(gold).Padding(0, 1)
-	boxBorder = r.NewStyle().
-		Border(lipgloss.DoubleBorder()).
-		BorderForeground(systemBlue).
-		Padding(0, 2)
-	return
-}
+	// Quest history (backfill) view
+	if m.authState == authHistory {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			h, ok := m.userData.HabitByID(m.detailHabitID)
+			if !ok {
+				m.authState = authMain
+				return m, nil
+			}
+			switch navKey(m.keymap, msg.String()) {
+			case "ctrl+c", "q":
+				return m.quit()
+			case "esc", "c":
+				m.lastToast = ""
+				m.authState = authDetail
+			case "up":
+				m.lastToast = ""
+				if m.historyCursor > 0 {
+					m.historyCursor--
+				}
+			case "down":
+				m.lastToast = ""
+				if m.historyCursor < store.BackfillDays {
+					m.historyCursor++
+				}
+			case " ":
+				if m.historyCursor == 0 {
+					return m.toggleQuest(h)
+				}
+				day := m.historyDay(m.historyCursor)
+				if m.userData.LockedIn(h.ID, day) {
+					m.lastToast = lockedToast(h)
+					return m, nil
+				}
+				if m.userData.UncheckCostsLevel(h.ID, day) {
+					m.confirmUncheck, m.confirmDay = h.ID, day
+					m.lastToast = uncheckLevelPrompt
+					return m, nil
+				}
+				return m.applyBackfill(h, day)
+			}
+		}
+		return m, nil
+	}
+
+	// First-run tutorial overlay
+	if m.tutorialStep >= 0 {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch navKey(m.keymap, msg.String()) {
+			case "ctrl+c":
+				return m.quit()
+			case "right", "down", "tab":
+				if m.tutorialStep < len(tutorialPages)-1 {
+					m.tutorialStep++
+				}
+			case "left", "up", "shift+tab":
+				if m.tutorialStep > 0 {
+					m.tutorialStep--
+				}
+			case "esc", "enter":
+				m.tutorialStep = -1
+				m.userData.MarkTutorialSeen()
+				m.save()
+			}
+		}
+		return m, nil
+	}
+
+	// Prestige confirmation prompt
+	if m.confirmPrestige {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			m.confirmPrestige = false
+			m.lastToast = ""
+			if msg.String() == "y" {
+				if err := m.userData.Prestige(); err != nil {
+					m.lastToast = err.Error()
+				} else {
+					m.lastToast = fmt.Sprintf("PRESTIGE %d! You have been reborn stronger. All stats +%d.", m.userData.PrestigeCount, store.PrestigeStatBonus)
+					m.save()
+				}
+			}
+		}
+		return m, nil
+	}
+
+	// Main app
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.addingHabit != nil {
+			switch msg.String() {
+			case "enter":
+				if m.editingHabitID == "" && strings.Contains(*m.addingHabit, "\n") {
+					added, skipped := m.userData.AddHabits(strings.Split(*m.addingHabit, "\n"))
+					m.lastToast = bulkAddToast(added, skipped)
+					m.addingHabit = nil
+					m.save()
+					return m, nil
+				}
+				name := strings.TrimSpace(*m.addingHabit)
+				if name != "" {
+					changes := store.Habit{Name: name, Note: m.addingNote, Icon: m.addingIcon, Color: m.addingColor, DependsOn: m.addingDependsOn, WeeklyTarget: m.addingWeekly}
+					if m.addingReminder >= 0 {
+						hour := m.addingReminder
+						changes.ReminderHour = &hour
+					}
+					var err error
+					if m.editingHabitID != "" {
+						err = m.userData.EditHabit(m.editingHabitID, changes)
+					} else {
+						h := m.userData.AddHabit(name)
+						err = m.userData.EditHabit(h.ID, changes)
+					}
+					if errors.Is(err, store.ErrChainCycle) {
+						m.lastToast = "That quest already comes after this one; chains can't loop."
+					}
+					m.save()
+				}
+				m.addingHabit = nil
+				return m, nil
+			case "esc":
+				m.addingHabit = nil
+				return m, nil
+			case "ctrl+g":
+				// Ask the SYSTEM for a quest; only when adding a new one
+				if m.editingHabitID != "" || m.suggesting {
+					return m, nil
+				}
+				m.suggesting = true
+				m.suggestError = ""
+				existing := m.userData.GetHabitNames()
+				ctx := m.ctx
+				if ctx == nil {
+					ctx = context.Background()
+				}
+				return m, func() tea.Msg {
+					name, err := gemini.SuggestQuestCtx(ctx, existing)
+					return questSuggestionMsg{name: name, err: err}
+				}
+			case "tab":
+				m.addingFocus = (m.addingFocus + 1) % habitFormFields
+				return m, nil
+			case "shift+tab":
+				m.addingFocus = (m.addingFocus + habitFormFields - 1) % habitFormFields
+				return m, nil
+			case "up", "down":
+				delta := 1
+				if msg.String() == "up" {
+					delta = -1
+				}
+				if m.addingFocus == 2 {
+					// Cycle the reminder through none, 00:00 ... 23:00
+					m.addingReminder = (m.addingReminder+1+delta+25)%25 - 1
+					return m, nil
+				}
+				if m.addingFocus == 5 {
+					// Cycle the weekly target through every day, 1x ... MaxWeeklyTarget x a week
+					n := store.MaxWeeklyTarget + 1
+					m.addingWeekly = (m.addingWeekly + delta + n) % n
+					return m, nil
+				}
+				if m.addingFocus == 4 {
+					// Cycle the color through none and the palette
+					i := 0
+					for j, c := range store.HabitColors {
+						if c == m.addingColor {
+							i = j
+						}
+					}
+					n := len(store.HabitColors)
+					m.addingColor = store.HabitColors[(i+delta+n)%n]
+					return m, nil
+				}
+				if m.addingFocus == 3 {
+					// Cycle the prerequisite through none and every quest it can follow
+					opts := append([]store.Habit{{}}, m.userData.PrerequisiteOptions(m.editingHabitID)...)
+					i := max(habitIndex(opts, m.addingDependsOn), 0)
+					m.addingDependsOn = opts[(i+delta+len(opts))%len(opts)].ID
+					return m, nil
+				}
+				// Cycle the quest icon
+				i := 0
+				for j, icon := range store.HabitIcons {
+					if icon == m.addingIcon {
+						i = j
+					}
+				}
+				n := len(store.HabitIcons)
+				m.addingIcon = store.HabitIcons[(i+delta+n)%n]
+				return m, nil
+			case "backspace":
+				if m.addingFocus == 5 {
+					m.addingWeekly = 0
+				} else if m.addingFocus == 4 {
+					m.addingColor = ""
+				} else if m.addingFocus == 3 {
+					m.addingDependsOn = ""
+				} else if m.addingFocus == 2 {
+					m.addingReminder = -1
+				} else if m.addingFocus == 1 {
+					m.addingNote = dropLastRune(m.addingNote)
+				} else if len(*m.addingHabit) > 0 {
+					s := (*m.addingHabit)[:len(*m.addingHabit)-1]
+					m.addingHabit = &s
+				}
+				return m, nil
+			default:
+				if msg.Paste && msg.Type == tea.KeyRunes && m.addingFocus == 0 {
+					// A paste of several lines adds one quest per line on Enter
+					s := *m.addingHabit + pastedLines(string(msg.Runes), m.editingHabitID == "")
+					m.addingHabit = &s
+					return m, nil
+				}
+				if len(msg.String()) == 1 && msg.Type == tea.KeyRunes && m.addingFocus < 2 {
+					if m.addingFocus == 1 {
+						if len([]rune(m.addingNote)) < store.MaxNoteRunes {
+							m.addingNote += msg.String()
+						}
+					} else {
+						s := *m.addingHabit + msg.String()
+						m.addingHabit = &s
+					}
+				}
+				return m, nil
+			}
+		}
+
+		// Any key ends the level-up flash
+		m.flashUntil = time.Time{}
+
+		switch questKey(m.userData.CompleteKey, navKey(m.keymap, msg.String())) {
+		case "ctrl+c", "q":
+			return m.quit()
+		case "ctrl+s":
+			// Write now instead of waiting for the debounce
+			m.lastToast = "Progress saved."
+			if err := m.saver.flush(); err != nil {
+				m.lastToast = saveFailedToast
+			}
+		case "up":
+			m.lastToast = ""
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down":
+			m.lastToast = ""
+			if m.cursor < len(m.userData.Habits)-1 {
+				m.cursor++
+			}
+		case "z":
+			if h, ok := m.selectedHabit(); ok {
+				m.snooze(h, time.Now())
+			}
+		case "f":
+			if h, ok := m.selectedHabit(); ok {
+				return m.toggleFocus(h, time.Now())
+			}
+		case "C":
+			if len(m.userData.Habits) > 0 {
+				return m.completeAll()
+			}
+		case "*":
+			// Spotlight the selected quest for the rest of the week, or turn it off
+			h, ok := m.selectedHabit()
+			if !ok || h.ID == store.WelcomeQuestID {
+				break
+			}
+			if spot, ok := m.userData.Spotlight(); ok && spot.ID == h.ID {
+				m.userData.SetSpotlight("")
+				m.lastToast = "Spotlight off until next week."
+			} else {
+				m.userData.SetSpotlight(h.ID)
+				m.lastToast = spotlightToast(h)
+			}
+			m.save()
+		case "F":
+			if m.focusHabitID != "" {
+				m.cancelFocus()
+				m.lastToast = "Focus timer cancelled."
+			}
+		case "g", "home":
+			m.lastToast = ""
+			m.cursor = 0
+		case "G", "end":
+			m.lastToast = ""
+			m.cursor = max(len(m.userData.Habits)-1, 0)
+		case " ":
+			if h, ok := m.selectedHabit(); ok {
+				return m.toggleQuest(h)
+			}
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			// Toggle the Nth quest on screen, leaving the cursor where it is
+			n := int(msg.String()[0] - '1')
+			if visible := m.visibleHabits(); n < len(visible) {
+				return m.toggleQuest(visible[n])
+			}
+		case "enter":
+			// Open quest detail
+			if h, ok := m.selectedHabit(); ok {
+				m.lastToast = ""
+				m.detailHabitID = h.ID
+				m.authState = authDetail
+			}
+		case "e":
+			if h, ok := m.selectedHabit(); ok {
+				m.lastToast = ""
+				m.openHabitForm(h)
+			}
+		case "a":
+			m.lastToast = ""
+			if m.userData.AtHabitLimit() {
+				m.lastToast = fmt.Sprintf("Quest limit reached (%d). Archive or delete a quest first.", store.MaxHabits)
+				break
+			}
+			m.openHabitForm(store.Habit{})
+		case "d", "x":
+			m.lastToast = ""
+			if h, ok := m.selectedHabit(); ok {
+				_, forfeited := m.userData.RemoveHabit(habitIndex(m.userData.Habits, h.ID))
+				if h.ID == m.focusHabitID {
+					m.cancelFocus()
+				}
+				if m.cursor >= len(m.userData.Habits) {
+					m.cursor = len(m.userData.Habits) - 1
+				}
+				if m.cursor < 0 {
+					m.cursor = 0
+				}
+				// The deletion may have left only completed quests
+				streak := m.userData.CurrentStreak
+				penalty, _ := m.userData.UpdateStreak()
+				m.save()
+				switch {
+				case penalty > 0:
+					m.lastToast = penaltyToast(penalty)
+				case forfeited:
+					m.lastToast = forfeitToast
+				case m.userData.CurrentStreak > streak:
+					next, cmd := m.afterToggle(false, 0, false)
+					if next.lastToast == "" {
+						next.lastToast = fmt.Sprintf("Every quest is done. Perfect day! 🔥 %d", next.userData.CurrentStreak)
+					}
+					return next, cmd
+				}
+			}
+		case "o":
+			// Cycle the sort mode, keeping the selected quest under the cursor
+			selected, _ := m.selectedHabit()
+			_ = m.userData.UpdateSortMode(store.NextSortMode(m.userData.SortMode))
+			if i := habitIndex(m.userData.SortedHabits(), selected.ID); i >= 0 {
+				m.cursor = i
+			}
+			m.lastToast = "Sorted by " + m.userData.SortMode
+			m.save()
+		case "P":
+			m.lastToast = ""
+			if m.userData.AtLevelCap() {
+				m.confirmPrestige = true
+			}
+		case "s":
+			// Open settings
+			m.lastToast = ""
+			m.settingsResetHour = m.userData.DayResetHour
+			m.settingsKeymap = m.userData.Keymap
+			m.settingsTheme = m.userData.Theme
+			m.settingsMuteBell = m.userData.MuteBell
+			m.settingsHardcore = m.userData.HardcoreMode
+			m.settingsQuietFrom = m.userData.QuietStart
+			m.settingsQuietTo = m.userData.QuietEnd
+			m.settingsTotalEXP = m.userData.ShowTotalEXP
+			m.settingsNudge = !m.userData.HideNudge
+			m.settingsAlmost = !m.userData.HideAlmostThere
+			m.settingsComplete = m.userData.CompleteKey
+			m.settingsQuestEXP = m.userData.EffectiveQuestEXP()
+			m.settingsDates = layoutName(m.userData.DateFormat)
+			m.settingsFocus = settingsFieldResetHour
+			m.settingsSaved = false
+			m.settingsErr = ""
+			m.authState = authSettings
+		case "w":
+			// Open weekly report
+			m.lastToast = ""
+			m.reportWeekOffset = 0
+			m.authState = authReport
+		case "r":
+			// Open leaderboard
+			m.lastToast = ""
+			m.standingsErr = ""
+			st, err := store.Leaderboard(leaderboardSize, m.userData.Username)
+			if err != nil {
+				m.standingsErr = "Could not load the leaderboard."
+			}
+			m.standings = st
+			m.authState = authRanks
+		case "?":
+			// Open the help page
+			m.lastToast = ""
+			m.help = loadHelp()
+			m.helpScroll = 0
+			m.authState = authHelp
+		case "L":
+			// Open activity log
+			m.lastToast = ""
+			m.activityErr = ""
+			m.activityScroll = 0
+			events, err := store.ReadAudit(m.userData.Username, activityLoad)
+			if err != nil {
+				m.activityErr = "Could not read your activity log."
+			}
+			m.activity = make([]store.AuditEvent, len(events))
+			for i, ev := range events {
+				m.activity[len(events)-1-i] = ev
+			}
+			m.authState = authActivity
+		case "t":
+			// Open stats chart
+			m.lastToast = ""
+			m.authState = authStats
+		case "A":
+			// Open archived quests
+			m.lastToast = ""
+			m.archiveCursor = 0
+			m.authState = authArchive
+		case "c":
+			// Compare with another hunter
+			m.lastToast = ""
+			m.compareName = ""
+			m.compareWith = nil
+			m.compareErr = ""
+			m.authState = authCompare
+		}
+	}
+
+	return m, nil
+}
+
+// updateTokens handles a key in the API tokens view
+func (m model) updateTokens(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m.quit()
+	}
+	if m.tokenLabel != nil {
+		// Typing a label for a new token
+		switch msg.String() {
+		case "esc":
+			m.tokenLabel = nil
+		case "enter":
+			token, err := store.GenerateAPIToken(m.userData, *m.tokenLabel)
+			if err != nil {
+				m.tokenErr = err.Error()
+				return m, nil
+			}
+			m.tokenLabel = nil
+			m.tokenErr = ""
+			m.newToken = token
+			m.tokenCursor = len(m.userData.APITokens) - 1
+			m.save()
+		case "backspace":
+			s := dropLastRune(*m.tokenLabel)
+			m.tokenLabel = &s
+		default:
+			if msg.Type == tea.KeyRunes && len([]rune(*m.tokenLabel)) < store.MaxTokenLabelRunes {
+				s := *m.tokenLabel + msg.String()
+				m.tokenLabel = &s
+			}
+		}
+		return m, nil
+	}
+	if m.confirmRevoke {
+		m.confirmRevoke = false
+		if msg.String() == "y" && m.tokenCursor < len(m.userData.APITokens) {
+			if err := m.userData.RevokeAPIToken(m.userData.APITokens[m.tokenCursor].ID); err != nil {
+				m.tokenErr = err.Error()
+				return m, nil
+			}
+			m.tokenCursor = max(min(m.tokenCursor, len(m.userData.APITokens)-1), 0)
+			m.save()
+		}
+		return m, nil
+	}
+	switch navKey(m.keymap, msg.String()) {
+	case "q":
+		return m.quit()
+	case "esc", "T":
+		m.newToken = ""
+		m.authState = authSettings
+	case "up":
+		if m.tokenCursor > 0 {
+			m.tokenCursor--
+		}
+	case "down":
+		if m.tokenCursor < len(m.userData.APITokens)-1 {
+			m.tokenCursor++
+		}
+	case "n":
+		s := ""
+		m.tokenLabel = &s
+		m.newToken = ""
+		m.tokenErr = ""
+	case "d", "x":
+		if m.tokenCursor < len(m.userData.APITokens) {
+			m.newToken = ""
+			m.confirmRevoke = true
+		}
+	}
+	return m, nil
+}
+
+// selectedHabit returns the quest under the cursor in the displayed order
+func (m model) selectedHabit() (store.Habit, bool) {
+	habits := m.userData.SortedHabits()
+	if m.cursor < 0 || m.cursor >= len(habits) {
+		return store.Habit{}, false
+	}
+	return habits[m.cursor], true
+}
+
+// visibleHabits returns the quests on screen in the displayed order: all of
+// them, or in the compact layout the ones that fit around the cursor
+func (m model) visibleHabits() []store.Habit {
+	habits := m.userData.SortedHabits()
+	if m.compact() {
+		start, end := m.compactWindow(m.compactHeader(), len(habits))
+		return habits[start:end]
+	}
+	return habits
+}
+
+// habitIndex returns the position of the habit with id in habits, or -1
+func habitIndex(habits []store.Habit, id string) int {
+	for i, h := range habits {
+		if h.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// uncheckLevelPrompt asks before an uncheck that would drop a level
+const uncheckLevelPrompt = "This will cost you a level — uncheck anyway? [y/n]"
+
+// forfeitToast explains why deleting a quest still open today doesn't
+// complete the day
+const forfeitToast = "Deleted an open quest, so today can't count as a perfect day."
+
+// lockedToast explains why a locked-in completion can't be unchecked: past
+// the grace period, or final under the locked uncheck policy
+func lockedToast(h store.Habit) string {
+	name := truncateQuestName(h.Name, maxQuestNameRunes)
+	switch {
+	case store.UncheckPolicy != store.UncheckLocked:
+		return fmt.Sprintf("🔒 '%s' is locked in for today.", name)
+	case store.UncheckGrace > 0:
+		return fmt.Sprintf("🔒 '%s' is final: completions can't be unchecked after %s on this server.", name, shortDuration(store.UncheckGrace))
+	}
+	return fmt.Sprintf("🔒 '%s' is final: completions can't be unchecked on this server.", name)
+}
+
+// chainedToast names the quest that has to be done before h
+func (m model) chainedToast(h store.Habit) string {
+	pre, _ := m.userData.Prerequisite(h)
+	return fmt.Sprintf("🔒 Complete '%s' first.", truncateQuestName(pre.Name, maxQuestNameRunes))
+}
+
+// toggleQuest toggles h for today, first asking when unchecking it would
+// cost a level
+func (m model) toggleQuest(h store.Habit) (model, tea.Cmd) {
+	if m.userData.LockedIn(h.ID, m.userData.TodayKey()) {
+		m.lastToast = lockedToast(h)
+		return m, nil
+	}
+	if m.userData.ChainLocked(h.ID, m.userData.TodayKey()) {
+		m.lastToast = m.chainedToast(h)
+		return m, nil
+	}
+	if m.userData.UncheckCostsLevel(h.ID, m.userData.TodayKey()) {
+		m.confirmUncheck, m.confirmDay = h.ID, ""
+		m.lastToast = uncheckLevelPrompt
+		return m, nil
+	}
+	return m.applyToggle(h)
+}
+
+// completeAll completes every quest still open today in one batch, skipping
+// any a chain keeps locked. The welcome quest is left to its own toggle.
+func (m model) completeAll() (model, tea.Cmd) {
+	var open []string
+	for _, h := range m.userData.Habits {
+		if h.ID != store.WelcomeQuestID && !m.userData.CompletedToday(h.ID) {
+			open = append(open, h.ID)
+		}
+	}
+	if len(open) == 0 {
+		m.lastToast = "Every quest is already done today."
+		return m, nil
+	}
+	exp, levels := m.userData.ToggleMany(open, true)
+	left := 0
+	for _, id := range open {
+		if !m.userData.CompletedToday(id) {
+			left++
+		}
+	}
+	if left == len(open) {
+		m.lastToast = "Nothing to complete: the open quests are locked."
+		return m, nil
+	}
+	if m.focusHabitID != "" && m.userData.CompletedToday(m.focusHabitID) {
+		m.cancelFocus()
+	}
+	penalty, shielded := m.userData.UpdateStreak()
+	m.expireSnoozes(time.Now())
+	m.save()
+	if penalty > 0 {
+		m.lastToast = penaltyToast(penalty)
+		return m, nil
+	}
+	next, cmd := m.afterToggle(false, 0, levels > 0)
+	if next.lastToast == "" {
+		next.lastToast = fmt.Sprintf("Completed %d quests. +%d EXP", len(open)-left, exp)
+		if left > 0 {
+			next.lastToast += fmt.Sprintf(" (%d still locked)", left)
+		}
+	}
+	if shielded > 0 {
+		next.lastToast = strings.TrimSpace(shieldToast(shielded) + "  " + next.lastToast)
+	}
+	return next, cmd
+}
+
+// applyBackfill toggles h on a past day and rebuilds the streak
+func (m model) applyBackfill(h store.Habit, day string) (model, tea.Cmd) {
+	before := m.userData.EXP
+	gainedEXP, leveledUp, err := m.userData.ToggleOnDay(h.ID, day)
+	if errors.Is(err, store.ErrCompletionLocked) {
+		m.lastToast = lockedToast(h)
+		return m, nil
+	}
+	if errors.Is(err, store.ErrQuestChained) {
+		m.lastToast = m.chainedToast(h)
+		return m, nil
+	}
+	if err != nil {
+		m.lastToast = err.Error()
+		return m, nil
+	}
+	m.userData.RecomputeStreak()
+	m.save()
+	return m.afterToggle(gainedEXP, m.userData.EXP-before, leveledUp)
+}
+
+// applyToggle toggles h for today, updates the streak and toast, and starts
+// the level-up flow when the toggle crosses a level
+func (m model) applyToggle(h store.Habit) (model, tea.Cmd) {
+	before := m.userData.EXP
+	gainedEXP, leveledUp, err := m.userData.ToggleToday(h.ID)
+	if errors.Is(err, store.ErrQuestChained) {
+		m.lastToast = m.chainedToast(h)
+		return m, nil
+	}
+	if err != nil {
+		m.lastToast = lockedToast(h)
+		return m, nil
+	}
+	penalty, shielded := m.userData.UpdateStreak() // Update streak after toggling
+	m.expireSnoozes(time.Now())
+	m.save()
+	if penalty > 0 {
+		m.lastToast = penaltyToast(penalty)
+		return m, nil
+	}
+	next, cmd := m.afterToggle(gainedEXP, m.userData.EXP-before, leveledUp)
+	if h.ID == store.WelcomeQuestID && gainedEXP {
+		// The welcome quest removed itself; keep the cursor on the list
+		next.cursor = max(min(next.cursor, len(next.userData.Habits)-1), 0)
+		if !next.pendingLevelUp {
+			next.lastToast = fmt.Sprintf("Welcome quest cleared! +%d EXP. Now press [a] to add your own.", store.WelcomeQuestBonus)
+		}
+	}
+	if shielded > 0 {
+		next.lastToast = strings.TrimSpace(shieldToast(shielded) + "  " + next.lastToast)
+	}
+	return next, cmd
+}
+
+// afterToggle awards any streak milestone the toggle reached, sets the toast,
+// and starts the level-up flow (AI stat allocation, flash, bell) when the
+// toggle or a milestone bonus crossed a level. paid is the EXP a completion
+// paid.
+func (m model) afterToggle(gainedEXP bool, paid int, leveledUp bool) (model, tea.Cmd) {
+	before := m.userData.Level
+	claimed := m.userData.CheckStreakMilestones()
+	if m.userData.Level > before {
+		leveledUp = true
+	}
+	var cmd tea.Cmd
+	if leveledUp {
+		// Async call to Gemini API for stat allocation
+		m.lastToast = "LEVEL UP! Allocating stats..."
+		m.pendingLevelUp = true
+		m.flashUntil = time.Now().Add(levelUpFlash)
+		habits := m.userData.RecentHabitNames()
+		level := m.userData.Level
+		cmd = tea.Batch(
+			func() tea.Msg {
+				stats, _ := gemini.GetLevelUpStatsCtx(m.ctx, habits, level)
+				return levelUpStatsMsg{stats: stats}
+			},
+			tea.Tick(levelUpFlash, func(time.Time) tea.Msg { return flashEndMsg{} }),
+			m.bell(),
+		)
+	} else if gainedEXP && paid > 0 {
+		m.lastToast = fmt.Sprintf("The conditions have been met. +%d EXP", paid)
+	} else if gainedEXP {
+		// Under the no-refund uncheck policy a re-check pays nothing
+		m.lastToast = "The conditions have been met. Its EXP was kept when you unchecked it."
+	} else {
+		m.lastToast = ""
+	}
+	if len(claimed) > 0 {
+		m.lastToast = milestoneToast(claimed)
+		m.save()
+	}
+	return m, cmd
+}
+
+// spotlightToast announces h as this week's spotlight
+func spotlightToast(h store.Habit) string {
+	return fmt.Sprintf("⭐ This week's spotlight: '%s' pays +%d EXP extra.", truncateQuestName(h.Name, maxQuestNameRunes), store.SpotlightBonus)
+}
+
+// milestoneToast celebrates newly claimed streak milestones, e.g.
+// "🏆 7-DAY STREAK! +50 EXP • Title: Persistent Hunter"
+func milestoneToast(claimed []store.Milestone) string {
+	parts := make([]string, len(claimed))
+	for i, ms := range claimed {
+		parts[i] = fmt.Sprintf("🏆 %d-DAY STREAK! +%d EXP", ms.Days, ms.EXP)
+	}
+	return strings.Join(parts, "  ") + " • Title: " + claimed[len(claimed)-1].Title
+}
+
+// historyDay returns the day key offset days before today
+func (m model) historyDay(offset int) string {
+	today, err := time.Parse(store.DayKeyLayout, m.userData.TodayKey())
+	if err != nil {
+		return ""
+	}
+	return today.AddDate(0, 0, -offset).Format(store.DayKeyLayout)
+}
+
+// penaltyToast is the SYSTEM's notice for a hardcore streak-break penalty
+func penaltyToast(penalty int) string {
+	return fmt.Sprintf("PENALTY: You failed to maintain your streak. -%d EXP.", penalty)
+}
+
+// shieldToast reports streak shields that covered missed days
+func shieldToast(used int) string {
+	if used == 1 {
+		return "🛡 A shield absorbed your missed day. Streak intact."
+	}
+	return fmt.Sprintf("🛡 %d shields absorbed your missed days. Streak intact.", used)
+}
+
+// authErrorText turns a login or registration error into a message for the
+// form. Unexpected errors are logged rather than shown.
+func authErrorText(err error) string {
+	switch {
+	case errors.Is(err, store.ErrUsernameRequired):
+		return "Enter a username."
+	case errors.Is(err, store.ErrUserNotFound):
+		if !store.AllowRegister {
+			return "No hunter by that name."
+		}
+		return "No hunter by that name. Press [r] to register."
+	case errors.Is(err, store.ErrRegistrationClosed):
+		return "Registration is closed on this server."
+	case errors.Is(err, store.ErrInvalidPassword):
+		return "Wrong password."
+	case errors.Is(err, store.ErrInvalidCredentials):
+		return "Wrong username or password."
+	case errors.Is(err, store.ErrUsernameTaken):
+		return "That name is taken. Choose another."
+	case errors.Is(err, store.ErrWeakPassword), errors.Is(err, store.ErrAccountLocked):
+		return err.Error()
+	}
+	log.Printf("auth: %v", err)
+	return "Something went wrong. Try again."
+}
+
+// loggedIn takes an authenticated user past the login form: to the forced
+// password change after an admin reset, otherwise into the quest log
+func (m *model) loggedIn(u *store.UserData) {
+	m.userData = u
+	m.loginPassword = ""
+	if u.MustChangePassword {
+		m.authState = authNewPass
+		m.newPassword, m.confirmPassword = "", ""
+		m.loginFocus = 0
+		return
+	}
+	m.enterMain()
+}
+
+// enterMain finishes a login: applies the user's preferences, settles any
+// broken streak, and opens the quest log
+func (m *model) enterMain() {
+	m.shareUser()
+	u := m.userData
+	m.keymap = u.Keymap
+	m.authState = authMain
+	others := m.trackSession()
+	if penalty, shielded := u.CheckStreakBreak(); penalty > 0 {
+		m.lastToast = penaltyToast(penalty)
+	} else if shielded > 0 {
+		m.lastToast = shieldToast(shielded)
+	} else if others > 0 {
+		m.lastToast = otherSessionToast
+	} else {
+		m.lastToast = glanceToast(u)
+	}
+	if archived := u.AutoArchiveStale(autoArchiveDays); len(archived) > 0 {
+		m.lastToast = archivedToast(archived)
+	}
+	if h, ok := u.CheckSpotlight(); ok {
+		m.lastToast = spotlightToast(h)
+	}
+	if rec := u.CheckSeason(); rec != nil {
+		_, season := store.CurrentSeason(time.Now())
+		m.lastToast = fmt.Sprintf("Season %d has begun! Last season you reached Lv %d; levels start over.", season, rec.Level)
+	}
+	m.restoreCursor()
+	if store.IsDemo(u.Username) {
+		m.lastToast = "Demo account — look around; nothing you change is saved."
+	}
+	if u.NeedsTutorial() {
+		m.tutorialStep = 0
+	}
+	m.save()
+}
+
+// rememberCursor notes which quest the main screen's cursor is on, by ID so
+// it still points at the same quest after sorting or deleting others.
+// enterMain puts the cursor back there. Moving the cursor never triggers a
+// save; the choice is written with the next one or on quit.
+func (m *model) rememberCursor() {
+	if m.authState != authMain || m.userData == nil {
+		return
+	}
+	if h, ok := m.selectedHabit(); ok && m.userData.SetSelectedHabit(h.ID) {
+		m.saver.markQuiet(m.userData)
+	}
+}
+
+// restoreCursor puts the cursor on the quest selected last session. One
+// deleted or archived since leaves it at the top.
+func (m *model) restoreCursor() {
+	m.cursor = max(habitIndex(m.userData.SortedHabits(), m.userData.SelectedHabit), 0)
+}
+
+// autoArchiveDays archives quests missed this many days in a row at login.
+// Set by SYSTEM_AUTO_ARCHIVE_DAYS; 0 turns it off.
+var autoArchiveDays = 0
+
+// archivedToast tells the hunter which quests went to the archive
+func archivedToast(archived []store.Habit) string {
+	if len(archived) == 1 {
+		return fmt.Sprintf("Archived '%s' after %d missed days. [A] to restore.", truncateQuestName(archived[0].Name, maxQuestNameRunes), autoArchiveDays)
+	}
+	return fmt.Sprintf("Archived %d stale quests after %d missed days. [A] to restore.", len(archived), autoArchiveDays)
+}
+
+// saveFailedToast replaces the usual toast when progress couldn't be written
+const saveFailedToast = "⚠ could not save — changes may be lost"
+
+// saveDebounce is how long changes collect before they're written, so a
+// burst of toggles costs one write. Set by SYSTEM_SAVE_DEBOUNCE; 0 writes at once.
+var saveDebounce = 500 * time.Millisecond
+
+// saveTickMsg flushes the pending save once saveDebounce has passed
+type saveTickMsg struct{}
+
+// pendingSave coalesces a session's saves. It's shared by every copy of the
+// model, so quitting or a dropped connection can flush what's still pending.
+type pendingSave struct {
+	mu        sync.Mutex
+	user      *store.UserData
+	dirty     bool
+	scheduled bool // a saveTickMsg is on its way
+	failed    bool // the last write failed; cleared by the next one that works
+	quiet     bool // changes that ride along with the next write; see markQuiet
+}
+
+// saveState is what the status box says about unsaved changes
+type saveState int
+
+const (
+	saveClean  saveState = iota // everything is written
+	saveDirty                   // changes are waiting for the debounce
+	saveFailed                  // the last write failed and changes are still pending
+)
+
+// state reports whether changes are pending and whether writing them failed
+func (p *pendingSave) state() saveState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.failed && p.dirty:
+		return saveFailed
+	case p.dirty:
+		return saveDirty
+	}
+	return saveClean
+}
+
+// mark records that user has unsaved changes
+func (p *pendingSave) mark(user *store.UserData) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.user = user
+	p.dirty = true
+}
+
+// markQuiet records that user has a change too small to be written on its
+// own, like the remembered cursor. It goes out with the next save or the
+// flush on quit or disconnect, and doesn't show as unsaved.
+func (p *pendingSave) markQuiet(user *store.UserData) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.user = user
+	p.quiet = true
+}
+
+// schedule reports whether a flush tick needs starting for pending changes
+func (p *pendingSave) schedule() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty || p.scheduled {
+		return false
+	}
+	p.scheduled = true
+	return true
+}
+
+// flush writes pending changes, if any. A failed write stays pending.
+func (p *pendingSave) flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.scheduled = false
+	if !p.dirty && !p.quiet {
+		return nil
+	}
+	if err := store.SaveUser(p.user); err != nil {
+		log.Printf("save %s: %v", p.user.Username, err)
+		p.failed = true
+		return err
+	}
+	p.dirty, p.quiet, p.failed = false, false, false
+	return nil
+}
+
+// saveIndicator shows state in the status box: "✓ saved", "● unsaved" while
+// the debounce runs, or a warning once a write failed
+func saveIndicator(state saveState, dim, errStyle lipgloss.Style) string {
+	switch state {
+	case saveDirty:
+		return dim.Render("● unsaved")
+	case saveFailed:
+		return errStyle.Render("⚠ not saved [ctrl+s] retry")
+	}
+	return dim.Render("✓ saved")
+}
+
+// save queues the user's data to be written within saveDebounce
+func (m *model) save() {
+	m.saver.mark(m.userData)
+}
+
+// quit flushes any pending save and ends the program. While a level-up's
+// stats are still being allocated it asks first instead; see confirmQuit.
+func (m model) quit() (tea.Model, tea.Cmd) {
+	if m.pendingLevelUp {
+		m.confirmQuit = true
+		return m, nil
+	}
+	return m.forceQuit()
+}
+
+// forceQuit flushes any pending save and ends the program. EXP and level are
+// already saved; only a pending stat allocation is lost.
+func (m model) forceQuit() (tea.Model, tea.Cmd) {
+	_ = m.saver.flush()
+	return m, tea.Quit
+}
+
+// glanceToast summarizes today for a returning hunter, e.g.
+// "Level 7 • 2/5 quests today • 12-day streak". Empty when there are no quests.
+func glanceToast(u *store.UserData) string {
+	if len(u.Habits) == 0 {
+		return ""
+	}
+	done, total := u.TodayProgress()
+	parts := []string{
+		fmt.Sprintf("Level %d", u.Level),
+		fmt.Sprintf("%d/%d quests today", done, total),
+	}
+	if u.CurrentStreak > 0 {
+		parts = append(parts, fmt.Sprintf("%d-day streak", u.CurrentStreak))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// lastQuestNudge calls out the one quest left for a perfect day, unless the
+// hunter turned it off
+func lastQuestNudge(u *store.UserData) string {
+	if u.HideNudge {
+		return ""
+	}
+	h, ok := u.LastQuestLeft()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("1 quest from a perfect day: %s %s", h.Icon, truncateQuestName(h.Name, maxQuestNameRunes))
+}
+
+// openHabitForm opens the add/edit form; a zero Habit means a new quest
+func (m *model) openHabitForm(h store.Habit) {
+	name := h.Name
+	m.addingHabit = &name
+	m.addingNote = h.Note
+	m.addingIcon = store.CleanIcon(h.Icon)
+	m.addingReminder = -1
+	if h.ReminderHour != nil {
+		m.addingReminder = *h.ReminderHour
+	}
+	m.addingDependsOn = h.DependsOn
+	m.addingColor = store.CleanColor(h.Color)
+	m.addingWeekly = h.WeeklyTarget
+	m.addingFocus = 0
+	m.editingHabitID = h.ID
+	m.suggesting = false
+	m.suggestError = ""
+}
+
+// bulkPreviewLines is how many pasted quest names the add form lists
+const bulkPreviewLines = 8
+
+// pastedLines cleans pasted text for the quest name field. With multi set
+// its lines are kept, one quest each; otherwise they're joined into one name.
+func pastedLines(text string, multi bool) string {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	text = strings.ReplaceAll(text, "\t", " ")
+	if !multi {
+		return strings.Join(strings.Fields(text), " ")
+	}
+	return strings.Trim(text, "\n")
+}
+
+// bulkAddToast reports a multi-line add: how many quests were added and
+// which names were skipped
+func bulkAddToast(added int, skipped []string) string {
+	quests := "quests"
+	if added == 1 {
+		quests = "quest"
+	}
+	msg := fmt.Sprintf("Added %d %s.", added, quests)
+	if len(skipped) == 0 {
+		return msg
+	}
+	why := "already on your list"
+	if store.MaxHabits > 0 {
+		why = fmt.Sprintf("already on your list or past the %d-quest limit", store.MaxHabits)
+	}
+	shown := make([]string, 0, 3)
+	for _, name := range skipped[:min(len(skipped), 3)] {
+		shown = append(shown, "'"+truncateQuestName(name, 20)+"'")
+	}
+	if len(skipped) > len(shown) {
+		shown = append(shown, fmt.Sprintf("%d more", len(skipped)-len(shown)))
+	}
+	return fmt.Sprintf("%s Skipped %s (%s).", msg, strings.Join(shown, ", "), why)
+}
+
+// dropLastRune removes the final rune of s, if any
+func dropLastRune(s string) string {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return s
+	}
+	return string(runes[:len(runes)-1])
+}
+
+// bell rings the terminal bell unless the user or server disabled it
+func (m model) bell() tea.Cmd {
+	if m.noBell || m.out == nil || (m.userData != nil && m.userData.MuteBell) {
+		return nil
+	}
+	out := m.out
+	return func() tea.Msg {
+		_, _ = io.WriteString(out, "\a")
+		return nil
+	}
+}
+
+// adjustSetting moves the focused settings field up (delta 1) or down (-1), wrapping around
+func (m *model) adjustSetting(delta int) {
+	switch m.settingsFocus {
+	case settingsFieldResetHour:
+		m.settingsResetHour = (m.settingsResetHour + delta + 24) % 24
+	case settingsFieldKeymap:
+		i := 0
+		for j, k := range store.Keymaps {
+			if k == m.settingsKeymap {
+				i = j
+			}
+		}
+		n := len(store.Keymaps)
+		m.settingsKeymap = store.Keymaps[(i+delta+n)%n]
+	case settingsFieldTheme:
+		i := 0
+		for j, t := range store.Themes {
+			if t == m.settingsTheme {
+				i = j
+			}
+		}
+		n := len(store.Themes)
+		m.settingsTheme = store.Themes[(i+delta+n)%n]
+	case settingsFieldBell:
+		m.settingsMuteBell = !m.settingsMuteBell
+	case settingsFieldHardcore:
+		m.settingsHardcore = !m.settingsHardcore
+	case settingsFieldQuietStart:
+		m.settingsQuietFrom = (m.settingsQuietFrom + delta + 24) % 24
+	case settingsFieldQuietEnd:
+		m.settingsQuietTo = (m.settingsQuietTo + delta + 24) % 24
+	case settingsFieldEXPDisplay:
+		m.settingsTotalEXP = !m.settingsTotalEXP
+	case settingsFieldNudge:
+		m.settingsNudge = !m.settingsNudge
+	case settingsFieldAlmost:
+		m.settingsAlmost = !m.settingsAlmost
+	case settingsFieldCompleteKey:
+		i := 0
+		for j, k := range store.CompleteKeys {
+			if k == m.settingsComplete {
+				i = j
+			}
+		}
+		n := len(store.CompleteKeys)
+		m.settingsComplete = store.CompleteKeys[(i+delta+n)%n]
+	case settingsFieldQuestEXP:
+		m.settingsQuestEXP = min(max(m.settingsQuestEXP+delta, store.MinQuestEXP), store.MaxQuestEXP)
+	case settingsFieldDateFormat:
+		i := 0
+		for j, f := range store.DateFormats {
+			if f == m.settingsDates {
+				i = j
+			}
+		}
+		n := len(store.DateFormats)
+		m.settingsDates = store.DateFormats[(i+delta+n)%n]
+	}
+}
+
+// navKey maps a navigation key to "up", "down", "left" or "right" according to
+// the keymap. Navigation keys the keymap doesn't use map to "" so they do
+// nothing; any other key is returned unchanged.
+func navKey(keymap, key string) string {
+	arrows := map[string]string{"up": "up", "down": "down", "left": "left", "right": "right"}
+	vim := map[string]string{"k": "up", "j": "down", "h": "left", "l": "right"}
+	if dir, ok := arrows[key]; ok {
+		if keymap == store.KeymapVim {
+			return ""
+		}
+		return dir
+	}
+	if dir, ok := vim[key]; ok {
+		if keymap == store.KeymapArrows {
+			return key
+		}
+		return dir
+	}
+	return key
+}
+
+// questKey swaps space and enter for hunters who complete quests with
+// Enter, so the quest handlers can always treat " " as complete and "enter"
+// as details
+func questKey(completeKey, key string) string {
+	if completeKey != store.CompleteKeyEnter {
+		return key
+	}
+	switch key {
+	case " ":
+		return "enter"
+	case "enter":
+		return " "
+	}
+	return key
+}
+
+// questKeyHint returns the key labels for completing a quest and opening its
+// details under completeKey
+func questKeyHint(completeKey string) (complete, detail string) {
+	if completeKey == store.CompleteKeyEnter {
+		return "enter", "space"
+	}
+	return "space", "enter"
+}
+
+// navHint returns the key labels for each direction in the keymap, e.g. "↑/k"
+func navHint(keymap string) (up, down, left, right string) {
+	switch keymap {
+	case store.KeymapVim:
+		return "k", "j", "h", "l"
+	case store.KeymapArrows:
+		return "↑", "↓", "←", "→"
+	default:
+		return "↑/k", "↓/j", "←/h", "→/l"
+	}
+}
+
+// resetPreview describes when a reset at hour would next happen, e.g.
+// "Next reset: tomorrow 05:00 (in 7h 12m)", with times in dates' layout
+func resetPreview(hour int, now time.Time, dates dateLayout) string {
+	next := store.NextResetAt(hour, now)
+	day := "today"
+	if next.Day() != now.Day() {
+		day = "tomorrow"
+	}
+	until := next.Sub(now)
+	return fmt.Sprintf("Next reset: %s %s (in %dh %dm)", day, next.Format(dates.clock), int(until.Hours()), int(until.Minutes())%60)
+}
+
+// resetShiftWarning explains what a new reset hour does to the quest day in
+// progress when it moves "today" to another date; "" when today stays put
+func resetShiftWarning(today string, hour int, now time.Time, dates dateLayout) string {
+	to := store.DayKeyAt(hour, now)
+	if to == today {
+		return ""
+	}
+	when := "yesterday"
+	if to > today {
+		when = "tomorrow"
+	}
+	return fmt.Sprintf("⚠ Heads-up: today becomes %s (%s). Today's completed quests move with it.", formatDayKey(to, dates.date), when)
+}
+
+// expBasis names the EXP display setting
+func expBasis(total bool) string {
+	if total {
+		return "total"
+	}
+	return "per level"
+}
+
+// quietHour shows one end of the quiet hours, or "off" when both ends match
+func quietHour(hour, other int, dates dateLayout) string {
+	if hour == other {
+		return hourLabel(hour, dates) + " (off)"
+	}
+	return hourLabel(hour, dates)
+}
+
+// questEXPLabel shows a Quest EXP setting, marking the default
+func questEXPLabel(exp int) string {
+	if exp == store.EXPPerQuest {
+		return fmt.Sprintf("%d (default)", exp)
+	}
+	return fmt.Sprintf("%d", exp)
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// barWidth is the length of the status box's EXP and time bars
+const barWidth = 24
+
+// renderBar draws a width-cell bar with ratio of it filled, rounding down.
+// ratio is clamped to 0-1 and a width of 0 or less draws nothing.
+func renderBar(ratio float64, width int, fill, empty lipgloss.Style) string {
+	if width <= 0 {
+		return ""
+	}
+	ratio = min(max(ratio, 0), 1)
+	// The epsilon keeps exact fractions like 1/3 of 24 from rounding down a cell
+	n := min(int(ratio*float64(width)+1e-9), width)
+	return fill.Render(strings.Repeat("█", n)) + empty.Render(strings.Repeat("░", width-n))
+}
+
+// renderTimeBar creates a progress bar showing time until next reset
+func renderTimeBar(timeUntil time.Duration, accent, dim, reward lipgloss.Style) string {
+	hoursLeft := timeUntil.Hours()
+	minutesLeft := int(timeUntil.Minutes()) % 60
+	bar := renderBar(hoursLeft/24, barWidth, reward, reward)
+	timeStr := fmt.Sprintf("%dh %dm until reset", int(hoursLeft), minutesLeft)
+
+	return accent.Render("Time ") + dim.Render("[") + bar + dim.Render("] ") + dim.Render(timeStr)
+}
+
+// questState is how a quest stands today
+type questState int
+
+const (
+	questDone questState = iota
+	questPending
+	questUrgent // still open with less than urgentWindow before reset, outside quiet hours
+)
+
+// urgentWindow is how close to the reset pending quests start to escalate
+const urgentWindow = 2 * time.Hour
+
+// classifyQuest returns a quest's state for today given whether it's done,
+// whether it can be skipped today (a weekly quest on track), the time left
+// until the day resets and whether it's quiet hours
+func classifyQuest(done, skippable bool, untilReset time.Duration, quiet bool) questState {
+	switch {
+	case done:
+		return questDone
+	case untilReset <= urgentWindow && !quiet && !skippable:
+		return questUrgent
+	default:
+		return questPending
+	}
+}
+
+// palette is a theme's colors; mono themes render without color
+type palette struct {
+	primary, dim, reward, err lipgloss.Color
+	mono                      bool
+}
+
+// themePalettes is the theme registry, keyed by store.Themes id
+var themePalettes = map[string]palette{
+	store.ThemeSystemBlue:    {primary: "63", dim: "245", reward: "220", err: "203"}, // Solo Leveling system blue
+	store.ThemeShadowMonarch: {primary: "93", dim: "243", reward: "141", err: "197"},
+	store.ThemeHunterGreen:   {primary: "34", dim: "245", reward: "190", err: "203"},
+	store.ThemeMonochrome:    {mono: true},
+}
+
+// themeStyles returns the style set for theme, falling back to the default
+// palette for unknown ids. Terminals without color support get a plain set
+// that keeps the borders.
+func themeStyles(r *lipgloss.Renderer, theme string) (systemTitle, accent, dim, reward, errStyle, toastStyle lipgloss.Style, boxBorder lipgloss.Style) {
+	p, ok := themePalettes[theme]
+	if !ok {
+		p = themePalettes[store.ThemeSystemBlue]
+	}
+	if p.mono || r.ColorProfile() == termenv.Ascii {
+		plain := r.NewStyle()
+		toastStyle = r.NewStyle().Padding(0, 1)
+		boxBorder = r.NewStyle().Border(lipgloss.DoubleBorder()).Padding(0, 2)
+		return plain, plain, plain, plain, plain, toastStyle, boxBorder
+	}
+	systemTitle = r.NewStyle().Bold(true).Foreground(p.primary)
+	accent = r.NewStyle().Foreground(p.primary)
+	dim = r.NewStyle().Foreground(p.dim)
+	reward = r.NewStyle().Bold(true).Foreground(p.reward)
+	errStyle = r.NewStyle().Foreground(p.err)
+	toastStyle = r.NewStyle().Bold(true).Foreground(p.reward).Padding(0, 1)
+	boxBorder = r.NewStyle().
+		Border(lipgloss.DoubleBorder()).
+		BorderForeground(p.primary).
+		Padding(0, 2)
+	return
+}
+
+// themeRenderer returns the renderer for the current theme; monochrome drops
+// every color, including rank and streak colors outside the palette
+func (m model) themeRenderer() *lipgloss.Renderer {
+	if themePalettes[m.theme()].mono && m.monoRenderer != nil {
+		return m.monoRenderer
+	}
+	return m.renderer
+}
+
+// theme is the theme to render with: the one being previewed in settings,
+// else the user's choice, else the default before login
+func (m model) theme() string {
+	switch {
+	case m.authState == authSettings:
+		return m.settingsTheme
+	case m.userData != nil:
+		return m.userData.Theme
+	}
+	return store.ThemeSystemBlue
+}
 
 // Hunter Rank based on level (Solo Leveling style)
 func hunterRank(level int) (rank string, color lipgloss.Color) {
@@ -387,6 +2343,60 @@ func streakStyle(r *lipgloss.Renderer, streak int) lipgloss.Style {
 	return r.NewStyle().Foreground(lipgloss.Color("220")) // gold
 }
 
+// progressColor is the main box's border color for today's progress: red
+// with under half the quests done, yellow from half and green once all are.
+// ok is false with no quests, leaving the theme's color.
+func progressColor(done, total int) (color lipgloss.Color, ok bool) {
+	switch {
+	case total == 0:
+		return "", false
+	case done >= total:
+		return lipgloss.Color("40"), true // green
+	case done*2 >= total:
+		return lipgloss.Color("220"), true // yellow
+	default:
+		return lipgloss.Color("196"), true // red
+	}
+}
+
+// expPreviewLine shows what completing the selected quest would pay, e.g.
+// "→ +20 EXP (would reach Lv8!)"
+func (m model) expPreviewLine(h store.Habit, dim, reward lipgloss.Style) string {
+	p := m.userData.PreviewCompletion(h.ID)
+	switch {
+	case p.Done:
+		return dim.Render("  ✓ already done today")
+	case p.EXP == 0:
+		return dim.Render("  → no EXP, it already paid today")
+	case m.userData.ChainLocked(h.ID, m.userData.TodayKey()):
+		return dim.Render(fmt.Sprintf("  → +%d EXP once unlocked", p.EXP))
+	case p.Level > m.userData.Level:
+		return reward.Render(fmt.Sprintf("  → +%d EXP (would reach Lv%d!)", p.EXP, p.Level))
+	}
+	return dim.Render(fmt.Sprintf("  → +%d EXP", p.EXP))
+}
+
+// questColors are the terminal colors of store.HabitColors
+var questColors = map[string]lipgloss.Color{
+	"red":    "203",
+	"orange": "208",
+	"yellow": "220",
+	"green":  "78",
+	"cyan":   "80",
+	"blue":   "75",
+	"purple": "141",
+	"pink":   "212",
+}
+
+// questNameStyle colors a quest's name with its tag color, if it has one
+func questNameStyle(r *lipgloss.Renderer, h store.Habit) (lipgloss.Style, bool) {
+	c, ok := questColors[h.Color]
+	if !ok {
+		return lipgloss.Style{}, false
+	}
+	return r.NewStyle().Foreground(c), true
+}
+
 // Stats are now stored directly in UserData (STR, VIT, AGI, INT)
 // Updated by Gemini AI on each level-up
 
@@ -427,10 +2437,40 @@ func boxLine(content string, innerWidth int, accentStyle lipgloss.Style) string
 }
 
 const (
-	maxQuestNameRunes = 32 // truncate long names so full line fits in box
-	maxQuestBoxWidth  = 56 // cap Daily Quests box width
+	maxQuestNameRunes = 32 // truncate long names in toasts and secondary views
+	maxQuestBoxWidth  = 56 // cap Daily Quests box width when the terminal width is unknown
+	viewChromeWidth   = 10 // cells around a box's inner width: outer border, padding, margin and box sides
+	minQuestNameWidth = 8  // never truncate a quest name in the list below this
 )
 
+// questBoxCap is the widest the Daily Quests box may grow: whatever the
+// terminal fits, or maxQuestBoxWidth until its width is known
+func (m model) questBoxCap() int {
+	if m.width <= 0 {
+		return maxQuestBoxWidth
+	}
+	return max(m.width-viewChromeWidth, boxMinInner)
+}
+
+// truncateToWidth shortens s to at most width terminal cells, counting wide
+// characters such as emoji and CJK as two, ending in "…" if truncated
+func truncateToWidth(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := lipgloss.Width(string(r))
+		if used+w > width-1 { // leave a cell for the ellipsis
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	return b.String() + "…"
+}
+
 // truncateQuestName shortens name to max runes and appends "…" if truncated.
 func truncateQuestName(name string, maxRunes int) string {
 	runes := []rune(name)
@@ -441,16 +2481,35 @@ func truncateQuestName(name string, maxRunes int) string {
 }
 
 func (m model) View() string {
-	r := m.renderer
-	titleStyle, accent, dim, reward, errStyle, toastStyle, boxBorder := soloStyles(r)
+	if m.live != nil {
+		m.live.Lock()
+		defer m.live.Unlock()
+	}
+	r := m.themeRenderer()
+	titleStyle, accent, dim, reward, errStyle, toastStyle, boxBorder := themeStyles(r, m.theme())
 	systemTitle := func(s string) string { return titleStyle.Render(s) }
 
+	// Quit requested while a level-up is still being allocated
+	if m.confirmQuit {
+		var b strings.Builder
+		b.WriteString(systemTitle("◆  S Y S T E M"))
+		b.WriteString(dim.Render("  —  Applying rewards..."))
+		b.WriteString("\n\n")
+		b.WriteString(toastStyle.Render("  ▶ AI stat allocation pending. Quit anyway? [y/n]") + "\n")
+		b.WriteString(dim.Render("    Your EXP and level are saved; the new stat points would be lost.") + "\n")
+		b.WriteString(dim.Render("    Wait and the SYSTEM will exit as soon as they arrive."))
+		return boxBorder.Render(b.String())
+	}
+
 	// Login screen — "Identify yourself."
 	if m.authState == authLogin {
 		var b strings.Builder
 		b.WriteString(systemTitle("◆  S Y S T E M"))
 		b.WriteString(dim.Render("  —  Identify yourself."))
 		b.WriteString("\n\n")
+		if banner := loginBanner.get(); banner != "" {
+			b.WriteString(dim.Render("  "+banner) + "\n\n")
+		}
 		b.WriteString(accent.Render("  Username  ") + dim.Render("› ") + m.loginUsername + "_")
 		b.WriteString("\n")
 		b.WriteString(accent.Render("  Password  ") + dim.Render("› ") + strings.Repeat("•", len(m.loginPassword)) + "_")
@@ -458,7 +2517,14 @@ func (m model) View() string {
 		if m.authError != "" {
 			b.WriteString(errStyle.Render("  ⚠ "+m.authError) + "\n\n")
 		}
-		b.WriteString(dim.Render("  [Tab] next  [Enter] login  [r] register  [q] quit"))
+		if store.DemoUser != "" {
+			b.WriteString(dim.Render(fmt.Sprintf("  Just looking? Log in as %q with any password.", store.DemoUser)) + "\n\n")
+		}
+		if store.AllowRegister {
+			b.WriteString(dim.Render("  [Tab] next  [Enter] login  [r] register  [q] quit"))
+		} else {
+			b.WriteString(dim.Render("  [Tab] next  [Enter] login  [q] quit"))
+		}
 		return boxBorder.Render(b.String())
 	}
 
@@ -479,56 +2545,365 @@ func (m model) View() string {
 		return boxBorder.Render(b.String())
 	}
 
+	// Forced password change — "Choose a new password."
+	if m.authState == authNewPass {
+		var b strings.Builder
+		b.WriteString(systemTitle("◆  S Y S T E M"))
+		b.WriteString(dim.Render("  —  Your password was reset. Choose a new one."))
+		b.WriteString("\n\n")
+		b.WriteString(accent.Render("  New       ") + dim.Render("› ") + strings.Repeat("•", len([]rune(m.newPassword))) + "_")
+		b.WriteString("\n")
+		b.WriteString(accent.Render("  Confirm   ") + dim.Render("› ") + strings.Repeat("•", len([]rune(m.confirmPassword))) + "_")
+		b.WriteString("\n\n")
+		if m.authError != "" {
+			b.WriteString(errStyle.Render("  ⚠ "+m.authError) + "\n\n")
+		}
+		b.WriteString(dim.Render("  [Tab] next  [Enter] save  [Esc] back to login"))
+		return boxBorder.Render(b.String())
+	}
+
 	// Settings view
 	if m.authState == authSettings {
 		var b strings.Builder
 		b.WriteString(systemTitle("◆  S Y S T E M"))
 		b.WriteString(dim.Render("  —  Settings"))
 		b.WriteString("\n\n")
-		b.WriteString(accent.Render("  Day Reset Time Configuration"))
+
+		// Description of the focused setting
+		var title string
+		var desc []string
+		switch m.settingsFocus {
+		case settingsFieldResetHour:
+			title = "Day Reset Time Configuration"
+			desc = []string{
+				"Your daily quests will reset at this hour each day.",
+				"This allows you to customize based on your timezone.",
+				resetPreview(m.settingsResetHour, time.Now(), layoutFor(m.settingsDates)),
+			}
+			if shift := resetShiftWarning(m.userData.TodayKey(), m.settingsResetHour, time.Now(), layoutFor(m.settingsDates)); shift != "" {
+				desc = append(desc, "", shift)
+			}
+		case settingsFieldKeymap:
+			title = "Navigation Keys"
+			desc = []string{
+				"default: arrows and h/j/k/l.  vim: h/j/k/l only.",
+				"arrows: arrow keys only, h/j/k/l do nothing.",
+			}
+		case settingsFieldTheme:
+			title = "Color Theme"
+			desc = []string{
+				"The colors used across the SYSTEM. Changes preview live;",
+				"press Enter to keep them or Esc to go back.",
+			}
+		case settingsFieldBell:
+			title = "Level-up Bell"
+			desc = []string{
+				"Ring the terminal bell when you level up.",
+			}
+			if m.noBell {
+				desc = append(desc, "The bell is disabled on this server.")
+			}
+		case settingsFieldHardcore:
+			title = "Hardcore Mode"
+			desc = []string{
+				fmt.Sprintf("Breaking your streak costs %d%% of your EXP,", store.HardcorePenaltyPercent),
+				"doubling for each further missed day. You never lose a level.",
+				fmt.Sprintf("Right now: 1 missed day = -%d EXP, 3 missed days = -%d EXP.",
+					m.userData.HardcorePenalty(1), m.userData.HardcorePenalty(3)),
+			}
+		case settingsFieldQuietStart, settingsFieldQuietEnd:
+			title = "Quiet Hours"
+			desc = []string{
+				"Between these hours reminders and near-reset warnings",
+				"are hidden; quests still work. Same start and end = off.",
+			}
+		case settingsFieldEXPDisplay:
+			title = "EXP Display"
+			have, need := store.EXPProgressFor(m.userData.Level, m.userData.EXP, m.settingsTotalEXP)
+			desc = []string{
+				fmt.Sprintf("per level: EXP earned in this level out of %d.", store.EXPPerLevel),
+				"total: all EXP earned out of what the next level needs.",
+				fmt.Sprintf("Right now: %d/%d", have, need),
+			}
+		case settingsFieldNudge:
+			title = "Last Quest Nudge"
+			desc = []string{
+				"When one quest is all that stands between you and a",
+				"perfect day, call it out above the quest list.",
+			}
+		case settingsFieldAlmost:
+			title = "Almost There"
+			desc = []string{
+				"When one more quest would reach the next level, show",
+				"⚡ almost there next to your EXP.",
+			}
+		case settingsFieldCompleteKey:
+			title = "Complete Key"
+			desc = []string{
+				"The key that completes the selected quest.",
+				"The other one of Space and Enter opens its details.",
+			}
+		case settingsFieldDateFormat:
+			title = "Date Format"
+			now := time.Now()
+			dates := layoutFor(m.settingsDates)
+			desc = []string{
+				"How dates and times are shown in history, reports and",
+				"the activity log. Your saved data doesn't change.",
+				"Right now: " + now.Format(dates.date) + ", " + now.Format(dates.clock),
+			}
+		case settingsFieldQuestEXP:
+			title = "Quest EXP"
+			desc = []string{
+				fmt.Sprintf("EXP each completed quest pays, %d-%d (default %d).", store.MinQuestEXP, store.MaxQuestEXP, store.EXPPerQuest),
+				"EXP you already earned stays as it is; unchecking an",
+				"old completion takes back what it paid at the time.",
+			}
+		}
+		b.WriteString(accent.Render("  " + title))
 		b.WriteString("\n\n")
-		b.WriteString(dim.Render("  Your daily quests will reset at this hour each day."))
+		for _, line := range desc {
+			b.WriteString(dim.Render("  "+line) + "\n")
+		}
 		b.WriteString("\n")
-		b.WriteString(dim.Render("  This allows you to customize based on your timezone."))
-		b.WriteString("\n\n")
 
-		// Display current hour with up/down arrows
-		hourStr := fmt.Sprintf("%02d:00", m.settingsResetHour)
-		b.WriteString("  " + dim.Render("▲") + "\n")
-		b.WriteString("  " + accent.Render("Reset Hour: ") + reward.Render(hourStr) + "\n")
-		b.WriteString("  " + dim.Render("▼") + "\n\n")
+		// One row per field; the focused one shows up/down arrows
+		rows := []struct {
+			label, value string
+		}{
+			{"Reset Hour", hourLabel(m.settingsResetHour, layoutFor(m.settingsDates))},
+			{"Keymap    ", m.settingsKeymap},
+			{"Theme     ", m.settingsTheme},
+			{"Bell      ", onOff(!m.settingsMuteBell)},
+			{"Hardcore  ", onOff(m.settingsHardcore)},
+			{"Quiet From", quietHour(m.settingsQuietFrom, m.settingsQuietTo, layoutFor(m.settingsDates))},
+			{"Quiet To  ", quietHour(m.settingsQuietTo, m.settingsQuietFrom, layoutFor(m.settingsDates))},
+			{"EXP Shown ", expBasis(m.settingsTotalEXP)},
+			{"Nudge     ", onOff(m.settingsNudge)},
+			{"Almost    ", onOff(m.settingsAlmost)},
+			{"Complete  ", m.settingsComplete},
+			{"Quest EXP ", questEXPLabel(m.settingsQuestEXP)},
+			{"Dates     ", m.settingsDates + " (" + time.Now().Format(layoutFor(m.settingsDates).date) + ")"},
+		}
+		for i, row := range rows {
+			if i == m.settingsFocus {
+				b.WriteString(accent.Render("  ▸ "+row.label+"  ") + dim.Render("▲ ") + reward.Render(row.value) + dim.Render(" ▼") + "\n")
+			} else {
+				b.WriteString(dim.Render("    "+row.label+"  ") + row.value + "\n")
+			}
+		}
+		b.WriteString("\n")
+		if m.settingsErr != "" {
+			b.WriteString(errStyle.Render("  ⚠ "+m.settingsErr) + "\n\n")
+		}
 
-		b.WriteString(dim.Render("  Use [") + accent.Render("↑") + dim.Render("/") + accent.Render("k") + dim.Render("] and [") + accent.Render("↓") + dim.Render("/") + accent.Render("j") + dim.Render("] to adjust"))
+		up, down, _, _ := navHint(m.keymap)
+		b.WriteString(dim.Render("  Use [") + accent.Render(up) + dim.Render("] and [") + accent.Render(down) + dim.Render("] to adjust, [") + accent.Render("Tab") + dim.Render("] next setting"))
 		b.WriteString("\n")
-		b.WriteString(dim.Render("  [Enter] save  [Esc] cancel  [q] quit"))
+		b.WriteString(dim.Render("  [Enter] save  [Esc] cancel  [T] API tokens  [R] reset everything  [q] quit"))
 		return boxBorder.Render(b.String())
 	}
 
+	// Weekly report view
+	if m.authState == authReport {
+		return boxBorder.Render(m.renderReport(accent, dim, reward, systemTitle))
+	}
+
+	// Leaderboard view
+	if m.authState == authRanks {
+		return boxBorder.Render(m.renderRanks(accent, dim, reward, errStyle, systemTitle))
+	}
+
+	// Activity log view
+	if m.authState == authActivity {
+		return boxBorder.Render(m.renderActivity(accent, dim, errStyle, systemTitle))
+	}
+
+	// Help page
+	if m.authState == authHelp {
+		return boxBorder.Render(m.renderHelp(accent, dim, reward, systemTitle))
+	}
+
+	// Stats chart view
+	if m.authState == authStats {
+		return boxBorder.Render(m.renderStats(accent, dim, reward, toastStyle, systemTitle))
+	}
+
+	// API tokens view
+	if m.authState == authTokens {
+		return boxBorder.Render(m.renderTokens(accent, dim, reward, errStyle, systemTitle))
+	}
+
+	// Reset everything view
+	if m.authState == authReset {
+		return boxBorder.Render(m.renderReset(accent, dim, errStyle, systemTitle))
+	}
+
+	// Archived quests view
+	if m.authState == authArchive {
+		return boxBorder.Render(m.renderArchive(accent, dim, toastStyle, systemTitle))
+	}
+
+	// Compare view
+	if m.authState == authCompare {
+		return boxBorder.Render(m.renderCompare(accent, dim, reward, errStyle, systemTitle))
+	}
+
 	// Main app: loading
 	if m.userData == nil {
 		return boxBorder.Render(systemTitle("◆  S Y S T E M") + "\n\n" + dim.Render("  Loading..."))
 	}
 
-	// Main app: new daily quest prompt
-	if m.addingHabit != nil {
+	// First-run tutorial overlay
+	if m.tutorialStep >= 0 && m.tutorialStep < len(tutorialPages) {
+		page := tutorialPages[m.tutorialStep]
+		lines := append([]string{accent.Render(page.title), ""}, page.body...)
+		lines = append(lines, "", dim.Render(fmt.Sprintf("Step %d of %d", m.tutorialStep+1, len(tutorialPages))))
+		inner := boxMinInner
+		for _, line := range lines {
+			if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+				inner = w
+			}
+		}
+		_, _, left, right := navHint(m.keymap)
 		var b strings.Builder
 		b.WriteString(systemTitle("◆  S Y S T E M"))
-		b.WriteString(dim.Render("  —  New Daily Quest"))
+		b.WriteString(dim.Render("  —  Tutorial"))
 		b.WriteString("\n\n")
-		b.WriteString(accent.Render("  Quest name  ") + dim.Render("› ") + *m.addingHabit + "_")
+		b.WriteString(reward.Render(boxTop(inner)) + "\n")
+		for _, line := range lines {
+			b.WriteString(reward.Render(boxLine(line, inner, reward)) + "\n")
+		}
+		b.WriteString(reward.Render(boxBottom(inner)) + "\n\n")
+		b.WriteString(dim.Render("  [" + left + "] back  [" + right + "] next  [Enter/Esc] close"))
+		return boxBorder.Render(b.String())
+	}
+
+	// Main app: new daily quest / edit quest form
+	if m.addingHabit != nil {
+		title := "New Daily Quest"
+		if m.editingHabitID != "" {
+			title = "Edit Daily Quest"
+		}
+		nameCursor, noteCursor := "_", ""
+		switch m.addingFocus {
+		case 1:
+			nameCursor, noteCursor = "", "_"
+		case 2, 3, 4, 5:
+			nameCursor = ""
+		}
+		reminder := dim.Render("none")
+		if m.addingReminder >= 0 {
+			reminder = fmt.Sprintf("⏰ %02d:00", m.addingReminder)
+		}
+		reminderLabel := accent.Render("  Remind at   ")
+		if m.addingFocus == 2 {
+			reminderLabel = reward.Render("  Remind at   ")
+		}
+		after := dim.Render("none")
+		if pre, ok := m.userData.HabitByID(m.addingDependsOn); ok {
+			after = pre.Icon + " " + truncateQuestName(pre.Name, maxQuestNameRunes)
+		}
+		afterLabel := accent.Render("  After       ")
+		if m.addingFocus == 3 {
+			afterLabel = reward.Render("  After       ")
+		}
+		color := dim.Render("none")
+		if style, ok := questNameStyle(m.themeRenderer(), store.Habit{Color: m.addingColor}); ok {
+			color = style.Render("● " + m.addingColor)
+		}
+		colorLabel := accent.Render("  Color       ")
+		if m.addingFocus == 4 {
+			colorLabel = reward.Render("  Color       ")
+		}
+		weekly := dim.Render("every day")
+		if m.addingWeekly > 0 {
+			weekly = fmt.Sprintf("%d× a week", m.addingWeekly)
+		}
+		weeklyLabel := accent.Render("  Goal        ")
+		if m.addingFocus == 5 {
+			weeklyLabel = reward.Render("  Goal        ")
+		}
+		var b strings.Builder
+		b.WriteString(systemTitle("◆  S Y S T E M"))
+		b.WriteString(dim.Render("  —  " + title))
 		b.WriteString("\n\n")
-		b.WriteString(dim.Render("  [Enter] accept  [Esc] cancel"))
+		if names := strings.Split(*m.addingHabit, "\n"); len(names) > 1 {
+			b.WriteString(accent.Render("  Quest names ") + dim.Render(fmt.Sprintf("› %d lines, one quest each", len(names))))
+			b.WriteString("\n")
+			for i, name := range names {
+				if i == bulkPreviewLines {
+					b.WriteString(dim.Render(fmt.Sprintf("                … and %d more", len(names)-i)) + "\n")
+					break
+				}
+				b.WriteString("                " + truncateQuestName(name, maxQuestNameRunes))
+				if i == len(names)-1 {
+					b.WriteString(nameCursor)
+				}
+				b.WriteString("\n")
+			}
+		} else {
+			b.WriteString(accent.Render("  Icon        ") + dim.Render("‹ ") + m.addingIcon + dim.Render(" ›"))
+			b.WriteString("\n")
+			b.WriteString(accent.Render("  Quest name  ") + dim.Render("› ") + *m.addingHabit + nameCursor)
+			b.WriteString("\n")
+		}
+		// Quests added from a pasted list start with the defaults, so their
+		// other fields aren't offered
+		if !strings.Contains(*m.addingHabit, "\n") {
+			b.WriteString(accent.Render("  Note        ") + dim.Render("› ") + m.addingNote + noteCursor)
+			b.WriteString("\n")
+			b.WriteString(dim.Render(fmt.Sprintf("                (optional, %d/%d)", len([]rune(m.addingNote)), store.MaxNoteRunes)))
+			b.WriteString("\n")
+			b.WriteString(reminderLabel + dim.Render("‹ ") + reminder + dim.Render(" ›"))
+			b.WriteString("\n")
+			b.WriteString(afterLabel + dim.Render("‹ ") + after + dim.Render(" ›"))
+			b.WriteString("\n")
+			b.WriteString(colorLabel + dim.Render("‹ ") + color + dim.Render(" ›"))
+			b.WriteString("\n")
+			b.WriteString(weeklyLabel + dim.Render("‹ ") + weekly + dim.Render(" ›"))
+			b.WriteString("\n\n")
+		} else {
+			b.WriteString("\n")
+		}
+		if m.suggesting {
+			b.WriteString(dim.Render("  The SYSTEM is choosing a quest...") + "\n\n")
+		} else if m.suggestError != "" {
+			b.WriteString(dim.Render("  "+m.suggestError) + "\n\n")
+		}
+		if strings.Contains(*m.addingHabit, "\n") {
+			b.WriteString(dim.Render("  [Enter] add them all (blanks and duplicates are skipped)  [Esc] cancel"))
+		} else if m.editingHabitID == "" {
+			b.WriteString(dim.Render("  [Tab] next  [↑/↓] icon/option  [Enter] accept  [ctrl+g] 🎲 suggest  [Esc] cancel"))
+		} else {
+			b.WriteString(dim.Render("  [Tab] next  [↑/↓] icon/option  [Enter] accept  [Esc] cancel"))
+		}
 		return boxBorder.Render(b.String())
 	}
 
+	// Quest history view
+	if m.authState == authHistory {
+		if h, ok := m.userData.HabitByID(m.detailHabitID); ok {
+			return boxBorder.Render(m.renderHistory(h, accent, dim, reward, errStyle, toastStyle, systemTitle))
+		}
+	}
+
+	// Quest detail view
+	if m.authState == authDetail {
+		if h, ok := m.userData.HabitByID(m.detailHabitID); ok {
+			return boxBorder.Render(m.renderDetail(h, accent, dim, reward, toastStyle, systemTitle))
+		}
+	}
+
+	// Short terminals get a one-line status and a bare quest list
+	if m.compact() {
+		return m.renderCompact(accent, dim, reward, errStyle, toastStyle)
+	}
+
 	// Main app: daily quests + stats
 	u := m.userData
-	expIn := u.EXPInCurrentLevel()
-	expPct := (expIn * 24) / 100
-	if expPct > 24 {
-		expPct = 24
-	}
-	expBar := strings.Repeat("█", expPct) + strings.Repeat("░", 24-expPct)
+	expHave, expNeed := u.EXPProgress()
+	expBar := renderBar(float64(expHave)/float64(expNeed), barWidth, reward, reward)
 	str, vit, agi, intel := u.STR, u.VIT, u.AGI, u.INT
 
 	// Get hunter rank
@@ -538,13 +2913,26 @@ func (m model) View() string {
 	var b strings.Builder
 	b.WriteString(systemTitle("◆  S Y S T E M"))
 	b.WriteString(dim.Render("  —  Hunter: ") + accent.Render(u.Username) + dim.Render(" ") + rankStyle.Render("["+rank+"]"))
+	if u.PrestigeCount > 0 {
+		b.WriteString(reward.Render(fmt.Sprintf(" ★%d", u.PrestigeCount)))
+	}
 	// Show streak if active
 	if u.CurrentStreak > 0 {
 		fireStyle := streakStyle(r, u.CurrentStreak)
 		b.WriteString("  " + fireStyle.Render(fmt.Sprintf("🔥 %d", u.CurrentStreak)))
 	}
-	b.WriteString("\n")
-	b.WriteString(dim.Render("  Complete your daily quests to level up."))
+	if u.Shields > 0 {
+		b.WriteString("  " + dim.Render(fmt.Sprintf("🛡 %d", u.Shields)))
+	}
+	if n := userSessions.count(u.Username); n > 1 {
+		b.WriteString("  " + dim.Render(fmt.Sprintf("⧉ %d sessions", n)))
+	}
+	b.WriteString("\n")
+	if u.Title != "" {
+		b.WriteString(dim.Render("  Title ") + reward.Render(u.Title))
+	} else {
+		b.WriteString(dim.Render("  Complete your daily quests to level up."))
+	}
 	b.WriteString("\n\n")
 
 	// Stats panel with colored stats
@@ -558,14 +2946,27 @@ func (m model) View() string {
 		dim.Render("  VIT ") + vitStyle.Render(fmt.Sprintf("%d", vit)) +
 		dim.Render("  AGI ") + agiStyle.Render(fmt.Sprintf("%d", agi)) +
 		dim.Render("  INT ") + intStyle.Render(fmt.Sprintf("%d", intel))
-	statusLine2 := accent.Render("EXP  ") + dim.Render("[") + reward.Render(expBar) + dim.Render("] ") +
-		reward.Render(fmt.Sprintf("%d/100", expIn))
+	expLabel := fmt.Sprintf("%d/%d", expHave, expNeed)
+	if u.AtLevelCap() {
+		expLabel = "MAX  [P] prestige"
+	}
+	statusLine2 := accent.Render("EXP  ") + dim.Render("[") + expBar + dim.Render("] ") +
+		reward.Render(expLabel)
+	if !u.HideAlmostThere && u.NearLevelUp() {
+		statusLine2 += "  " + reward.Render("⚡ almost there")
+	}
 	// Add time bar
 	timeUntil := u.TimeUntilReset()
+	quiet := u.InQuietHours(time.Now())
 	timeBarLine := renderTimeBar(timeUntil, accent, dim, reward)
 
+	statusTitle := accent.Render("Status") + "  " + saveIndicator(m.saver.state(), dim, errStyle)
+
 	// Calculate box width from all lines
 	statusInner := lipgloss.Width(statusLine1)
+	if w0 := lipgloss.Width(statusTitle); w0 > statusInner {
+		statusInner = w0
+	}
 	if w2 := lipgloss.Width(statusLine2); w2 > statusInner {
 		statusInner = w2
 	}
@@ -576,20 +2977,34 @@ func (m model) View() string {
 	if statusInner < boxMinInner {
 		statusInner = boxMinInner
 	}
-	b.WriteString(accent.Render(boxTop(statusInner)) + "\n")
-	b.WriteString(accent.Render(boxLine(accent.Render("Status"), statusInner, accent)) + "\n")
-	b.WriteString(accent.Render(boxLine(statusLine1, statusInner, accent)) + "\n")
-	b.WriteString(accent.Render(boxLine(statusLine2, statusInner, accent)) + "\n")
-	b.WriteString(accent.Render(boxLine(timeBarLine, statusInner, accent)) + "\n")
-	b.WriteString(accent.Render(boxBottom(statusInner)) + "\n\n")
+	// Border flashes gold right after a level-up
+	frame := accent
+	if !m.flashUntil.IsZero() && time.Now().Before(m.flashUntil) {
+		frame = reward
+	}
+	b.WriteString(frame.Render(boxTop(statusInner)) + "\n")
+	b.WriteString(frame.Render(boxLine(statusTitle, statusInner, frame)) + "\n")
+	b.WriteString(frame.Render(boxLine(statusLine1, statusInner, frame)) + "\n")
+	b.WriteString(frame.Render(boxLine(statusLine2, statusInner, frame)) + "\n")
+	b.WriteString(frame.Render(boxLine(timeBarLine, statusInner, frame)) + "\n")
+	b.WriteString(frame.Render(boxBottom(statusInner)) + "\n\n")
 
-	// Toast (quest complete / level up)
-	if m.lastToast != "" {
+	// Toast (quest complete / level up) or prestige prompt
+	if m.confirmPrestige {
+		b.WriteString(toastStyle.Render("  ▶ PRESTIGE? Level, EXP and stats reset; habits and history stay.") + "\n")
+		b.WriteString(toastStyle.Render(fmt.Sprintf("    You gain a ★ and +%d to every stat for good. [y/n]", store.PrestigeStatBonus)) + "\n\n")
+	} else if m.lastToast != "" {
 		b.WriteString(toastStyle.Render("  ▶ "+m.lastToast) + "\n\n")
 	}
+	if nudge := lastQuestNudge(u); nudge != "" {
+		b.WriteString(reward.Render("  ★ "+nudge) + "\n\n")
+	}
 
 	// Daily Quests panel — dynamic box from content width (+ 2 for spaces inside boxLine)
 	questTitle := accent.Render("Daily Quests")
+	if u.SortMode != store.SortManual {
+		questTitle += dim.Render("  by " + u.SortMode)
+	}
 	questInner := lipgloss.Width(questTitle) + boxPaddingRunes
 	if questInner < boxMinInner {
 		questInner = boxMinInner
@@ -599,9 +3014,7 @@ func (m model) View() string {
 		if w := lipgloss.Width(emptyLine) + boxPaddingRunes; w > questInner {
 			questInner = w
 		}
-		if questInner > maxQuestBoxWidth {
-			questInner = maxQuestBoxWidth
-		}
+		questInner = min(questInner, m.questBoxCap())
 		b.WriteString(accent.Render(boxTop(questInner)) + "\n")
 		b.WriteString(accent.Render(boxLine(questTitle, questInner, accent)) + "\n")
 		b.WriteString(accent.Render(boxLine(emptyLine, questInner, dim)) + "\n")
@@ -619,65 +3032,865 @@ func (m model) View() string {
 		// Build each quest line and track max width
 		questLines := make([]string, 0, len(u.Habits)+2)
 		questLines = append(questLines, questTitle, summaryLine)
-		for i, h := range u.Habits {
+		spot, _ := u.Spotlight()
+		for i, h := range u.SortedHabits() {
 			arrow := "   "
 			if m.cursor == i {
 				arrow = accent.Render(" ▸ ")
 			}
-			done := u.CompletedToday(h.ID)
 			check := dim.Render("[ ]")
-			if done {
+			switch classifyQuest(u.CompletedToday(h.ID), u.WeeklyOnTrack(h.ID), timeUntil, quiet) {
+			case questDone:
 				greenCheck := r.NewStyle().Bold(true).Foreground(lipgloss.Color("40")) // green
 				check = greenCheck.Render("[✓]")
+			case questUrgent:
+				check = errStyle.Render("[!]")
+			}
+			questEXP := u.EffectiveQuestEXP()
+			if h.ID == store.WelcomeQuestID {
+				questEXP = store.WelcomeQuestBonus
+			}
+			prefix := arrow + check + " " + h.Icon + " "
+			suffix := "  " + dim.Render("→ ") + reward.Render(fmt.Sprintf("+%d EXP", questEXP))
+			if h.ID == spot.ID {
+				suffix = " " + reward.Render("⭐") + "  " + dim.Render("→ ") + reward.Render(fmt.Sprintf("+%d EXP", questEXP+store.SpotlightBonus))
+			}
+			due := false
+			if h.ReminderHour != nil {
+				reminder := dim.Render(fmt.Sprintf("⏰ %02d:00", *h.ReminderHour))
+				if due = u.ReminderDue(h, time.Now()); due {
+					reminder = errStyle.Render("⏰ due")
+					if left := m.snoozeLeft(h, time.Now()); left > 0 {
+						due = false
+						reminder = dim.Render("💤 " + shortDuration(left))
+					}
+				}
+				suffix = " " + reminder + suffix
+			}
+			if done, target := u.WeeklyProgress(h.ID); target > 0 {
+				week := dim.Render(fmt.Sprintf("%d/%d this week", done, target))
+				if done >= target {
+					week = reward.Render(fmt.Sprintf("%d/%d this week", done, target))
+				}
+				suffix = " " + week + suffix
+			}
+			chained := u.ChainLocked(h.ID, u.TodayKey())
+			if chained || u.LockedIn(h.ID, u.TodayKey()) {
+				suffix = " " + dim.Render("🔒") + suffix
+			}
+			if h.ID == m.focusHabitID {
+				clock := reward.Render("⏱ " + focusClock(m.focusLeft(time.Now())))
+				if m.focusPaused {
+					clock = dim.Render("⏸ " + focusClock(m.focusRemain))
+				}
+				suffix = " " + clock + suffix
 			}
-			displayName := truncateQuestName(h.Name, maxQuestNameRunes)
-			line := arrow + check + " " + displayName + "  " + dim.Render("→ ") + reward.Render(fmt.Sprintf("+%d EXP", store.EXPPerQuest))
+			// The name gets whatever the widest box leaves; the detail view shows it in full
+			nameWidth := m.questBoxCap() - boxPaddingRunes - lipgloss.Width(prefix) - lipgloss.Width(suffix)
+			name := truncateToWidth(h.Name, max(nameWidth, minQuestNameWidth))
+			if due {
+				name = reward.Render(name)
+			} else if chained {
+				name = dim.Render(name)
+			} else if style, ok := questNameStyle(r, h); ok {
+				name = style.Render(name)
+			}
+			line := prefix + name + suffix
 			if w := lipgloss.Width(line) + boxPaddingRunes; w > questInner {
 				questInner = w
 			}
 			questLines = append(questLines, line)
 		}
-		if questInner < boxMinInner {
-			questInner = boxMinInner
-		}
-		if questInner > maxQuestBoxWidth {
-			questInner = maxQuestBoxWidth
-		}
+		questInner = min(max(questInner, boxMinInner), m.questBoxCap())
 		b.WriteString(accent.Render(boxTop(questInner)) + "\n")
 		for _, line := range questLines {
 			b.WriteString(accent.Render(boxLine(line, questInner, accent)) + "\n")
 		}
 	}
-	b.WriteString(accent.Render(boxBottom(questInner)) + "\n\n")
-	b.WriteString(dim.Render("  [a] add  [d] delete  [space] complete  [s] settings  [q] quit"))
+	b.WriteString(accent.Render(boxBottom(questInner)) + "\n")
+	if h, ok := m.selectedHabit(); ok {
+		b.WriteString(m.expPreviewLine(h, dim, reward) + "\n")
+	}
+	b.WriteString("\n")
+	complete, detail := questKeyHint(u.CompleteKey)
+	b.WriteString(dim.Render(fmt.Sprintf("  [%s/1-9] complete  [%s] detail  [a] add  [e] edit  [d] delete  [C] all  [o] sort  [f] focus", complete, detail)))
+	b.WriteString("\n")
+	b.WriteString(dim.Render("  [w] week  [t] stats  [r] rankings  [c] compare  [A] archive  [L] activity  [s] settings  [?] help  [q] quit"))
+	// The border shows today's progress at a glance; the clock tick keeps it
+	// current across the reset
+	if color, ok := progressColor(u.TodayProgress()); ok {
+		boxBorder = boxBorder.BorderForeground(color)
+	}
 	return boxBorder.Render(b.String())
 }
 
+// renderRanks draws the leaderboard, with the user's own rank appended when
+// they aren't in the top list
+func (m model) renderRanks(accent, dim, reward, errStyle lipgloss.Style, systemTitle func(string) string) string {
+	var b strings.Builder
+	b.WriteString(systemTitle("◆  S Y S T E M"))
+	b.WriteString(dim.Render("  —  Hunter Rankings"))
+	if m.standings.Season > 0 {
+		b.WriteString(dim.Render(fmt.Sprintf("  ·  Season %d", m.standings.Season)))
+	}
+	b.WriteString("\n\n")
+
+	entryLine := func(e store.LeaderboardEntry) string {
+		rank, color := hunterRank(e.Level)
+		name := e.Username
+		nameStyle := accent
+		if e.Username == m.userData.Username {
+			name += " (you)"
+			nameStyle = reward
+		}
+		return dim.Render(fmt.Sprintf("#%-4d", e.Rank)) + nameStyle.Render(fmt.Sprintf("%-20s", truncateQuestName(name, 20))) +
+			dim.Render(" Lv ") + reward.Render(fmt.Sprintf("%-3d", e.Level)) + " " +
+			m.themeRenderer().NewStyle().Foreground(color).Render(rank)
+	}
+
+	var lines []string
+	lines = append(lines, accent.Render("Top Hunters"))
+	if m.standingsErr != "" {
+		lines = append(lines, errStyle.Render(m.standingsErr))
+	} else if len(m.standings.Top) == 0 {
+		lines = append(lines, dim.Render("No hunters yet."))
+	}
+	for _, e := range m.standings.Top {
+		lines = append(lines, entryLine(e))
+	}
+	if len(m.standings.Around) > 0 {
+		lines = append(lines, dim.Render("…"))
+		for _, e := range m.standings.Around {
+			lines = append(lines, entryLine(e))
+		}
+		if last := m.standings.Around[len(m.standings.Around)-1]; last.Rank < m.standings.Total {
+			lines = append(lines, dim.Render("…"))
+		}
+	}
+	if m.standings.Total > 0 {
+		lines = append(lines, "", dim.Render(fmt.Sprintf("%d hunters registered", m.standings.Total)))
+	}
+
+	inner := boxMinInner
+	for _, line := range lines {
+		if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+			inner = w
+		}
+	}
+	b.WriteString(accent.Render(boxTop(inner)) + "\n")
+	for _, line := range lines {
+		b.WriteString(accent.Render(boxLine(line, inner, accent)) + "\n")
+	}
+	b.WriteString(accent.Render(boxBottom(inner)) + "\n\n")
+	b.WriteString(dim.Render("  [Esc] back  [q] quit"))
+	return b.String()
+}
+
+// statBarWidth is the length of the longest bar in the stats chart
+const statBarWidth = 24
+
+// statBar draws value as a bar scaled so that top fills statBarWidth. Any
+// positive value gets at least one cell so small stats stay visible.
+func statBar(value, top int) (filled, empty string) {
+	n := 0
+	if top > 0 && value > 0 {
+		n = max(value*statBarWidth/top, 1)
+	}
+	n = min(n, statBarWidth)
+	return strings.Repeat("█", n), strings.Repeat("░", statBarWidth-n)
+}
+
+// renderStats charts STR, VIT, AGI and INT as bars scaled to the highest one
+func (m model) renderStats(accent, dim, reward, toastStyle lipgloss.Style, systemTitle func(string) string) string {
+	u := m.userData
+	var b strings.Builder
+	b.WriteString(systemTitle("◆  S Y S T E M"))
+	b.WriteString(dim.Render("  —  Hunter Stats"))
+	b.WriteString("\n\n")
+
+	stats := []struct {
+		name  string
+		value int
+	}{{"STR", u.STR}, {"VIT", u.VIT}, {"AGI", u.AGI}, {"INT", u.INT}}
+	top, total := 0, 0
+	for _, st := range stats {
+		top = max(top, st.value)
+		total += st.value
+	}
+
+	lines := []string{accent.Render("Stats") + dim.Render(fmt.Sprintf("  Level %d", u.Level)), ""}
+	var strongest []string
+	for _, st := range stats {
+		style := m.themeRenderer().NewStyle().Bold(true).Foreground(statColor(st.name))
+		filled, empty := statBar(st.value, top)
+		lines = append(lines, style.Render(st.name)+" "+style.Render(filled)+dim.Render(empty)+" "+reward.Render(fmt.Sprintf("%3d", st.value)))
+		if st.value == top {
+			strongest = append(strongest, st.name)
+		}
+	}
+	lines = append(lines, "")
+	if len(strongest) == len(stats) {
+		lines = append(lines, dim.Render("Perfectly balanced. Level up to grow."))
+	} else {
+		lines = append(lines, dim.Render("Strongest: ")+accent.Render(strings.Join(strongest, ", ")))
+	}
+	lines = append(lines, dim.Render(fmt.Sprintf("Total %d", total)))
+	sinceMiss, missed := u.DaysSinceLastMiss()
+	sinceLine := dim.Render("Days since last miss  ") + reward.Render(fmt.Sprintf("%d", sinceMiss))
+	if !missed {
+		sinceLine += dim.Render("  (never missed)")
+	}
+	lines = append(lines, "", accent.Render("Resilience"), sinceLine,
+		dim.Render("Best comeback         ")+reward.Render(fmt.Sprintf("%d", u.BestComeback()))+dim.Render(" days"))
+	if len(u.Seasons) > 0 {
+		last := u.Seasons[len(u.Seasons)-1]
+		lines = append(lines, "", accent.Render("Seasons"),
+			dim.Render("Lifetime EXP          ")+reward.Render(fmt.Sprintf("%d", u.TotalEXP())),
+			dim.Render("Last season           ")+reward.Render(fmt.Sprintf("Lv %d", last.Level))+dim.Render(fmt.Sprintf(" (%d EXP)", last.EXP)))
+	}
+	lines = append(lines, "", accent.Render("Streak Shields ")+reward.Render(fmt.Sprintf("🛡 %d/%d", u.Shields, store.MaxShields)),
+		dim.Render("Each shield covers one missed day of your streak."))
+
+	inner := boxMinInner
+	for _, line := range lines {
+		if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+			inner = w
+		}
+	}
+	b.WriteString(accent.Render(boxTop(inner)) + "\n")
+	for _, line := range lines {
+		b.WriteString(accent.Render(boxLine(line, inner, accent)) + "\n")
+	}
+	b.WriteString(accent.Render(boxBottom(inner)) + "\n\n")
+	if m.lastToast != "" {
+		b.WriteString(toastStyle.Render("  ▶ "+m.lastToast) + "\n\n")
+	}
+	b.WriteString(dim.Render(fmt.Sprintf("  [b] buy a shield (%d EXP)  [Esc] back  [q] quit", store.ShieldCost)))
+	return b.String()
+}
+
+// renderCompare shows the compare prompt or, once a hunter is loaded, the
+// user and that hunter side by side with the better value of each row lit
+func (m model) renderCompare(accent, dim, reward, errStyle lipgloss.Style, systemTitle func(string) string) string {
+	var b strings.Builder
+	b.WriteString(systemTitle("◆  S Y S T E M"))
+	b.WriteString(dim.Render("  —  Compare Hunters"))
+	b.WriteString("\n\n")
+
+	if m.compareWith == nil {
+		b.WriteString(accent.Render("  Hunter  ") + dim.Render("› ") + m.compareName + "_")
+		b.WriteString("\n\n")
+		if m.compareErr != "" {
+			b.WriteString(errStyle.Render("  ⚠ "+m.compareErr) + "\n\n")
+		}
+		b.WriteString(dim.Render("  [Enter] compare  [Esc] back"))
+		return b.String()
+	}
+
+	me, them := m.userData.Public(), m.compareWith
+	const colWidth = 16
+	cell := func(s string, style lipgloss.Style) string {
+		s = truncateQuestName(s, colWidth-2)
+		return style.Render(s) + strings.Repeat(" ", max(colWidth-lipgloss.Width(s), 0))
+	}
+	// row renders one stat; the higher side is highlighted
+	row := func(label string, a, b int, format string) string {
+		aStyle, bStyle := accent, accent
+		if a > b {
+			aStyle = reward
+		} else if b > a {
+			bStyle = reward
+		}
+		return dim.Render(fmt.Sprintf("%-10s", label)) + cell(fmt.Sprintf(format, a), aStyle) + cell(fmt.Sprintf(format, b), bStyle)
+	}
+	rankOf := func(level int) string {
+		rank, _ := hunterRank(level)
+		return rank
+	}
+	title := func(p *store.PublicUserData) string {
+		if p.Title == "" {
+			return "—"
+		}
+		return p.Title
+	}
+	lines := []string{
+		dim.Render(fmt.Sprintf("%-10s", "")) + cell(me.Username+" (you)", reward) + cell(them.Username, accent),
+		dim.Render(fmt.Sprintf("%-10s", "Rank")) + cell(rankOf(me.Level), accent) + cell(rankOf(them.Level), accent),
+		dim.Render(fmt.Sprintf("%-10s", "Title")) + cell(title(me), accent) + cell(title(them), accent),
+		"",
+		row("Level", me.Level, them.Level, "%d"),
+		row("Prestige", me.Prestige, them.Prestige, "★%d"),
+		row("EXP", me.EXP, them.EXP, "%d"),
+		row("Streak", me.CurrentStreak, them.CurrentStreak, "🔥 %d"),
+		row("Best", me.LongestStreak, them.LongestStreak, "%d days"),
+		row("Quests", me.Quests, them.Quests, "%d"),
+		"",
+		row("STR", me.STR, them.STR, "%d"),
+		row("VIT", me.VIT, them.VIT, "%d"),
+		row("AGI", me.AGI, them.AGI, "%d"),
+		row("INT", me.INT, them.INT, "%d"),
+	}
+
+	inner := boxMinInner
+	for _, line := range lines {
+		if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+			inner = w
+		}
+	}
+	b.WriteString(accent.Render(boxTop(inner)) + "\n")
+	for _, line := range lines {
+		b.WriteString(accent.Render(boxLine(line, inner, accent)) + "\n")
+	}
+	b.WriteString(accent.Render(boxBottom(inner)) + "\n\n")
+	b.WriteString(dim.Render("  [Esc] compare another  [q] quit"))
+	return b.String()
+}
+
+// helpWindow is how many help lines fit on screen, leaving room for the
+// title, box and key hints
+func (m model) helpWindow() int {
+	if m.height == 0 {
+		return activityWindow
+	}
+	return max(m.height-10, 5)
+}
+
+// renderHelp draws the help page, scrolled to helpScroll
+func (m model) renderHelp(accent, dim, reward lipgloss.Style, systemTitle func(string) string) string {
+	var b strings.Builder
+	b.WriteString(systemTitle("◆  S Y S T E M"))
+	b.WriteString(dim.Render("  —  Help"))
+	b.WriteString("\n\n")
+
+	text := m.themeRenderer().NewStyle()
+	lines := renderMarkdown(m.help, helpWidth, accent.Bold(true), accent, text, reward.Bold(true))
+	end := min(m.helpScroll+m.helpWindow(), len(lines))
+	shown := lines[min(m.helpScroll, end):end]
+
+	inner := boxMinInner
+	for _, line := range lines {
+		if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+			inner = w
+		}
+	}
+	b.WriteString(accent.Render(boxTop(inner)) + "\n")
+	for _, line := range shown {
+		b.WriteString(accent.Render(boxLine(line, inner, accent)) + "\n")
+	}
+	b.WriteString(accent.Render(boxBottom(inner)) + "\n\n")
+	up, down, _, _ := navHint(m.keymap)
+	hint := "  [Esc] back  [q] quit"
+	if len(lines) > len(shown) {
+		hint = fmt.Sprintf("  [%s] [%s] scroll  [PgUp] [PgDn] page  %d-%d of %d  [Esc] back  [q] quit", up, down, m.helpScroll+1, end, len(lines))
+	}
+	b.WriteString(dim.Render(hint))
+	return b.String()
+}
+
+// renderActivity lists the user's recent audit events, newest first
+func (m model) renderActivity(accent, dim, errStyle lipgloss.Style, systemTitle func(string) string) string {
+	var b strings.Builder
+	b.WriteString(systemTitle("◆  S Y S T E M"))
+	b.WriteString(dim.Render("  —  Recent Activity"))
+	b.WriteString("\n\n")
+
+	var lines []string
+	switch {
+	case m.activityErr != "":
+		lines = append(lines, errStyle.Render(m.activityErr))
+	case len(m.activity) == 0:
+		lines = append(lines, dim.Render("No recent activity."))
+	}
+	end := min(m.activityScroll+activityWindow, len(m.activity))
+	for _, ev := range m.activity[m.activityScroll:end] {
+		lines = append(lines, dim.Render(ev.Time.Local().Format(m.dates().stamp)+"  ")+describeEvent(ev))
+	}
+	if len(m.activity) > activityWindow {
+		lines = append(lines, "", dim.Render(fmt.Sprintf("%d-%d of %d", m.activityScroll+1, end, len(m.activity))))
+	}
+
+	inner := boxMinInner
+	for _, line := range lines {
+		if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+			inner = w
+		}
+	}
+	b.WriteString(accent.Render(boxTop(inner)) + "\n")
+	for _, line := range lines {
+		b.WriteString(accent.Render(boxLine(line, inner, accent)) + "\n")
+	}
+	b.WriteString(accent.Render(boxBottom(inner)) + "\n\n")
+	up, down, _, _ := navHint(m.keymap)
+	b.WriteString(dim.Render(fmt.Sprintf("  [%s] [%s] scroll  [Esc] back  [q] quit", up, down)))
+	return b.String()
+}
+
+// renderTokens lists the user's API tokens; a new token's plaintext is
+// shown once, right after it's created
+func (m model) renderTokens(accent, dim, reward, errStyle lipgloss.Style, systemTitle func(string) string) string {
+	var b strings.Builder
+	b.WriteString(systemTitle("◆  S Y S T E M"))
+	b.WriteString(dim.Render("  —  API Tokens"))
+	b.WriteString("\n\n")
+
+	lines := []string{dim.Render("Send as \"Authorization: Bearer <token>\" to the HTTP API.")}
+	if len(m.userData.APITokens) == 0 {
+		lines = append(lines, "", dim.Render("No API tokens."))
+	} else {
+		lines = append(lines, "")
+	}
+	for i, t := range m.userData.APITokens {
+		arrow := "   "
+		if m.tokenCursor == i {
+			arrow = accent.Render(" ▸ ")
+		}
+		lines = append(lines, arrow+t.Label+"  "+dim.Render("created "+t.Created.Local().Format(m.dates().date)+"  #"+t.ID))
+	}
+	if m.newToken != "" {
+		lines = append(lines, "", reward.Render(m.newToken), dim.Render("Copy it now — it won't be shown again."))
+	}
+
+	inner := boxMinInner
+	for _, line := range lines {
+		if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+			inner = w
+		}
+	}
+	b.WriteString(accent.Render(boxTop(inner)) + "\n")
+	for _, line := range lines {
+		b.WriteString(accent.Render(boxLine(line, inner, accent)) + "\n")
+	}
+	b.WriteString(accent.Render(boxBottom(inner)) + "\n\n")
+	if m.tokenErr != "" {
+		b.WriteString(errStyle.Render("  ⚠ "+m.tokenErr) + "\n\n")
+	}
+	switch {
+	case m.tokenLabel != nil:
+		b.WriteString(accent.Render("  Label  ") + dim.Render("› ") + *m.tokenLabel + "_\n\n")
+		b.WriteString(dim.Render("  [Enter] create  [Esc] cancel"))
+	case m.confirmRevoke:
+		b.WriteString(errStyle.Render(fmt.Sprintf("  Revoke '%s'? Clients using it stop working. [y/n]", m.userData.APITokens[m.tokenCursor].Label)))
+	default:
+		up, down, _, _ := navHint(m.keymap)
+		b.WriteString(dim.Render(fmt.Sprintf("  [%s] [%s] move  [n] new  [d] revoke  [Esc] back  [q] quit", up, down)))
+	}
+	return b.String()
+}
+
+// renderReset asks for the hunter name before exporting and wiping progress
+func (m model) renderReset(accent, dim, errStyle lipgloss.Style, systemTitle func(string) string) string {
+	var b strings.Builder
+	b.WriteString(systemTitle("◆  S Y S T E M"))
+	b.WriteString(dim.Render("  —  Reset Everything"))
+	b.WriteString("\n\n")
+
+	lines := []string{
+		errStyle.Render("Start over from level 1."),
+		"",
+		dim.Render("Quests, history, EXP, level, stats, streaks, titles,"),
+		dim.Render("shields and past seasons are wiped. Your login,"),
+		dim.Render("settings and API tokens stay. A backup of everything"),
+		dim.Render("is saved on the server first; ask the admin if you"),
+		dim.Render("want it back."),
+	}
+	inner := boxMinInner
+	for _, line := range lines {
+		if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+			inner = w
+		}
+	}
+	b.WriteString(errStyle.Render(boxTop(inner)) + "\n")
+	for _, line := range lines {
+		b.WriteString(errStyle.Render(boxLine(line, inner, errStyle)) + "\n")
+	}
+	b.WriteString(errStyle.Render(boxBottom(inner)) + "\n\n")
+	b.WriteString(dim.Render("  Type ") + accent.Render(m.userData.Username) + dim.Render(" to confirm") + "\n")
+	b.WriteString(accent.Render("  Name  ") + dim.Render("› ") + m.resetConfirm + "_\n\n")
+	if m.resetErr != "" {
+		b.WriteString(errStyle.Render("  ⚠ "+m.resetErr) + "\n\n")
+	}
+	b.WriteString(dim.Render("  [Enter] reset everything  [Esc] back"))
+	return b.String()
+}
+
+// renderArchive lists archived quests with how long ago each was last done
+func (m model) renderArchive(accent, dim, toastStyle lipgloss.Style, systemTitle func(string) string) string {
+	var b strings.Builder
+	b.WriteString(systemTitle("◆  S Y S T E M"))
+	b.WriteString(dim.Render("  —  Archived Quests"))
+	b.WriteString("\n\n")
+
+	var lines []string
+	if len(m.userData.Archived) == 0 {
+		lines = append(lines, dim.Render("No archived quests."))
+	}
+	for i, h := range m.userData.Archived {
+		arrow := "   "
+		if m.archiveCursor == i {
+			arrow = accent.Render(" ▸ ")
+		}
+		lines = append(lines, arrow+h.Icon+" "+truncateQuestName(h.Name, maxQuestNameRunes))
+	}
+
+	inner := boxMinInner
+	for _, line := range lines {
+		if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+			inner = w
+		}
+	}
+	b.WriteString(accent.Render(boxTop(inner)) + "\n")
+	for _, line := range lines {
+		b.WriteString(accent.Render(boxLine(line, inner, accent)) + "\n")
+	}
+	b.WriteString(accent.Render(boxBottom(inner)) + "\n\n")
+	if m.lastToast != "" {
+		b.WriteString(toastStyle.Render("  "+m.lastToast) + "\n\n")
+	}
+	up, down, _, _ := navHint(m.keymap)
+	b.WriteString(dim.Render(fmt.Sprintf("  [%s] [%s] move  [Space] restore  [Esc] back  [q] quit", up, down)))
+	return b.String()
+}
+
+// describeEvent turns an audit event into a short sentence for the activity view
+func describeEvent(ev store.AuditEvent) string {
+	quest := "'" + truncateQuestName(ev.Habit, maxQuestNameRunes) + "'"
+	switch ev.Type {
+	case store.AuditLogin:
+		return "Logged in"
+	case store.AuditLoginFailed:
+		return "Failed login attempt"
+	case store.AuditLockout:
+		return "Locked out after too many failed logins"
+	case store.AuditHabitAdded:
+		return "Added quest " + quest
+	case store.AuditHabitRemoved:
+		return "Removed quest " + quest
+	case store.AuditComplete:
+		return fmt.Sprintf("Completed %s (+%d EXP)", quest, store.EXPPerQuest)
+	case store.AuditUncomplete:
+		return fmt.Sprintf("Unchecked %s (-%d EXP)", quest, store.EXPPerQuest)
+	case store.AuditLevelUp:
+		return fmt.Sprintf("Reached level %d", ev.Level)
+	case store.AuditStreak:
+		if ev.Streak == 0 {
+			return "Streak broken"
+		}
+		return fmt.Sprintf("Streak now %d days", ev.Streak)
+	case store.AuditPrestige:
+		return "Prestiged (" + ev.Detail + ")"
+	case store.AuditMilestone:
+		return fmt.Sprintf("%d-day streak milestone (%s)", ev.Streak, ev.Detail)
+	case store.AuditPasswordReset:
+		return "Password reset by an admin"
+	case store.AuditPasswordChanged:
+		return "Changed password"
+	case store.AuditAdminEdit:
+		return "Record edited by an admin"
+	case store.AuditShield:
+		return "Streak shield " + ev.Detail
+	case store.AuditHabitArchived:
+		return "Archived quest " + quest + " " + ev.Detail
+	case store.AuditHabitRestored:
+		return "Restored quest " + quest
+	case store.AuditTokenCreated:
+		return "Created API token '" + ev.Detail + "'"
+	case store.AuditTokenRevoked:
+		return "Revoked API token '" + ev.Detail + "'"
+	case store.AuditHardReset:
+		return "Reset everything (backup " + ev.Detail + ")"
+	case store.AuditSeason:
+		return fmt.Sprintf("New season, ended the last at Lv%d (%d EXP)", ev.Level, ev.EXP)
+	case store.AuditSpotlight:
+		return "Spotlight on " + quest + " (" + ev.Detail + ")"
+	case store.AuditDayComplete:
+		return fmt.Sprintf("Completed every quest for %s", ev.Day)
+	}
+	return ev.Type
+}
+
+// renderHistory lists the last BackfillDays days for one quest so missed
+// days can be completed after the fact
+func (m model) renderHistory(h store.Habit, accent, dim, reward, errStyle, toastStyle lipgloss.Style, systemTitle func(string) string) string {
+	var b strings.Builder
+	b.WriteString(systemTitle("◆  S Y S T E M"))
+	b.WriteString(dim.Render("  —  Quest History"))
+	b.WriteString("\n\n")
+
+	lines := []string{accent.Render(truncateQuestName(h.Name, maxQuestNameRunes))}
+	for i := 0; i <= store.BackfillDays; i++ {
+		key := m.historyDay(i)
+		label := key
+		switch i {
+		case 0:
+			label = "Today"
+		case 1:
+			label = "Yesterday"
+		default:
+			label = formatDayKey(key, m.dates().day)
+		}
+		arrow := "   "
+		if m.historyCursor == i {
+			arrow = accent.Render(" ▸ ")
+		}
+		check := dim.Render("[ ]")
+		if m.userData.CompletedOn(h.ID, key) {
+			check = reward.Render("[✓]")
+		} else if m.userData.MissedOn(h.ID, key) {
+			check = errStyle.Render("[✗]")
+			label += errStyle.Render("  missed")
+		}
+		if exp := m.userData.EXPOn(key); exp > 0 {
+			label += dim.Render(fmt.Sprintf("  +%d EXP that day", exp))
+		}
+		lines = append(lines, arrow+check+" "+label)
+	}
+
+	inner := boxMinInner
+	for _, line := range lines {
+		if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+			inner = w
+		}
+	}
+	b.WriteString(accent.Render(boxTop(inner)) + "\n")
+	for _, line := range lines {
+		b.WriteString(accent.Render(boxLine(line, inner, accent)) + "\n")
+	}
+	b.WriteString(accent.Render(boxBottom(inner)) + "\n\n")
+	if m.lastToast != "" {
+		b.WriteString(toastStyle.Render("  ▶ "+m.lastToast) + "\n\n")
+	}
+	b.WriteString(dim.Render("  [space] toggle day  [Esc] back  [q] quit"))
+	return b.String()
+}
+
+// renderDetail draws the full record of one quest, including its note
+func (m model) renderDetail(h store.Habit, accent, dim, reward, toastStyle lipgloss.Style, systemTitle func(string) string) string {
+	var b strings.Builder
+	b.WriteString(systemTitle("◆  S Y S T E M"))
+	b.WriteString(dim.Render("  —  Quest Detail"))
+	b.WriteString("\n\n")
+
+	status := dim.Render("[ ] not completed today")
+	if m.userData.CompletedToday(h.ID) {
+		status = reward.Render("[✓] completed today")
+		if m.userData.LockedIn(h.ID, m.userData.TodayKey()) {
+			status += dim.Render("  🔒 locked in")
+		}
+	} else if m.userData.ChainLocked(h.ID, m.userData.TodayKey()) {
+		status += dim.Render("  🔒 locked")
+	}
+	note := dim.Render("No note. Press [e] to add one.")
+	if h.Note != "" {
+		note = h.Note
+	}
+	streak := m.userData.HabitStreak(h.ID)
+	streakLine := dim.Render("No current streak")
+	if streak > 0 {
+		streakLine = streakStyle(m.themeRenderer(), streak).Render(fmt.Sprintf("🔥 %d-day streak", streak))
+	}
+	detailName := accent
+	if style, ok := questNameStyle(m.themeRenderer(), h); ok {
+		detailName = style.Bold(true)
+	}
+	rate := m.userData.HabitCompletionRate(h.ID)
+	rateLine := dim.Render("Completed ") + accent.Render(fmt.Sprintf("%.0f%%", rate*100)) +
+		dim.Render(" of days since added")
+	if h.ReminderHour != nil {
+		rateLine += dim.Render(fmt.Sprintf("  ⏰ %02d:00", *h.ReminderHour))
+	}
+	lines := []string{
+		h.Icon + " " + detailName.Render(h.Name), // in full; the quest list may have truncated it
+		status,
+		streakLine,
+		rateLine,
+	}
+	if done, target := m.userData.WeeklyProgress(h.ID); target > 0 {
+		week := dim.Render(fmt.Sprintf("Goal %d× a week: ", target)) + accent.Render(fmt.Sprintf("%d/%d this week", done, target))
+		if m.userData.WeeklyOnTrack(h.ID) && !m.userData.CompletedToday(h.ID) {
+			week += dim.Render("  (on track without today)")
+		}
+		lines = append(lines, week)
+	}
+	if pre, ok := m.userData.Prerequisite(h); ok {
+		lines = append(lines, dim.Render("Unlocked each day by ")+pre.Icon+" "+truncateQuestName(pre.Name, maxQuestNameRunes))
+	}
+	if spot, ok := m.userData.Spotlight(); ok && spot.ID == h.ID {
+		lines = append(lines, reward.Render(fmt.Sprintf("⭐ This week's spotlight: +%d EXP extra", store.SpotlightBonus)))
+	}
+	lines = append(lines, "", dim.Render("Note"), note)
+
+	inner := boxMinInner
+	for _, line := range lines {
+		if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+			inner = w
+		}
+	}
+	b.WriteString(accent.Render(boxTop(inner)) + "\n")
+	for _, line := range lines {
+		b.WriteString(accent.Render(boxLine(line, inner, accent)) + "\n")
+	}
+	b.WriteString(accent.Render(boxBottom(inner)) + "\n\n")
+	if m.lastToast != "" {
+		b.WriteString(toastStyle.Render("  ▶ "+m.lastToast) + "\n\n")
+	}
+	complete, _ := questKeyHint(m.userData.CompleteKey)
+	b.WriteString(dim.Render(fmt.Sprintf("  [%s] complete  [e] edit  [c] history  [Esc] back  [q] quit", complete)))
+	return b.String()
+}
+
+// renderReport draws the weekly summary for m.reportWeekOffset
+// weekdayInitials label the days of an ISO week, Monday first
+var weekdayInitials = [7]string{"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"}
+
+func (m model) renderReport(accent, dim, reward lipgloss.Style, systemTitle func(string) string) string {
+	rep := m.userData.WeeklySummary(m.reportWeekOffset)
+
+	var b strings.Builder
+	b.WriteString(systemTitle("◆  S Y S T E M"))
+	b.WriteString(dim.Render("  —  Weekly Report"))
+	b.WriteString("\n\n")
+	b.WriteString(accent.Render("  Week ") + reward.Render(formatDayKey(rep.WeekStart, m.dates().date)) + dim.Render(" → ") + reward.Render(formatDayKey(rep.WeekEnd, m.dates().date)))
+	if m.reportWeekOffset == 0 {
+		b.WriteString(dim.Render("  (this week)"))
+	}
+	b.WriteString("\n\n")
+
+	lines := []string{
+		accent.Render("Summary"),
+		dim.Render("Days completed  ") + reward.Render(fmt.Sprintf("%d/%d", rep.DaysCompleted, rep.DaysElapsed)),
+		dim.Render("EXP gained      ") + reward.Render(fmt.Sprintf("+%d", rep.TotalEXP)),
+		dim.Render("Best streak     ") + reward.Render(fmt.Sprintf("%d days", rep.BestStreak)),
+	}
+	perDay := make([]string, 0, rep.DaysElapsed)
+	for d, exp := range rep.EXPByDay[:rep.DaysElapsed] {
+		perDay = append(perDay, dim.Render(weekdayInitials[d]+" ")+reward.Render(strconv.Itoa(exp)))
+	}
+	if len(perDay) > 0 {
+		lines = append(lines, dim.Render("EXP per day     ")+strings.Join(perDay, "  "))
+	}
+	if len(rep.Habits) > 0 {
+		lines = append(lines, "", accent.Render("Quests"))
+	}
+	for _, h := range rep.Habits {
+		name := truncateQuestName(h.Name, maxQuestNameRunes)
+		if h.Eligible == 0 {
+			lines = append(lines, name+"  "+dim.Render("not yet added"))
+			continue
+		}
+		lines = append(lines, name+"  "+dim.Render(fmt.Sprintf("%d/%d ", h.Completed, h.Eligible))+
+			reward.Render(fmt.Sprintf("%d%%", int(h.Rate()*100))))
+	}
+
+	inner := boxMinInner
+	for _, line := range lines {
+		if w := lipgloss.Width(line) + boxPaddingRunes; w > inner {
+			inner = w
+		}
+	}
+	b.WriteString(accent.Render(boxTop(inner)) + "\n")
+	for _, line := range lines {
+		b.WriteString(accent.Render(boxLine(line, inner, accent)) + "\n")
+	}
+	b.WriteString(accent.Render(boxBottom(inner)) + "\n\n")
+	_, _, left, right := navHint(m.keymap)
+	b.WriteString(dim.Render("  [" + left + "] prev week  [" + right + "] next week  [Esc] back  [q] quit"))
+	return b.String()
+}
+
 func main() {
-	hostKeyPath := "ssh_host_key"
-	if _, err := os.Stat(hostKeyPath); err != nil {
-		kp, err := keygen.New(hostKeyPath, keygen.WithKeyType(keygen.Ed25519), keygen.WithWrite())
-		if err != nil {
-			log.Fatalf("generate ssh host key: %v", err)
-		}
-		_ = kp
-		log.Println("generated new SSH host key at", hostKeyPath)
-	}
-	s, err := wish.NewServer(
-		wish.WithAddress(":23234"),
-		wish.WithHostKeyPath(hostKeyPath),
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdmin(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	configPath := flag.String("config", os.Getenv("SYSTEM_CONFIG"), "YAML config file; environment variables and flags override it")
+	flag.String("http", "", "address for the optional JSON API (e.g. :8080); disabled when empty")
+	flag.String("health", "", "address for the health check endpoint (e.g. :8081); disabled when empty")
+	flag.String("host-keys", "ed25519", "comma-separated SSH host key types to load or generate: ed25519, rsa, ecdsa")
+	flag.Bool("regenerate-bad-host-keys", false, "replace a host key file that exists but can't be parsed, keeping it as a .bak file")
+	flag.Parse()
+
+	cfg, err := loadConfig(*configPath, os.Getenv)
+	if err == nil {
+		err = cfg.applyFlags(flag.CommandLine)
+	}
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	cfg.apply()
+	log.Printf("stat allocation provider: %s", gemini.Allocator)
+
+	if err := store.CheckDataDir(); err != nil {
+		log.Fatalf("data_dir (SYSTEM_DATA_DIR) %q can't be used: %v", store.DataDir, err)
+	}
+	if n, err := store.MigrateFlatLayout(); err != nil {
+		log.Fatalf("migrating data directory: %v", err)
+	} else if n > 0 {
+		log.Printf("moved %d users into sharded data directories", n)
+	}
+	if n, err := store.MigrateUsernames(); err != nil {
+		log.Fatalf("normalizing usernames: %v", err)
+	} else if n > 0 {
+		log.Printf("renamed %d users to their NFC usernames", n)
+	}
+	if store.DemoUser != "" && store.UserExists(store.DemoUser) {
+		log.Fatalf("demo_user (SYSTEM_DEMO_USER) %q is an existing account; pick a name nobody has registered", store.DemoUser)
+	}
+	loginBanner.get() // start the first scan before anyone connects
+
+	keyTypes, err := parseHostKeyTypes(cfg.HostKeys)
+	if err != nil {
+		log.Fatalf("host_keys: %v", err)
+	}
+	keyOpts, err := hostKeyOptions("", keyTypes)
+	if err != nil {
+		log.Fatalf("ssh host keys: %v", err)
+	}
+	if sshAuth {
+		keyOpts = append(keyOpts, wish.WithKeyboardInteractiveAuth(keyboardInteractive))
+	}
+	opts := append(keyOpts,
+		wish.WithAddress(sshAddr),
 		wish.WithMiddleware(
 			logging.Middleware(),
 			bubbletea.Middleware(func(sess ssh.Session) (tea.Model, []tea.ProgramOption) {
-				return initialModel(sess), []tea.ProgramOption{tea.WithAltScreen()}
+				return initialModel(sess), []tea.ProgramOption{tea.WithAltScreen(), tea.WithFilter(newNavCoalescer().filter)}
 			}),
+			requirePTY(),     // before the TUI starts, so scripted connections get a hint
+			sessionCounter(), // last, so it wraps the whole session
 		),
 	)
+	s, err := wish.NewServer(opts...)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	log.Println("⚔ SYSTEM — Habit tracker listening on :23234")
-	log.Println("   Connect: ssh -p 23234 user@localhost  (production: ssh system.hostagedown.com)")
+	if cfg.HealthAddr != "" {
+		serveHealth(cfg.HealthAddr, time.Now())
+	}
+	publishLeaderboard()
+	startWebhooks()
+	if cfg.HTTPAddr != "" {
+		srv := &http.Server{
+			Addr:              cfg.HTTPAddr,
+			Handler:           api.NewHandler(),
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      gemini.Timeout + 10*time.Second, // a level-up waits for its stats
+		}
+		go func() {
+			log.Println("⚔ SYSTEM — JSON API listening on", cfg.HTTPAddr)
+			if err := srv.ListenAndServe(); err != nil {
+				log.Println("JSON API stopped:", err)
+			}
+		}()
+	}
+	log.Println("⚔ SYSTEM — Habit tracker listening on", sshAddr)
+	if _, port, err := net.SplitHostPort(sshAddr); err == nil {
+		log.Printf("   Connect: ssh -p %s user@localhost  (production: ssh system.hostagedown.com)", port)
+	}
 	log.Println("   Then enter your username and password in the app.")
 	log.Fatal(s.ListenAndServe())
 }
@@ -0,0 +1,707 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "server-test")
+	if err != nil {
+		panic(err)
+	}
+	store.DataDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+var testUsers int
+
+// newTestUser saves a hunter who has seen the tutorial, with the given
+// quests and a midnight reset so day keys are calendar dates
+func newTestUser(t *testing.T, habits ...string) *store.UserData {
+	t.Helper()
+	testUsers++
+	u := &store.UserData{
+		Username:         fmt.Sprintf("hunter%d", testUsers),
+		Level:            store.DefaultLevel,
+		DailyCompletions: make(map[string]map[string]bool),
+		Keymap:           store.KeymapDefault,
+		Theme:            store.ThemeSystemBlue,
+		SortMode:         store.SortManual,
+		CompleteKey:      store.CompleteKeySpace,
+		TutorialSeen:     true,
+		CreatedAt:        time.Now(),
+	}
+	for _, name := range habits {
+		u.AddHabit(name)
+	}
+	if err := store.SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// newTestModel is a session logged in to the main screen as u
+func newTestModel(u *store.UserData) model {
+	r := lipgloss.NewRenderer(io.Discard)
+	m := model{
+		renderer:     r,
+		monoRenderer: r,
+		saver:        &pendingSave{},
+		out:          io.Discard,
+		noBell:       true,
+		width:        100,
+		height:       40,
+		tutorialStep: -1,
+		userData:     u,
+	}
+	m.enterMain()
+	m.lastToast = ""
+	_ = m.saver.flush()
+	return m
+}
+
+// newLoginModel is a session at the login form, connected until the test ends
+func newLoginModel(t *testing.T) model {
+	r := lipgloss.NewRenderer(io.Discard)
+	return model{
+		authState:    authLogin,
+		ctx:          t.Context(),
+		renderer:     r,
+		monoRenderer: r,
+		saver:        &pendingSave{},
+		out:          io.Discard,
+		noBell:       true,
+		width:        100,
+		height:       40,
+		tutorialStep: -1,
+	}
+}
+
+// typeText presses each rune of s
+func typeText(t *testing.T, m model, s string) model {
+	t.Helper()
+	for _, r := range s {
+		m = press(t, m, string(r))
+	}
+	return m
+}
+
+// setFor sets *p to v until the test ends
+func setFor[T any](t *testing.T, p *T, v T) {
+	t.Helper()
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+// keyMsgs are the non-rune keys tests press, by their tea.KeyMsg names
+var keyMsgs = map[string]tea.KeyType{
+	"up":        tea.KeyUp,
+	"down":      tea.KeyDown,
+	"left":      tea.KeyLeft,
+	"right":     tea.KeyRight,
+	"enter":     tea.KeyEnter,
+	"esc":       tea.KeyEsc,
+	"tab":       tea.KeyTab,
+	"backspace": tea.KeyBackspace,
+	" ":         tea.KeySpace,
+	"ctrl+s":    tea.KeyCtrlS,
+	"ctrl+c":    tea.KeyCtrlC,
+}
+
+func keyMsg(key string) tea.KeyMsg {
+	if typ, ok := keyMsgs[key]; ok {
+		return tea.KeyMsg{Type: typ}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+// press sends each key through Update, as the program would, ignoring the
+// commands it returns
+func press(t *testing.T, m model, keys ...string) model {
+	t.Helper()
+	for _, key := range keys {
+		next, _ := m.Update(keyMsg(key))
+		var ok bool
+		if m, ok = next.(model); !ok {
+			t.Fatalf("Update(%q) returned %T", key, next)
+		}
+	}
+	return m
+}
+
+func TestCursorMovesDontSave(t *testing.T) {
+	u := newTestUser(t, "read", "run", "write")
+	m := newTestModel(u)
+	m = press(t, m, "down", "down", "up")
+	if state := m.saver.state(); state != saveClean {
+		t.Errorf("save state after moving the cursor = %v, want clean", state)
+	}
+	if u.SelectedHabit != u.Habits[1].ID {
+		t.Errorf("selected habit = %q, want %q", u.SelectedHabit, u.Habits[1].ID)
+	}
+	saved, err := store.LoadUser(u.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.SelectedHabit != "" {
+		t.Errorf("cursor written before any save: %q", saved.SelectedHabit)
+	}
+
+	m.quit()
+	saved, err = store.LoadUser(u.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.SelectedHabit != u.Habits[1].ID {
+		t.Errorf("cursor saved on quit = %q, want %q", saved.SelectedHabit, u.Habits[1].ID)
+	}
+	if restored := newTestModel(saved); restored.cursor != 1 {
+		t.Errorf("cursor restored to %d, want 1", restored.cursor)
+	}
+}
+
+func TestRegisterEntersMain(t *testing.T) {
+	setFor(t, &store.WelcomeQuestEnabled, true)
+	m := newLoginModel(t)
+	m = press(t, m, "r")
+	m = typeText(t, m, "newhunter")
+	m = press(t, m, "tab")
+	m = typeText(t, m, "Correct-horse-9")
+	m = press(t, m, "enter")
+	if m.authState != authMain || m.userData == nil {
+		t.Fatalf("after registering: state %v, error %q", m.authState, m.authError)
+	}
+	if m.keymap != m.userData.Keymap || m.loginPassword != "" {
+		t.Errorf("login not finished like enterMain: keymap %q, password left %q", m.keymap, m.loginPassword)
+	}
+	if m.tutorialStep != 0 {
+		t.Errorf("tutorial step = %d, want the tutorial to start", m.tutorialStep)
+	}
+	if n := userSessions.count(m.userData.Username); n != 1 {
+		t.Errorf("%d sessions tracked, want 1", n)
+	}
+	if want := glanceToast(m.userData); m.lastToast != want {
+		t.Errorf("toast = %q, want the login glance %q", m.lastToast, want)
+	}
+}
+
+func TestQuestKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		complete string // the hunter's complete key setting
+		keys     []string
+		done     []bool // each quest completed today afterwards
+		cursor   int
+		state    authState
+	}{
+		{name: "space completes", keys: []string{"down", " "}, done: []bool{false, true, false}, cursor: 1, state: authMain},
+		{name: "enter opens details", keys: []string{"enter"}, done: []bool{false, false, false}, state: authDetail},
+		{name: "enter completes when set", complete: store.CompleteKeyEnter, keys: []string{"enter"}, done: []bool{true, false, false}, state: authMain},
+		{name: "space opens details when enter completes", complete: store.CompleteKeyEnter, keys: []string{" "}, done: []bool{false, false, false}, state: authDetail},
+		{name: "last quest", keys: []string{"G"}, done: []bool{false, false, false}, cursor: 2, state: authMain},
+		{name: "first quest", keys: []string{"G", "g"}, done: []bool{false, false, false}, state: authMain},
+		{name: "end and home", keys: []string{"end", "home", "end"}, done: []bool{false, false, false}, cursor: 2, state: authMain},
+		{name: "complete all", keys: []string{"C"}, done: []bool{true, true, true}, state: authMain},
+		{name: "number keys leave the cursor", keys: []string{"3", "1"}, done: []bool{true, false, true}, state: authMain},
+		{name: "number past the list", keys: []string{"4", "9"}, done: []bool{false, false, false}, state: authMain},
+		{name: "number twice unchecks", keys: []string{"2", "2"}, done: []bool{false, false, false}, state: authMain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newTestUser(t, "read", "run", "write")
+			if tt.complete != "" {
+				u.CompleteKey = tt.complete
+			}
+			m := press(t, newTestModel(u), tt.keys...)
+			for i, want := range tt.done {
+				if got := u.CompletedToday(u.Habits[i].ID); got != want {
+					t.Errorf("quest %d completed = %v, want %v (toast %q)", i, got, want, m.lastToast)
+				}
+			}
+			if m.cursor != tt.cursor || m.authState != tt.state {
+				t.Errorf("cursor %d on %v, want %d on %v", m.cursor, m.authState, tt.cursor, tt.state)
+			}
+		})
+	}
+}
+
+func TestLockedToast(t *testing.T) {
+	tests := []struct {
+		policy string
+		grace  time.Duration
+		want   string
+	}{
+		{store.UncheckRefund, 10 * time.Minute, "🔒 'read' is locked in for today."},
+		{store.UncheckLocked, 0, "🔒 'read' is final: completions can't be unchecked on this server."},
+		{store.UncheckLocked, 10 * time.Minute, "🔒 'read' is final: completions can't be unchecked after 10m on this server."},
+	}
+	for _, tt := range tests {
+		setFor(t, &store.UncheckPolicy, tt.policy)
+		setFor(t, &store.UncheckGrace, tt.grace)
+		if got := lockedToast(store.Habit{Name: "read"}); got != tt.want {
+			t.Errorf("%s, grace %s: lockedToast = %q, want %q", tt.policy, tt.grace, got, tt.want)
+		}
+	}
+}
+
+func TestResetEverything(t *testing.T) {
+	u := newTestUser(t, "read", "run")
+	u.EXP, u.Level = 250, 3
+	m := press(t, newTestModel(u), "s", "R")
+	if m.authState != authReset {
+		t.Fatalf("state %v, want the reset screen", m.authState)
+	}
+	m = press(t, typeText(t, m, u.Username), "enter")
+	if u.Level != store.DefaultLevel || len(u.Habits) != 0 {
+		t.Fatalf("after the reset: level %d, %d quests, error %q", u.Level, len(u.Habits), m.resetErr)
+	}
+	if m.authState != authMain || !strings.Contains(m.lastToast, "backed up") {
+		t.Errorf("state %v, toast %q", m.authState, m.lastToast)
+	}
+}
+
+func TestResetEverythingRefused(t *testing.T) {
+	for _, typed := range []string{"someone", ""} {
+		u := newTestUser(t, "read", "run")
+		u.EXP, u.Level = 250, 3
+		m := press(t, typeText(t, press(t, newTestModel(u), "s", "R"), typed), "enter")
+		if u.Level != 3 || len(u.Habits) != 2 {
+			t.Errorf("typing %q reset the hunter: level %d, %d quests", typed, u.Level, len(u.Habits))
+		}
+		if m.resetErr == "" || m.authState != authReset {
+			t.Errorf("typing %q refused without saying why: state %v, error %q", typed, m.authState, m.resetErr)
+		}
+	}
+}
+
+func TestSettingsSave(t *testing.T) {
+	tests := []struct {
+		name  string
+		dates string // the date format field; "" leaves it as opened
+		saved bool
+	}{
+		{"valid", "", true},
+		{"one field invalid", "klingon", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newTestUser(t, "read")
+			m := press(t, newTestModel(u), "s", "up", "tab", "up") // reset hour and keymap changed
+			if tt.dates != "" {
+				m.settingsDates = tt.dates
+			}
+			m = press(t, m, "enter")
+			if saved := u.DayResetHour == 1 && u.Keymap != store.KeymapDefault; saved != tt.saved {
+				t.Fatalf("reset hour %d, keymap %q: saved = %v, want %v", u.DayResetHour, u.Keymap, saved, tt.saved)
+			}
+			if tt.saved {
+				if m.authState != authMain || m.settingsErr != "" || m.saver.state() == saveClean {
+					t.Errorf("state %v, error %q, save %v", m.authState, m.settingsErr, m.saver.state())
+				}
+				return
+			}
+			if m.authState != authSettings || !strings.Contains(m.settingsErr, "date format") {
+				t.Fatalf("state %v, error %q; want to stay in settings saying why", m.authState, m.settingsErr)
+			}
+			if !strings.Contains(m.View(), m.settingsErr) {
+				t.Error("the error isn't shown in the settings view")
+			}
+			if m.saver.state() != saveClean {
+				t.Error("a refused save was queued")
+			}
+		})
+	}
+}
+
+func TestNavKey(t *testing.T) {
+	tests := []struct {
+		keymap, key, want string
+	}{
+		{store.KeymapDefault, "up", "up"},
+		{store.KeymapDefault, "k", "up"},
+		{store.KeymapVim, "up", ""},
+		{store.KeymapVim, "j", "down"},
+		{store.KeymapArrows, "left", "left"},
+		{store.KeymapArrows, "h", "h"},
+		{store.KeymapVim, "q", "q"},
+	}
+	for _, tt := range tests {
+		if got := navKey(tt.keymap, tt.key); got != tt.want {
+			t.Errorf("navKey(%q, %q) = %q, want %q", tt.keymap, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestKeymapMovesCursor(t *testing.T) {
+	tests := []struct {
+		keymap string
+		key    string
+		cursor int
+	}{
+		{store.KeymapDefault, "j", 1},
+		{store.KeymapDefault, "down", 1},
+		{store.KeymapVim, "j", 1},
+		{store.KeymapVim, "down", 0},
+		{store.KeymapArrows, "j", 0},
+		{store.KeymapArrows, "down", 1},
+	}
+	for _, tt := range tests {
+		u := newTestUser(t, "read", "run")
+		u.Keymap = tt.keymap
+		if m := press(t, newTestModel(u), tt.key); m.cursor != tt.cursor {
+			t.Errorf("%s keymap, %q: cursor %d, want %d", tt.keymap, tt.key, m.cursor, tt.cursor)
+		}
+	}
+}
+
+func TestResetPreview(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 30, 0, 0, time.Local)
+	tests := []struct {
+		hour   int
+		format string
+		want   string
+	}{
+		{4, store.DateFormatISO, "Next reset: tomorrow 04:00 (in 17h 30m)"},
+		{14, store.DateFormatISO, "Next reset: today 14:00 (in 3h 30m)"},
+		{14, store.DateFormatUS, "Next reset: today 2:00 PM (in 3h 30m)"},
+	}
+	for _, tt := range tests {
+		if got := resetPreview(tt.hour, now, layoutFor(tt.format)); got != tt.want {
+			t.Errorf("resetPreview(%d) in %s = %q, want %q", tt.hour, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestThemePalettes(t *testing.T) {
+	for _, theme := range store.Themes {
+		p, ok := themePalettes[theme]
+		if !ok {
+			t.Errorf("theme %q has no palette", theme)
+			continue
+		}
+		if !p.mono && (p.primary == "" || p.dim == "" || p.reward == "" || p.err == "") {
+			t.Errorf("theme %q is missing colors: %+v", theme, p)
+		}
+	}
+	if len(themePalettes) != len(store.Themes) {
+		t.Errorf("%d palettes for %d themes", len(themePalettes), len(store.Themes))
+	}
+}
+
+func TestForcedPasswordChange(t *testing.T) {
+	u := newTestUser(t, "read")
+	if err := u.SetPassword("Correct-horse-9"); err != nil {
+		t.Fatal(err)
+	}
+	u.MustChangePassword = true
+	if err := store.SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	m := newLoginModel(t)
+	// Typed, the r in either would switch the form to registering
+	m.loginUsername, m.loginPassword, m.loginFocus = u.Username, "Correct-horse-9", 1
+	m = press(t, m, "enter")
+	if m.authState != authNewPass {
+		t.Fatalf("state after logging in = %v, error %q; want the password change", m.authState, m.authError)
+	}
+
+	m = press(t, typeText(t, m, "Another-horse-7"), "tab")
+	m = press(t, typeText(t, m, "Another-horse-8"), "enter")
+	if m.authState != authNewPass || m.authError == "" {
+		t.Fatalf("mismatched passwords: state %v, error %q", m.authState, m.authError)
+	}
+	m = press(t, typeText(t, m, "Another-horse-7"), "enter")
+	if m.authState != authMain {
+		t.Fatalf("state after the change = %v, error %q", m.authState, m.authError)
+	}
+	if _, err := store.AuthUser(u.Username, "Another-horse-7"); err != nil {
+		t.Errorf("new password: %v", err)
+	}
+	saved, err := store.LoadUser(u.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.MustChangePassword {
+		t.Error("the saved record still forces a change")
+	}
+}
+
+func TestSaveDebounce(t *testing.T) {
+	tests := []struct {
+		name     string
+		debounce time.Duration
+		state    saveState // after completing a quest
+	}{
+		{"debounced", time.Minute, saveDirty},
+		{"at once", 0, saveClean},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFor(t, &saveDebounce, tt.debounce)
+			u := newTestUser(t, "read")
+			m := press(t, newTestModel(u), " ")
+			if state := m.saver.state(); state != tt.state {
+				t.Fatalf("save state = %v, want %v", state, tt.state)
+			}
+			next, _ := m.Update(saveTickMsg{})
+			if state := next.(model).saver.state(); state != saveClean {
+				t.Errorf("save state after the tick = %v, want clean", state)
+			}
+			saved, err := store.LoadUser(u.Username)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !saved.CompletedToday(u.Habits[0].ID) {
+				t.Error("completion not written")
+			}
+		})
+	}
+}
+
+func TestSaveDebounceCoalesces(t *testing.T) {
+	setFor(t, &saveDebounce, time.Minute)
+	u := newTestUser(t, "read", "run", "write")
+	m := press(t, newTestModel(u), " ", "down", " ", "down", " ")
+	saved, err := store.LoadUser(u.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range u.Habits {
+		if saved.CompletedToday(h.ID) {
+			t.Errorf("%s written before the debounce ran out", h.Name)
+		}
+	}
+	if m.saver.schedule() {
+		t.Error("each change started its own save tick")
+	}
+
+	next, _ := m.Update(saveTickMsg{})
+	m = next.(model)
+	saved, err = store.LoadUser(u.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range u.Habits {
+		if !saved.CompletedToday(h.ID) {
+			t.Errorf("%s missing from the one write", h.Name)
+		}
+	}
+	if m.saver.state() != saveClean {
+		t.Errorf("save state after the tick = %v, want clean", m.saver.state())
+	}
+}
+
+func TestRegisterClosed(t *testing.T) {
+	setFor(t, &store.AllowRegister, false)
+	m := press(t, newLoginModel(t), "r")
+	if m.authState != authLogin || m.loginUsername != "r" {
+		t.Errorf("r with registration closed: state %v, username %q; want it typed into the login form", m.authState, m.loginUsername)
+	}
+	if strings.Contains(m.View(), "register") {
+		t.Error("the login form still offers registration")
+	}
+}
+
+func TestUncheckLevelConfirm(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		done   bool
+		level  int
+	}{
+		{"yes", "y", false, 2},
+		{"no", "n", true, 3},
+		{"anything else", "esc", true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newTestUser(t, "read")
+			u.EXP, u.Level = 2*store.EXPPerLevel-5, 2
+			if _, leveledUp, err := u.ToggleToday(u.Habits[0].ID); err != nil || !leveledUp {
+				t.Fatalf("setup: leveled up %v, %v", leveledUp, err)
+			}
+			m := press(t, newTestModel(u), " ")
+			if m.lastToast != uncheckLevelPrompt || !u.CompletedToday(u.Habits[0].ID) {
+				t.Fatalf("uncheck didn't ask first: toast %q", m.lastToast)
+			}
+			m = press(t, m, tt.answer)
+			if u.CompletedToday(u.Habits[0].ID) != tt.done || u.Level != tt.level {
+				t.Errorf("after %q: done %v level %d, want %v level %d", tt.answer, u.CompletedToday(u.Habits[0].ID), u.Level, tt.done, tt.level)
+			}
+			if m.confirmUncheck != "" {
+				t.Error("still asking after an answer")
+			}
+		})
+	}
+}
+
+func TestResetShiftWarning(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.Local)
+	dates := layoutFor(store.DateFormatISO)
+	tests := []struct {
+		name  string
+		today string
+		hour  int
+		want  string // substring; "" for no warning
+	}{
+		{"same day", "2026-10-18", 6, ""},
+		{"back to yesterday", "2026-10-18", 11, "2026-10-17 (yesterday)"},
+		{"on to tomorrow", "2026-10-17", 3, "2026-10-18 (tomorrow)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resetShiftWarning(tt.today, tt.hour, now, dates)
+			if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("resetShiftWarning(%s, %d) = %q, want %q", tt.today, tt.hour, got, tt.want)
+			}
+		})
+	}
+}
+
+// expPreview renders the EXP preview line for u's first quest
+func expPreview(t *testing.T, u *store.UserData) string {
+	t.Helper()
+	m := newTestModel(u)
+	u.SetSpotlight("") // logging in spotlights the first quest
+	s := lipgloss.NewStyle()
+	return strings.TrimSpace(m.expPreviewLine(u.Habits[0], s, s))
+}
+
+func TestEXPPreviewLine(t *testing.T) {
+	u := newTestUser(t, "read", "run")
+	if got, want := expPreview(t, u), "→ +10 EXP"; got != want {
+		t.Errorf("expPreviewLine = %q, want %q", got, want)
+	}
+}
+
+func TestEXPPreviewLineLevelUp(t *testing.T) {
+	u := newTestUser(t, "read", "run")
+	u.EXP, u.Level = 195, 2
+	if got, want := expPreview(t, u), "→ +10 EXP (would reach Lv3!)"; got != want {
+		t.Errorf("expPreviewLine = %q, want %q", got, want)
+	}
+}
+
+func TestEXPPreviewLineDone(t *testing.T) {
+	u := newTestUser(t, "read", "run")
+	if _, _, err := u.ToggleToday(u.Habits[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := expPreview(t, u), "✓ already done today"; got != want {
+		t.Errorf("expPreviewLine = %q, want %q", got, want)
+	}
+}
+
+func TestEXPPreviewLineChained(t *testing.T) {
+	u := newTestUser(t, "read", "run")
+	if err := u.EditHabit(u.Habits[0].ID, store.Habit{Name: "read", DependsOn: u.Habits[1].ID}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := expPreview(t, u), "→ +10 EXP once unlocked"; got != want {
+		t.Errorf("expPreviewLine = %q, want %q", got, want)
+	}
+}
+
+func TestEXPPreviewLineSpotlight(t *testing.T) {
+	u := newTestUser(t, "read", "run")
+	m := newTestModel(u)
+	u.SetSpotlight(u.Habits[0].ID)
+	s := lipgloss.NewStyle()
+	if got, want := strings.TrimSpace(m.expPreviewLine(u.Habits[0], s, s)), "→ +20 EXP"; got != want {
+		t.Errorf("expPreviewLine = %q, want %q", got, want)
+	}
+}
+
+func TestTruncateToWidth(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"read", 10, "read"},
+		{"read a book", 6, "read …"},
+		{"読書をする", 5, "読書…"},
+		{"🔥🔥🔥", 4, "🔥…"},
+	}
+	for _, tt := range tests {
+		if got := truncateToWidth(tt.s, tt.width); got != tt.want {
+			t.Errorf("truncateToWidth(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestSaveNow(t *testing.T) {
+	setFor(t, &saveDebounce, time.Minute)
+	u := newTestUser(t, "read")
+	m := press(t, newTestModel(u), " ", "ctrl+s")
+	if state := m.saver.state(); state != saveClean || m.lastToast != "Progress saved." {
+		t.Errorf("after ctrl+s: state %v, toast %q", state, m.lastToast)
+	}
+}
+
+func TestSaveNowFails(t *testing.T) {
+	setFor(t, &saveDebounce, time.Minute)
+	u := newTestUser(t, "read")
+	// A directory in place of the record makes the write fail
+	paths, _ := filepath.Glob(filepath.Join(store.DataDir, "*", u.Username+".json"))
+	if len(paths) != 1 {
+		t.Fatalf("record files %v", paths)
+	}
+	if err := os.Remove(paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(paths[0], "blocker"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m := press(t, newTestModel(u), " ", "ctrl+s")
+	if state := m.saver.state(); state != saveFailed || m.lastToast != saveFailedToast {
+		t.Errorf("after a failed ctrl+s: state %v, toast %q", state, m.lastToast)
+	}
+	s := lipgloss.NewStyle()
+	if got := saveIndicator(m.saver.state(), s, s); !strings.Contains(got, "retry") {
+		t.Errorf("indicator = %q, want a retry hint", got)
+	}
+
+	if err := os.RemoveAll(paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	m = press(t, m, "ctrl+s")
+	if state := m.saver.state(); state != saveClean {
+		t.Errorf("after retrying: state %v, toast %q", state, m.lastToast)
+	}
+}
+
+func TestProgressColor(t *testing.T) {
+	tests := []struct {
+		done, total int
+		want        lipgloss.Color
+		ok          bool
+	}{
+		{0, 0, "", false},
+		{0, 4, "196", true},
+		{1, 4, "196", true},
+		{2, 4, "220", true},
+		{3, 4, "220", true},
+		{4, 4, "40", true},
+		{1, 1, "40", true},
+	}
+	for _, tt := range tests {
+		if got, ok := progressColor(tt.done, tt.total); got != tt.want || ok != tt.ok {
+			t.Errorf("progressColor(%d, %d) = %q, %v; want %q, %v", tt.done, tt.total, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+)
+
+// noPTYMessage is sent to clients that connect without a terminal, such as
+// `ssh host command`, instead of starting the TUI
+const noPTYMessage = "System is an interactive app and needs a terminal.\nConnect with: ssh -t <host> -p <port>"
+
+// requirePTY ends sessions that didn't request a PTY with a hint and exit
+// status 1, since the TUI can't run without one
+func requirePTY() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			if _, _, ok := sess.Pty(); !ok {
+				log.Printf("rejected session without a pty from %s (user %q, command %q)", sess.RemoteAddr(), sess.User(), sess.RawCommand())
+				wish.Fatalln(sess, noPTYMessage)
+				return
+			}
+			next(sess)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// leaderboardFile is where the leaderboard is published as JSON for a web
+// page; empty (the default) publishes nothing. Set by SYSTEM_LEADERBOARD_FILE.
+var leaderboardFile = ""
+
+// leaderboardEvery is how often the published leaderboard is rewritten. Set
+// by SYSTEM_LEADERBOARD_EVERY.
+var leaderboardEvery = 5 * time.Minute
+
+// leaderboardFileTop is how many hunters the published leaderboard lists
+const leaderboardFileTop = 100
+
+// publishLeaderboard writes leaderboardFile now and then every
+// leaderboardEvery for as long as the server runs. Failures are logged and
+// retried on the next tick.
+func publishLeaderboard() {
+	if leaderboardFile == "" {
+		return
+	}
+	write := func() {
+		if err := store.WriteLeaderboard(leaderboardFile, leaderboardFileTop); err != nil {
+			log.Printf("publishing leaderboard to %s: %v", leaderboardFile, err)
+		}
+	}
+	go func() {
+		write()
+		for range time.Tick(leaderboardEvery) {
+			write()
+		}
+	}()
+}
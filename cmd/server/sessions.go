@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// userSessions counts each hunter's logged-in SSH sessions, so a second
+// login can mention that another session has the same progress open
+var userSessions = &sessionTracker{open: map[string]int{}}
+
+// sessionTracker is a per-username count of open sessions
+type sessionTracker struct {
+	mu   sync.Mutex
+	open map[string]int
+}
+
+// join records a session for username and returns how many others it has open
+func (t *sessionTracker) join(username string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.open[username]++
+	return t.open[username] - 1
+}
+
+// leave drops one session for username; the entry goes once none are left
+func (t *sessionTracker) leave(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.open[username] <= 1 {
+		delete(t.open, username)
+		return
+	}
+	t.open[username]--
+}
+
+// count returns how many sessions username has open
+func (t *sessionTracker) count(username string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.open[username]
+}
+
+// otherSessionToast notes that another session has the same account open
+const otherSessionToast = "You're also connected in another session; changes in one show up in the other."
+
+// trackSession counts this session against the logged-in hunter until the
+// client disconnects, and reports how many other sessions they have open.
+// The demo account is shared by design and isn't tracked.
+func (m *model) trackSession() int {
+	name := m.userData.Username
+	if store.IsDemo(name) || m.ctx == nil {
+		return 0
+	}
+	others := userSessions.join(name)
+	ctx := m.ctx
+	go func() {
+		<-ctx.Done()
+		userSessions.leave(name)
+	}()
+	return others
+}
+
+// shareUser swaps the logged-in hunter's record for the one their other
+// sessions and the JSON API share (see store.Share), so nobody's save writes
+// over another's changes. It's left locked for the rest of the Update that
+// logged in. The share is released once the client disconnects and the
+// session's last changes are written.
+func (m *model) shareUser() {
+	if m.ctx == nil {
+		return
+	}
+	live := store.Share(m.userData)
+	live.Lock()
+	m.live, m.userData = live, live.User
+	ctx, saver := m.ctx, m.saver
+	go func() {
+		<-ctx.Done()
+		live.Lock()
+		_ = saver.flush()
+		live.Unlock()
+		live.Release()
+	}()
+}
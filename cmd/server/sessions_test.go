@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+func TestSessionTracker(t *testing.T) {
+	tr := &sessionTracker{open: map[string]int{}}
+	steps := []struct {
+		op     string // "join" or "leave"
+		user   string
+		others int // for a join, the sessions it reports; for a leave, the count after
+	}{
+		{"join", "alice", 0},
+		{"join", "alice", 1},
+		{"join", "bob", 0},
+		{"leave", "alice", 1},
+		{"leave", "alice", 0},
+		{"leave", "alice", 0}, // one too many leaves never goes negative
+		{"join", "alice", 0},
+	}
+	for i, s := range steps {
+		var got int
+		if s.op == "join" {
+			got = tr.join(s.user)
+		} else {
+			tr.leave(s.user)
+			got = tr.count(s.user)
+		}
+		if got != s.others {
+			t.Errorf("step %d: %s %s = %d, want %d", i, s.op, s.user, got, s.others)
+		}
+	}
+}
+
+func TestTrackSession(t *testing.T) {
+	setFor(t, &store.DemoUser, "demo")
+	tests := []struct {
+		name    string
+		user    string
+		tracked bool
+	}{
+		{"hunter", "trackee", true},
+		{"demo", "demo", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			m := model{ctx: ctx, userData: &store.UserData{Username: tt.user}}
+			m.trackSession()
+			if got := userSessions.count(tt.user) == 1; got != tt.tracked {
+				t.Fatalf("tracked = %v, want %v", got, tt.tracked)
+			}
+			cancel()
+			deadline := time.Now().Add(time.Second)
+			for userSessions.count(tt.user) != 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("session still counted after disconnecting")
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestSessionsShareRecord(t *testing.T) {
+	u := newTestUser(t, "read")
+	connect := func() (model, context.CancelFunc) {
+		loaded, err := store.LoadUser(u.Username)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		m := newLoginModel(t)
+		m.ctx = ctx
+		m.loggedIn(loaded)
+		m.live.Unlock() // as initialModel does after a login in the handshake
+		return m, cancel
+	}
+	a, disconnectA := connect()
+	b, disconnectB := connect()
+	if a.userData != b.userData {
+		t.Fatal("two sessions for the same hunter hold different records")
+	}
+	press(t, a, " ")
+	if !b.userData.CompletedToday(u.Habits[0].ID) {
+		t.Error("a completion in one session doesn't show in the other")
+	}
+
+	// The completion is still waiting on the debounce; disconnecting writes it
+	disconnectA()
+	disconnectB()
+	deadline := time.Now().Add(time.Second)
+	for {
+		saved, err := store.LoadUser(u.Username)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved.CompletedToday(u.Habits[0].ID) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pending completion not saved after disconnecting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
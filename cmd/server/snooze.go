@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// snoozeFor is how long [z] quiets a due reminder. Set by SYSTEM_SNOOZE.
+var snoozeFor = 30 * time.Minute
+
+// snoozeLeft returns how much of h's snooze remains; 0 if it isn't snoozed
+func (m model) snoozeLeft(h store.Habit, now time.Time) time.Duration {
+	return max(m.snoozed[h.ID].Sub(now), 0)
+}
+
+// snooze quiets h's due reminder for snoozeFor. Snoozing a snoozed quest
+// restarts the snooze.
+func (m *model) snooze(h store.Habit, now time.Time) {
+	if !m.userData.ReminderDue(h, now) {
+		m.lastToast = "Only a due reminder can be snoozed."
+		return
+	}
+	if m.snoozed == nil {
+		m.snoozed = make(map[string]time.Time)
+	}
+	m.snoozed[h.ID] = now.Add(snoozeFor)
+	m.lastToast = fmt.Sprintf("Snoozed '%s' for %s.", truncateQuestName(h.Name, maxQuestNameRunes), shortDuration(snoozeFor))
+}
+
+// expireSnoozes drops snoozes that have run out or whose quest is done, so
+// a quest unchecked later nags again right away
+func (m *model) expireSnoozes(now time.Time) {
+	for id, until := range m.snoozed {
+		if !now.Before(until) || m.userData == nil || m.userData.CompletedToday(id) {
+			delete(m.snoozed, id)
+		}
+	}
+}
+
+// shortDuration formats d as minutes, or hours and minutes, e.g. "1h30m"
+func shortDuration(d time.Duration) string {
+	mins := int(d.Round(time.Minute).Minutes())
+	switch {
+	case mins < 60:
+		return fmt.Sprintf("%dm", max(mins, 1))
+	case mins%60 == 0:
+		return fmt.Sprintf("%dh", mins/60)
+	}
+	return fmt.Sprintf("%dh%dm", mins/60, mins%60)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShortDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{10 * time.Second, "1m"},
+		{30 * time.Minute, "30m"},
+		{59*time.Minute + 40*time.Second, "1h"},
+		{2 * time.Hour, "2h"},
+		{90 * time.Minute, "1h30m"},
+	}
+	for _, tt := range tests {
+		if got := shortDuration(tt.d); got != tt.want {
+			t.Errorf("shortDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestSnooze(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		reminder bool
+		done     bool          // the quest is completed after snoozing
+		later    time.Duration // when expireSnoozes runs
+		left     time.Duration // snooze left after that
+	}{
+		{name: "no reminder", later: 0, left: 0},
+		{name: "due", reminder: true, later: time.Minute, left: snoozeFor - time.Minute},
+		{name: "run out", reminder: true, later: snoozeFor, left: 0},
+		{name: "completed", reminder: true, done: true, later: time.Minute, left: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newTestUser(t, "stretch")
+			if tt.reminder {
+				hour := 0 // due all day with a midnight reset
+				u.Habits[0].ReminderHour = &hour
+			}
+			m := newTestModel(u)
+			h := u.Habits[0]
+			m.snooze(h, now)
+			if snoozed := m.snoozeLeft(h, now) > 0; snoozed != tt.reminder {
+				t.Fatalf("snoozed = %v (toast %q), want %v", snoozed, m.lastToast, tt.reminder)
+			}
+			if tt.done {
+				if _, _, err := u.ToggleToday(h.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			at := now.Add(tt.later)
+			m.expireSnoozes(at)
+			if left := m.snoozeLeft(h, at); left != tt.left {
+				t.Errorf("snooze left = %s, want %s", left, tt.left)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+
+	"github.com/charmbracelet/ssh"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// sshAuth asks for the password during the SSH handshake (keyboard-
+// interactive auth), so `ssh alice@host` lands straight in the quest log.
+// Leaving the prompt empty falls back to the in-app form, which is also how
+// new hunters register. Set by SYSTEM_SSH_AUTH.
+var sshAuth = false
+
+// sshAuthPrompt is the one question keyboardInteractive asks
+const sshAuthPrompt = "Password (empty to log in or register in the app): "
+
+// sshUserKey holds the user keyboardInteractive logged in on the
+// connection's context
+type sshUserKey struct{}
+
+// keyboardInteractive checks the SSH username and the password the client
+// prompts for with AuthUser, so failures count toward the lockout and don't
+// say which part was wrong unless RevealLoginErrors is set. A failure is
+// shown to the client and refused; most clients then ask again.
+func keyboardInteractive(ctx ssh.Context, challenge gossh.KeyboardInteractiveChallenge) bool {
+	answers, err := challenge("", "", []string{sshAuthPrompt}, []bool{false})
+	if err != nil || len(answers) != 1 {
+		return false
+	}
+	if answers[0] == "" {
+		return true
+	}
+	u, err := store.AuthUser(ctx.User(), answers[0])
+	if err != nil {
+		_, _ = challenge("", authErrorText(err), nil, nil)
+		return false
+	}
+	ctx.SetValue(sshUserKey{}, u)
+	return true
+}
+
+// sshUser returns the user the SSH handshake logged in, if any
+func sshUser(ctx context.Context) (*store.UserData, bool) {
+	u, ok := ctx.Value(sshUserKey{}).(*store.UserData)
+	return u, ok
+}
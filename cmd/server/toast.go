@@ -0,0 +1,54 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// toastFor is how long a toast stays up without a key press; 0 keeps it
+// until the next key. Set by SYSTEM_TOAST.
+var toastFor = 10 * time.Second
+
+// toastTickMsg clears the toast once it has expired. gen ties it to one
+// toast, so a tick from a toast already replaced does nothing.
+type toastTickMsg struct{ gen int }
+
+func tickToast(d time.Duration, gen int) tea.Cmd {
+	return tea.Tick(d, func(time.Time) tea.Msg { return toastTickMsg{gen: gen} })
+}
+
+// noteToast (re)starts the toast timer after an update: when the toast
+// changed, or a key press left one up. A newer toast simply replaces the
+// older one with the full toastFor ahead of it.
+func (m *model) noteToast(before string, msg tea.Msg, now time.Time) tea.Cmd {
+	_, key := msg.(tea.KeyMsg)
+	if toastFor <= 0 || m.lastToast == "" || (m.lastToast == before && !key) {
+		return nil
+	}
+	m.toastGen++
+	m.toastExpires = now.Add(toastFor)
+	return tickToast(toastFor, m.toastGen)
+}
+
+// toastHeld reports whether the toast is waiting on something and mustn't
+// expire yet: the y/n prompt of a costly uncheck, or a level-up's stats
+func (m model) toastHeld() bool {
+	return m.confirmUncheck != "" || m.pendingLevelUp
+}
+
+// onToastTick clears an expired toast. A held one is checked again after
+// another toastFor; the toast that ends the hold restarts the timer anyway.
+func (m model) onToastTick(tick toastTickMsg, now time.Time) (model, tea.Cmd) {
+	if tick.gen != m.toastGen || m.lastToast == "" {
+		return m, nil
+	}
+	if now.Before(m.toastExpires) {
+		return m, tickToast(m.toastExpires.Sub(now), m.toastGen)
+	}
+	if m.toastHeld() {
+		return m, tickToast(toastFor, m.toastGen)
+	}
+	m.lastToast = ""
+	return m, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestNoteToast(t *testing.T) {
+	tests := []struct {
+		name     string
+		toastFor time.Duration
+		before   string
+		toast    string
+		msg      tea.Msg
+		timer    bool
+	}{
+		{"new toast", 10 * time.Second, "", "Saved.", tea.WindowSizeMsg{}, true},
+		{"replaced toast", 10 * time.Second, "Saved.", "Loaded.", tea.WindowSizeMsg{}, true},
+		{"key leaves the toast up", 10 * time.Second, "Saved.", "Saved.", keyMsg("j"), true},
+		{"unchanged toast", 10 * time.Second, "Saved.", "Saved.", tea.WindowSizeMsg{}, false},
+		{"no toast", 10 * time.Second, "", "", keyMsg("j"), false},
+		{"until a key", 0, "", "Saved.", tea.WindowSizeMsg{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFor(t, &toastFor, tt.toastFor)
+			now := time.Now()
+			m := model{lastToast: tt.toast}
+			cmd := m.noteToast(tt.before, tt.msg, now)
+			if (cmd != nil) != tt.timer {
+				t.Fatalf("timer started = %v, want %v", cmd != nil, tt.timer)
+			}
+			if tt.timer && (m.toastGen != 1 || !m.toastExpires.Equal(now.Add(toastFor))) {
+				t.Errorf("gen %d expires %s", m.toastGen, m.toastExpires)
+			}
+		})
+	}
+}
+
+func TestOnToastTick(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		m       model
+		tick    toastTickMsg
+		cleared bool
+		again   bool // another tick is scheduled
+	}{
+		{"expired", model{lastToast: "Saved.", toastGen: 2, toastExpires: now}, toastTickMsg{gen: 2}, true, false},
+		{"replaced since", model{lastToast: "Saved.", toastGen: 3, toastExpires: now}, toastTickMsg{gen: 2}, false, false},
+		{"extended by a key", model{lastToast: "Saved.", toastGen: 2, toastExpires: now.Add(time.Second)}, toastTickMsg{gen: 2}, false, true},
+		{"uncheck prompt", model{lastToast: uncheckLevelPrompt, confirmUncheck: "h_1", toastGen: 2, toastExpires: now}, toastTickMsg{gen: 2}, false, true},
+		{"level-up pending", model{lastToast: "LEVEL UP!", pendingLevelUp: true, toastGen: 2, toastExpires: now}, toastTickMsg{gen: 2}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, cmd := tt.m.onToastTick(tt.tick, now)
+			if cleared := next.lastToast == ""; cleared != tt.cleared {
+				t.Errorf("toast %q, want cleared %v", next.lastToast, tt.cleared)
+			}
+			if (cmd != nil) != tt.again {
+				t.Errorf("another tick = %v, want %v", cmd != nil, tt.again)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// webhookEvents are the audit events a webhook can be sent. Anything else,
+// logins, tokens and passwords included, never leaves the server.
+var webhookEvents = []string{store.AuditLevelUp, store.AuditMilestone, store.AuditDayComplete}
+
+// webhookTargets are the URLs sent events, from the config file's webhooks
+// section; none (the default) sends nothing
+var webhookTargets []WebhookTarget
+
+// webhookTimeout bounds each delivery attempt. Set by SYSTEM_WEBHOOK_TIMEOUT.
+var webhookTimeout = 5 * time.Second
+
+// webhookRetries is how many more times a failed delivery is tried, the
+// first after webhookRetryDelay and each later one after twice the last
+const webhookRetries = 2
+
+var webhookRetryDelay = time.Second
+
+// webhookQueue is how many events a webhook may fall behind by before new
+// ones are dropped
+const webhookQueue = 64
+
+// webhookPayload is the JSON body POSTed to a webhook. It's built field by
+// field from the audit event rather than passing the event on, so nothing
+// else the event carries (quest names, details) is sent.
+type webhookPayload struct {
+	Event    string    `json:"event"`
+	Username string    `json:"username"`
+	Time     time.Time `json:"time"`
+	Level    int       `json:"level"`
+	EXP      int       `json:"exp"`
+	Streak   int       `json:"streak,omitempty"`
+	Day      string    `json:"day,omitempty"`
+}
+
+// webhook is one configured URL and the events it's sent, with its own
+// queue and sender so a slow endpoint only delays itself. Logs name it by
+// host alone, as the rest of a URL often holds a secret.
+type webhook struct {
+	url    string
+	host   string
+	events []string
+	queue  chan webhookPayload
+}
+
+// startWebhooks starts a sender per webhookTargets entry and hooks them to
+// the store's audit events. Delivery is best-effort: a full queue drops the
+// event and failures are logged after the last retry.
+func startWebhooks() {
+	if len(webhookTargets) == 0 {
+		return
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	var hooks []*webhook
+	for _, t := range webhookTargets {
+		events := t.Events
+		if len(events) == 0 {
+			events = webhookEvents
+		}
+		h := &webhook{url: t.URL, events: events, queue: make(chan webhookPayload, webhookQueue)}
+		if u, err := url.Parse(t.URL); err == nil {
+			h.host = u.Host
+		}
+		go h.run(client)
+		hooks = append(hooks, h)
+	}
+	store.OnAudit = func(username string, ev store.AuditEvent) {
+		if !slices.Contains(webhookEvents, ev.Type) {
+			return
+		}
+		p := webhookPayload{
+			Event:    ev.Type,
+			Username: username,
+			Time:     ev.Time,
+			Level:    ev.Level,
+			EXP:      ev.EXP,
+			Streak:   ev.Streak,
+			Day:      ev.Day,
+		}
+		for _, h := range hooks {
+			if !slices.Contains(h.events, ev.Type) {
+				continue
+			}
+			select {
+			case h.queue <- p:
+			default:
+				log.Printf("webhook %s: queue full, dropped %s for %s", h.host, p.Event, username)
+			}
+		}
+	}
+}
+
+func (h *webhook) run(client *http.Client) {
+	for p := range h.queue {
+		body, err := json.Marshal(p)
+		if err != nil {
+			log.Printf("webhook %s: %v", h.host, err)
+			continue
+		}
+		delay := webhookRetryDelay
+		for attempt := 0; ; attempt++ {
+			err = h.post(client, body)
+			if err == nil || attempt == webhookRetries {
+				break
+			}
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err != nil {
+			log.Printf("webhook %s: %s for %s not delivered: %v", h.host, p.Event, p.Username, err)
+		}
+	}
+}
+
+// post sends one delivery attempt; any non-2xx answer is a failure. The
+// error leaves out the URL.
+func (h *webhook) post(client *http.Client, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("bad URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "system-webhook")
+	resp, err := client.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// delivery is one request a test webhook server received
+type delivery struct {
+	path string
+	body map[string]any
+}
+
+func TestWebhooks(t *testing.T) {
+	got := make(chan delivery, 16)
+	var flaky atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("webhook body %q: %v", data, err)
+		}
+		got <- delivery{path: r.URL.Path, body: body}
+		if r.URL.Path == "/flaky" && flaky.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	setFor(t, &webhookRetryDelay, time.Millisecond)
+	setFor(t, &store.OnAudit, nil)
+	setFor(t, &webhookTargets, []WebhookTarget{
+		{URL: srv.URL + "/all"},
+		{URL: srv.URL + "/levels", Events: []string{store.AuditLevelUp}},
+		{URL: srv.URL + "/flaky", Events: []string{store.AuditDayComplete}},
+	})
+	startWebhooks()
+
+	store.Audit("hunter", store.AuditEvent{Type: store.AuditLogin})
+	store.Audit("hunter", store.AuditEvent{Type: store.AuditLevelUp, Habit: "see the doctor", Level: 8, EXP: 700, Detail: "private"})
+	store.Audit("hunter", store.AuditEvent{Type: store.AuditDayComplete, Day: "2026-10-18", Streak: 4})
+
+	// /all gets both, /levels the level-up, /flaky the day twice (one retry)
+	byPath := map[string][]map[string]any{}
+	for range 5 {
+		select {
+		case d := <-got:
+			byPath[d.path] = append(byPath[d.path], d.body)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for webhooks; got %v", byPath)
+		}
+	}
+	select {
+	case d := <-got:
+		t.Errorf("unexpected delivery to %s: %v", d.path, d.body)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	events := func(path string) []string {
+		var out []string
+		for _, b := range byPath[path] {
+			out = append(out, b["event"].(string))
+		}
+		return out
+	}
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/all", []string{store.AuditLevelUp, store.AuditDayComplete}},
+		{"/levels", []string{store.AuditLevelUp}},
+		{"/flaky", []string{store.AuditDayComplete, store.AuditDayComplete}},
+	}
+	for _, tt := range tests {
+		if got := events(tt.path); !slices.Equal(got, tt.want) {
+			t.Errorf("%s got %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	sent := []string{"event", "username", "time", "level", "exp", "streak", "day"}
+	for path, bodies := range byPath {
+		for _, b := range bodies {
+			for key := range b {
+				if !slices.Contains(sent, key) {
+					t.Errorf("%s payload has %q: %v", path, key, b)
+				}
+			}
+		}
+	}
+	if lv := byPath["/levels"]; len(lv) == 1 && (lv[0]["username"] != "hunter" || lv[0]["level"] != 8.0) {
+		t.Errorf("level-up payload = %v", lv[0])
+	}
+}
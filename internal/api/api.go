@@ -0,0 +1,462 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abhigyan-mohanta/system/internal/gemini"
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+const (
+	defaultLeaderboardSize = 10
+	maxHabitNameRunes      = 64
+)
+
+// Profile is the JSON view of a user, without credentials or raw history
+type Profile struct {
+	Username       string `json:"username"`
+	Level          int    `json:"level"`
+	Prestige       int    `json:"prestige"`
+	Title          string `json:"title,omitempty"`
+	EXP            int    `json:"exp"`
+	EXPInLevel     int    `json:"exp_in_level"`
+	EXPForNext     int    `json:"exp_for_next_level"`
+	LifetimeEXP    int    `json:"lifetime_exp"` // This season's EXP plus every past season's
+	STR            int    `json:"str"`
+	VIT            int    `json:"vit"`
+	AGI            int    `json:"agi"`
+	INT            int    `json:"int"`
+	CurrentStreak  int    `json:"current_streak"`
+	LongestStreak  int    `json:"longest_streak"`
+	Shields        int    `json:"shields"`
+	DaysSinceMiss  int    `json:"days_since_last_miss"`
+	BestComeback   int    `json:"best_comeback"`
+	DayResetHour   int    `json:"day_reset_hour"`
+	SecondsToReset int    `json:"seconds_to_reset"`
+}
+
+// HabitStatus is a habit plus whether it is done for the current day
+type HabitStatus struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Icon           string `json:"icon"`
+	Color          string `json:"color,omitempty"`
+	ReminderHour   *int   `json:"reminder_hour,omitempty"`
+	CompletedToday bool   `json:"completed_today"`
+	LockedIn       bool   `json:"locked_in,omitempty"` // past the uncheck grace period; can't be unchecked
+
+	DependsOn   string `json:"depends_on,omitempty"`   // ID of the quest that has to be done first each day
+	ChainLocked bool   `json:"chain_locked,omitempty"` // waiting on DependsOn; can't be completed yet
+
+	WeeklyTarget int `json:"weekly_target,omitempty"` // completions wanted per week; absent for a daily quest
+	WeeklyDone   int `json:"weekly_done,omitempty"`   // completions so far this week, for a quest with a weekly target
+
+	Spotlight bool `json:"spotlight,omitempty"` // this week's spotlight; completing it pays store.SpotlightBonus extra
+}
+
+// ToggleResult reports the outcome of toggling a habit for today
+type ToggleResult struct {
+	Habit       HabitStatus          `json:"habit"`
+	GainedEXP   bool                 `json:"gained_exp"`
+	LeveledUp   bool                 `json:"leveled_up"`
+	Stats       *gemini.StatResponse `json:"stats,omitempty"`
+	ShieldsUsed int                  `json:"shields_used,omitempty"` // Streak shields that covered missed days
+	Milestones  []store.Milestone    `json:"milestones,omitempty"`   // Streak milestones this toggle claimed
+	Profile     Profile              `json:"profile"`
+}
+
+// BatchResult reports the outcome of setting several habits at once
+type BatchResult struct {
+	EXPDelta    int                  `json:"exp_delta"`
+	LevelDelta  int                  `json:"level_delta"`
+	Stats       *gemini.StatResponse `json:"stats,omitempty"`
+	ShieldsUsed int                  `json:"shields_used,omitempty"`
+	Milestones  []store.Milestone    `json:"milestones,omitempty"`
+	Habits      []HabitStatus        `json:"habits"`
+	Profile     Profile              `json:"profile"`
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// NewHandler returns the HTTP handler serving the JSON API.
+// Every endpoint requires either an API token (Authorization: Bearer,
+// created in the SSH app's settings) or HTTP basic auth with the same
+// username and password used in the SSH app.
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/profile", withUser(handleProfile))
+	mux.HandleFunc("GET /api/habits", withUser(handleListHabits))
+	mux.HandleFunc("POST /api/habits", withUser(handleAddHabit))
+	mux.HandleFunc("POST /api/habits/{id}/toggle", withUser(handleToggle))
+	mux.HandleFunc("POST /api/habits/toggle", withUser(handleToggleMany))
+	mux.HandleFunc("GET /api/report", withUser(handleReport))
+	mux.HandleFunc("GET /api/leaderboard", withUser(handleLeaderboard))
+	return mux
+}
+
+// userHandler serves a request for an authenticated user. It's called with
+// live locked; see allocateStats for the one place it's let go.
+type userHandler func(w http.ResponseWriter, r *http.Request, live *store.Live)
+
+// withUser authenticates the request and passes the user's shared record to h
+func withUser(h userHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			u, err := store.VerifyAPIToken(token)
+			if err != nil {
+				if !errors.Is(err, store.ErrInvalidToken) {
+					log.Printf("api: token auth: %v", err)
+					writeError(w, http.StatusInternalServerError, "could not load user")
+					return
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="system"`)
+				writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			serveUser(w, r, u, h)
+			return
+		}
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="system"`)
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		u, err := store.AuthUser(username, password)
+		if errors.Is(err, store.ErrAccountLocked) {
+			secs := int((store.LockoutLeft(username) + time.Second - 1) / time.Second)
+			w.Header().Set("Retry-After", strconv.Itoa(max(secs, 1)))
+			writeError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		if err != nil {
+			if !errors.Is(err, store.ErrInvalidCredentials) && !errors.Is(err, store.ErrUsernameRequired) {
+				log.Printf("api: auth %s: %v", username, err)
+				writeError(w, http.StatusInternalServerError, "could not load user")
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="system"`)
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		serveUser(w, r, u, h)
+	}
+}
+
+// serveUser runs h for an authenticated user, unless an admin reset their
+// password; that locks out tokens too until they log in over SSH. h gets the
+// record the user's SSH sessions and other requests share, so a change made
+// here isn't written over by their next save, or theirs by this one's.
+func serveUser(w http.ResponseWriter, r *http.Request, u *store.UserData, h userHandler) {
+	live := store.Share(u)
+	defer live.Release()
+	live.Lock()
+	defer live.Unlock()
+	u = live.User
+	if u.MustChangePassword {
+		writeError(w, http.StatusForbidden, "password reset: log in over SSH to choose a new password")
+		return
+	}
+	season := u.CheckSeason() != nil
+	if _, spotlight := u.CheckSpotlight(); season || spotlight {
+		if err := store.SaveUser(u); err != nil {
+			writeError(w, http.StatusInternalServerError, "could not save")
+			return
+		}
+	}
+	h(w, r, live)
+}
+
+// bearerToken returns the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func handleProfile(w http.ResponseWriter, r *http.Request, live *store.Live) {
+	u := live.User
+	writeJSON(w, http.StatusOK, profileOf(u))
+}
+
+func handleListHabits(w http.ResponseWriter, r *http.Request, live *store.Live) {
+	u := live.User
+	habits := make([]HabitStatus, 0, len(u.Habits))
+	for _, h := range u.Habits {
+		habits = append(habits, habitStatusOf(u, h))
+	}
+	writeJSON(w, http.StatusOK, habits)
+}
+
+func handleAddHabit(w http.ResponseWriter, r *http.Request, live *store.Live) {
+	u := live.User
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name required")
+		return
+	}
+	if len([]rune(name)) > maxHabitNameRunes {
+		writeError(w, http.StatusBadRequest, "name too long")
+		return
+	}
+	if u.AtHabitLimit() {
+		writeError(w, http.StatusConflict, fmt.Sprintf("quest limit reached (%d)", store.MaxHabits))
+		return
+	}
+	h := u.AddHabit(name)
+	if err := store.SaveUser(u); err != nil {
+		writeError(w, http.StatusInternalServerError, "could not save")
+		return
+	}
+	writeJSON(w, http.StatusCreated, habitStatusOf(u, h))
+}
+
+func handleToggle(w http.ResponseWriter, r *http.Request, live *store.Live) {
+	u := live.User
+	h, ok := u.HabitByID(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown habit")
+		return
+	}
+	// ?done=true|false makes the call idempotent: only flip if the state differs
+	if want := r.URL.Query().Get("done"); want != "" {
+		done, err := strconv.ParseBool(want)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "done must be true or false")
+			return
+		}
+		if u.CompletedToday(h.ID) == done {
+			writeJSON(w, http.StatusOK, ToggleResult{Habit: habitStatusOf(u, h), Profile: profileOf(u)})
+			return
+		}
+	}
+	gainedEXP, leveledUp, err := u.ToggleToday(h.ID)
+	if errors.Is(err, store.ErrCompletionLocked) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if errors.Is(err, store.ErrQuestChained) {
+		writeError(w, http.StatusConflict, "complete the prerequisite quest first")
+		return
+	}
+	_, shielded := u.UpdateStreak()
+	before := u.Level
+	milestones := u.CheckStreakMilestones()
+	leveledUp = leveledUp || u.Level > before
+	res := ToggleResult{GainedEXP: gainedEXP, LeveledUp: leveledUp, ShieldsUsed: shielded, Milestones: milestones}
+	if leveledUp {
+		// Unlike the TUI there is no screen to update later, so allocate inline
+		res.Stats = allocateStats(r, live)
+	}
+	if err := store.SaveUser(u); err != nil {
+		writeError(w, http.StatusInternalServerError, "could not save")
+		return
+	}
+	res.Habit = habitStatusOf(u, h)
+	res.Profile = profileOf(u)
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleToggleMany sets today's state of several habits in one save. The body
+// is {"ids": [...], "done": true|false}; an empty ids list means every habit.
+// Habits already in that state, locked in, or waiting on a chain are skipped.
+func handleToggleMany(w http.ResponseWriter, r *http.Request, live *store.Live) {
+	u := live.User
+	var body struct {
+		IDs  []string `json:"ids"`
+		Done *bool    `json:"done"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if body.Done == nil {
+		writeError(w, http.StatusBadRequest, "done required")
+		return
+	}
+	ids := body.IDs
+	if len(ids) == 0 {
+		for _, h := range u.Habits {
+			ids = append(ids, h.ID)
+		}
+	}
+	for _, id := range ids {
+		if _, ok := u.HabitByID(id); !ok {
+			writeError(w, http.StatusNotFound, "unknown habit: "+id)
+			return
+		}
+	}
+	before := u.Level
+	expDelta, _ := u.ToggleMany(ids, *body.Done)
+	_, shielded := u.UpdateStreak()
+	milestones := u.CheckStreakMilestones()
+	res := BatchResult{EXPDelta: expDelta, ShieldsUsed: shielded, Milestones: milestones}
+	res.LevelDelta = u.Level - before
+	if res.LevelDelta > 0 {
+		res.Stats = allocateStats(r, live)
+	}
+	if err := store.SaveUser(u); err != nil {
+		writeError(w, http.StatusInternalServerError, "could not save")
+		return
+	}
+	res.Habits = make([]HabitStatus, 0, len(ids))
+	for _, id := range ids {
+		if h, ok := u.HabitByID(id); ok {
+			res.Habits = append(res.Habits, habitStatusOf(u, h))
+		}
+	}
+	res.Profile = profileOf(u)
+	writeJSON(w, http.StatusOK, res)
+}
+
+// allocateStats asks the AI for a level-up's stat points and applies them.
+// live is unlocked during the call, so a slow provider doesn't stall the
+// user's SSH sessions or other requests; it's locked again on return.
+func allocateStats(r *http.Request, live *store.Live) *gemini.StatResponse {
+	habits, level := live.User.RecentHabitNames(), live.User.Level
+	live.Unlock()
+	stats, _ := gemini.GetLevelUpStatsCtx(r.Context(), habits, level)
+	live.Lock()
+	live.User.ApplyLevelUpStats(stats.STR, stats.VIT, stats.AGI, stats.INT)
+	return &stats
+}
+
+// handleReport serves the weekly summary; ?week=-1 selects last week
+func handleReport(w http.ResponseWriter, r *http.Request, live *store.Live) {
+	u := live.User
+	offset := 0
+	if v := r.URL.Query().Get("week"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n > 0 {
+			writeError(w, http.StatusBadRequest, "week must be 0 or a negative offset")
+			return
+		}
+		offset = n
+	}
+	writeJSON(w, http.StatusOK, u.WeeklySummary(offset))
+}
+
+// leaderboardTTL is how long the leaderboard endpoint reuses a ranking
+// before loading every hunter's record again, so polling clients don't
+// cost a scan of the data directory each
+var leaderboardTTL = 30 * time.Second
+
+// rankings is the last full ranking the leaderboard endpoint loaded
+var rankings struct {
+	sync.Mutex
+	st store.Standings
+	at time.Time
+}
+
+// ranking returns every hunter in leaderboard order, reusing the last
+// ranking while it's younger than leaderboardTTL. Requests that find it
+// stale wait for the one loading it rather than each loading their own.
+func ranking() (store.Standings, error) {
+	rankings.Lock()
+	defer rankings.Unlock()
+	if !rankings.at.IsZero() && time.Since(rankings.at) < leaderboardTTL {
+		return rankings.st, nil
+	}
+	st, err := store.Leaderboard(0, "")
+	if err != nil {
+		return store.Standings{}, err
+	}
+	rankings.st, rankings.at = st, time.Now()
+	return st, nil
+}
+
+// handleLeaderboard serves the top hunters plus the caller's own rank
+func handleLeaderboard(w http.ResponseWriter, r *http.Request, live *store.Live) {
+	username := live.User.Username
+	// The ranking reads records from disk, not this one; don't hold up the
+	// hunter's sessions while it loads
+	live.Unlock()
+	full, err := ranking()
+	live.Lock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "could not load leaderboard")
+		return
+	}
+	st := full.Page(defaultLeaderboardSize, username)
+	if st.Top == nil {
+		st.Top = []store.LeaderboardEntry{}
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func profileOf(u *store.UserData) Profile {
+	sinceMiss, _ := u.DaysSinceLastMiss()
+	return Profile{
+		Username:       u.Username,
+		Level:          u.Level,
+		Prestige:       u.PrestigeCount,
+		Title:          u.Title,
+		EXP:            u.EXP,
+		EXPInLevel:     u.EXPInCurrentLevel(),
+		EXPForNext:     u.EXPForNextLevel(),
+		LifetimeEXP:    u.TotalEXP(),
+		STR:            u.STR,
+		VIT:            u.VIT,
+		AGI:            u.AGI,
+		INT:            u.INT,
+		CurrentStreak:  u.CurrentStreak,
+		LongestStreak:  u.LongestStreak,
+		Shields:        u.Shields,
+		DaysSinceMiss:  sinceMiss,
+		BestComeback:   u.BestComeback(),
+		DayResetHour:   u.DayResetHour,
+		SecondsToReset: int(u.TimeUntilReset().Seconds()),
+	}
+}
+
+func habitStatusOf(u *store.UserData, h store.Habit) HabitStatus {
+	today := u.TodayKey()
+	s := HabitStatus{
+		ID:             h.ID,
+		Name:           h.Name,
+		Icon:           h.Icon,
+		Color:          h.Color,
+		ReminderHour:   h.ReminderHour,
+		CompletedToday: u.CompletedOn(h.ID, today),
+		LockedIn:       u.LockedIn(h.ID, today),
+		DependsOn:      h.DependsOn,
+		ChainLocked:    u.ChainLocked(h.ID, today),
+	}
+	if h.WeeklyTarget > 0 {
+		s.WeeklyDone, s.WeeklyTarget = u.WeeklyProgress(h.ID)
+	}
+	if spot, ok := u.Spotlight(); ok && spot.ID == h.ID {
+		s.Spotlight = true
+	}
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorBody{Error: msg})
+}
@@ -0,0 +1,565 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/abhigyan-mohanta/system/internal/gemini"
+	"github.com/abhigyan-mohanta/system/internal/store"
+)
+
+// testPassword is every hunter's password, hashed at the lowest cost to keep
+// tests fast
+const testPassword = "Correct-horse-9"
+
+var testHash = func() string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		panic(err)
+	}
+	return string(hash)
+}()
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "api-test")
+	if err != nil {
+		panic(err)
+	}
+	store.DataDir = dir
+	gemini.Allocator = gemini.Local{} // level-ups never leave the machine
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+var testUsers int
+
+// newHunter saves a new hunter with testPassword and the given quests
+func newHunter(t *testing.T, habits ...string) *store.UserData {
+	t.Helper()
+	testUsers++
+	u := &store.UserData{
+		Username:         fmt.Sprintf("hunter%d", testUsers),
+		PasswordHash:     testHash,
+		Level:            store.DefaultLevel,
+		DailyCompletions: make(map[string]map[string]bool),
+		DayResetHour:     store.DefaultResetHour,
+		Keymap:           store.KeymapDefault,
+		Theme:            store.ThemeSystemBlue,
+		SortMode:         store.SortManual,
+		CompleteKey:      store.CompleteKeySpace,
+		CreatedAt:        time.Now(),
+	}
+	for _, name := range habits {
+		u.AddHabit(name)
+	}
+	if err := store.SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// setFor sets *p to v until the test ends
+func setFor[T any](t *testing.T, p *T, v T) {
+	t.Helper()
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+// auth sets how a request authenticates
+type auth func(r *http.Request)
+
+func basic(username, password string) auth {
+	return func(r *http.Request) { r.SetBasicAuth(username, password) }
+}
+
+func bearer(token string) auth {
+	return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+}
+
+// serve sends one request through NewHandler; a nil a sends no credentials
+func serve(t *testing.T, a auth, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if a != nil {
+		a(r)
+	}
+	w := httptest.NewRecorder()
+	NewHandler().ServeHTTP(w, r)
+	return w
+}
+
+// decode unmarshals the response body into v
+func decode(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+}
+
+// reload reads u back from disk, as the next request would see it
+func reload(t *testing.T, u *store.UserData) *store.UserData {
+	t.Helper()
+	saved, err := store.LoadUser(u.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return saved
+}
+
+func TestAuth(t *testing.T) {
+	u := newHunter(t, "read")
+	tests := []struct {
+		name      string
+		auth      auth
+		status    int
+		challenge string // WWW-Authenticate, for 401s
+	}{
+		{"none", nil, http.StatusUnauthorized, `Basic realm="system"`},
+		{"password", basic(u.Username, testPassword), http.StatusOK, ""},
+		{"username case", basic(strings.ToUpper(u.Username), testPassword), http.StatusOK, ""},
+		{"wrong password", basic(u.Username, "nope"), http.StatusUnauthorized, `Basic realm="system"`},
+		{"unknown user", basic("nobody", testPassword), http.StatusUnauthorized, `Basic realm="system"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, tt.auth, "GET", "/api/profile", "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.challenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.challenge)
+			}
+			if tt.status != http.StatusOK {
+				var body errorBody
+				decode(t, w, &body)
+				if body.Error == "" {
+					t.Error("no error message")
+				}
+				return
+			}
+			var p Profile
+			decode(t, w, &p)
+			if p.Username != u.Username || p.Level != u.Level {
+				t.Errorf("profile = %+v, want %s's", p, u.Username)
+			}
+		})
+	}
+}
+
+func TestAuthToken(t *testing.T) {
+	u := newHunter(t, "read")
+	token, err := store.GenerateAPIToken(u, "phone widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := store.GenerateAPIToken(u, "old laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.RevokeAPIToken(u.APITokens[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"token", token, http.StatusOK},
+		{"revoked token", revoked, http.StatusUnauthorized},
+		{"forged token", "sys_" + fmt.Sprintf("%x", u.Username) + "_00", http.StatusUnauthorized},
+		{"malformed token", "hunter", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, bearer(tt.token), "GET", "/api/profile", "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusOK {
+				return
+			}
+			if got, want := w.Header().Get("WWW-Authenticate"), `Bearer realm="system"`; got != want {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAuthPasswordReset(t *testing.T) {
+	u := newHunter(t)
+	u.MustChangePassword = true
+	if err := store.SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	w := serve(t, basic(u.Username, testPassword), "GET", "/api/profile", "")
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d until the password is changed", w.Code, http.StatusForbidden)
+	}
+	var body errorBody
+	decode(t, w, &body)
+	if body.Error == "" {
+		t.Error("no error message")
+	}
+}
+
+func TestAuthLockout(t *testing.T) {
+	setFor(t, &store.LockoutAttempts, 2)
+	u := newHunter(t)
+	for i := 0; i < store.LockoutAttempts; i++ {
+		if w := serve(t, basic(u.Username, "nope"), "GET", "/api/profile", ""); w.Code != http.StatusUnauthorized {
+			t.Fatalf("failed login %d: status %d", i+1, w.Code)
+		}
+	}
+	w := serve(t, basic(u.Username, testPassword), "GET", "/api/profile", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status while locked = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("no Retry-After while locked")
+	}
+}
+
+func TestAddHabit(t *testing.T) {
+	setFor(t, &store.MaxHabits, 2)
+	u := newHunter(t, "read")
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"added", `{"name": "  run  "}`, http.StatusCreated},
+		{"not JSON", `name=run`, http.StatusBadRequest},
+		{"no name", `{"name": " "}`, http.StatusBadRequest},
+		{"too long", fmt.Sprintf(`{"name": %q}`, strings.Repeat("x", maxHabitNameRunes+1)), http.StatusBadRequest},
+		{"at the limit", `{"name": "write"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, basic(u.Username, testPassword), "POST", "/api/habits", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+	saved := reload(t, u)
+	if len(saved.Habits) != 2 || saved.Habits[1].Name != "run" {
+		t.Errorf("saved quests = %+v, want read and run", saved.Habits)
+	}
+}
+
+func TestToggle(t *testing.T) {
+	u := newHunter(t, "read")
+	read := u.Habits[0].ID
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		done   bool // read's state afterwards
+		gained bool
+	}{
+		{"unknown quest", "/api/habits/h_0/toggle", http.StatusNotFound, false, false},
+		{"bad done", "/api/habits/" + read + "/toggle?done=maybe", http.StatusBadRequest, false, false},
+		{"already undone", "/api/habits/" + read + "/toggle?done=false", http.StatusOK, false, false},
+		{"complete", "/api/habits/" + read + "/toggle", http.StatusOK, true, true},
+		{"already done", "/api/habits/" + read + "/toggle?done=true", http.StatusOK, true, false},
+		{"uncheck", "/api/habits/" + read + "/toggle", http.StatusOK, false, false},
+		{"done", "/api/habits/" + read + "/toggle?done=true", http.StatusOK, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, basic(u.Username, testPassword), "POST", tt.path, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if done := reload(t, u).CompletedToday(read); done != tt.done {
+				t.Errorf("read done = %v, want %v", done, tt.done)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var res ToggleResult
+			decode(t, w, &res)
+			if res.Habit.ID != read || res.Habit.CompletedToday != tt.done || res.GainedEXP != tt.gained {
+				t.Errorf("result = %+v, want done %v, gained %v", res, tt.done, tt.gained)
+			}
+		})
+	}
+}
+
+func TestToggleChained(t *testing.T) {
+	u := newHunter(t, "read", "run")
+	read, run := u.Habits[0].ID, u.Habits[1].ID
+	if err := u.EditHabit(run, store.Habit{Name: "run", DependsOn: read}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(t, basic(u.Username, testPassword), "POST", "/api/habits/"+run+"/toggle", ""); w.Code != http.StatusConflict {
+		t.Fatalf("run before read: status %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := serve(t, basic(u.Username, testPassword), "POST", "/api/habits/"+read+"/toggle", ""); w.Code != http.StatusOK {
+		t.Fatalf("read: status %d: %s", w.Code, w.Body)
+	}
+	var status []HabitStatus
+	decode(t, serve(t, basic(u.Username, testPassword), "GET", "/api/habits", ""), &status)
+	if len(status) != 2 || status[1].DependsOn != read || status[1].ChainLocked {
+		t.Errorf("habits = %+v, want run unlocked after read", status)
+	}
+}
+
+func TestToggleLockedIn(t *testing.T) {
+	setFor(t, &store.UncheckPolicy, store.UncheckLocked)
+	u := newHunter(t, "read")
+	path := "/api/habits/" + u.Habits[0].ID + "/toggle"
+	if w := serve(t, basic(u.Username, testPassword), "POST", path, ""); w.Code != http.StatusOK {
+		t.Fatalf("complete: status %d: %s", w.Code, w.Body)
+	}
+	if w := serve(t, basic(u.Username, testPassword), "POST", path, ""); w.Code != http.StatusConflict {
+		t.Errorf("uncheck a locked-in completion: status %d, want %d", w.Code, http.StatusConflict)
+	}
+	if !reload(t, u).CompletedToday(u.Habits[0].ID) {
+		t.Error("locked-in completion was unchecked")
+	}
+}
+
+func TestToggleLevelUp(t *testing.T) {
+	u := newHunter(t, "read")
+	u.EXP = u.EXPForNextLevel() - 1
+	if err := store.SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	w := serve(t, basic(u.Username, testPassword), "POST", "/api/habits/"+u.Habits[0].ID+"/toggle", "")
+	var res ToggleResult
+	decode(t, w, &res)
+	if !res.LeveledUp || res.Stats == nil {
+		t.Fatalf("result = %+v, want a level-up with stats", res)
+	}
+	if sum := res.Stats.STR + res.Stats.VIT + res.Stats.AGI + res.Stats.INT; sum != gemini.PointsPerLevel {
+		t.Errorf("stats %+v allocate %d points, want %d", res.Stats, sum, gemini.PointsPerLevel)
+	}
+	if res.Profile.Level != u.Level+1 {
+		t.Errorf("profile level = %d, want %d", res.Profile.Level, u.Level+1)
+	}
+}
+
+func TestToggleMany(t *testing.T) {
+	u := newHunter(t, "read", "run", "write")
+	ids := func(h ...int) string {
+		var quoted []string
+		for _, i := range h {
+			quoted = append(quoted, fmt.Sprintf("%q", u.Habits[i].ID))
+		}
+		return "[" + strings.Join(quoted, ",") + "]"
+	}
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		done   []bool
+	}{
+		{"no done", `{"ids": []}`, http.StatusBadRequest, []bool{false, false, false}},
+		{"unknown quest", `{"ids": ["h_0"], "done": true}`, http.StatusNotFound, []bool{false, false, false}},
+		{"some", `{"ids": ` + ids(0, 2) + `, "done": true}`, http.StatusOK, []bool{true, false, true}},
+		{"all", `{"done": true}`, http.StatusOK, []bool{true, true, true}},
+		{"undo one", `{"ids": ` + ids(1) + `, "done": false}`, http.StatusOK, []bool{true, false, true}},
+	}
+	exp := 0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, basic(u.Username, testPassword), "POST", "/api/habits/toggle", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if w.Code == http.StatusOK {
+				var res BatchResult
+				decode(t, w, &res)
+				exp += res.EXPDelta
+				if len(res.Habits) == 0 || res.Profile.EXP != exp {
+					t.Errorf("result = %+v, want the quests and %d EXP", res, exp)
+				}
+			}
+			saved := reload(t, u)
+			for i, want := range tt.done {
+				if done := saved.CompletedToday(u.Habits[i].ID); done != want {
+					t.Errorf("%s done = %v, want %v", u.Habits[i].Name, done, want)
+				}
+			}
+		})
+	}
+	saved := reload(t, u)
+	if exp <= 0 || saved.EXP != exp || saved.EXPOn(saved.TodayKey()) != exp {
+		t.Errorf("EXP after the batches = %d (%d today), want the deltas' sum %d", saved.EXP, saved.EXPOn(saved.TodayKey()), exp)
+	}
+}
+
+func TestConcurrentToggles(t *testing.T) {
+	names := make([]string, 30)
+	for i := range names {
+		names[i] = fmt.Sprintf("quest %d", i)
+	}
+	u := newHunter(t, names...)
+	token, err := store.GenerateAPIToken(u, "widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, h := range u.Habits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if w := serve(t, bearer(token), "POST", "/api/habits/"+h.ID+"/toggle?done=true", ""); w.Code != http.StatusOK {
+				t.Errorf("toggle %s: status %d: %s", h.Name, w.Code, w.Body)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	saved := reload(t, u)
+	for _, h := range u.Habits {
+		if !saved.CompletedToday(h.ID) {
+			t.Errorf("%s lost its completion to another request's save", h.Name)
+		}
+	}
+	if want := len(names) * store.EXPPerQuest; saved.TotalEXP() < want {
+		t.Errorf("lifetime EXP = %d, want at least %d", saved.TotalEXP(), want)
+	}
+}
+
+func TestToggleKeptBySessionSave(t *testing.T) {
+	u := newHunter(t, "read")
+	session := store.Share(u) // an SSH session logged in as u
+	defer session.Release()
+	if w := serve(t, basic(u.Username, testPassword), "POST", "/api/habits/"+u.Habits[0].ID+"/toggle", ""); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	// The session's debounced save comes after and writes its record
+	session.Lock()
+	err := store.SaveUser(session.User)
+	session.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reload(t, u).CompletedToday(u.Habits[0].ID) {
+		t.Error("the session's save wrote over a completion made through the API")
+	}
+}
+
+func TestReport(t *testing.T) {
+	u := newHunter(t, "read")
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"?week=-1", http.StatusOK},
+		{"?week=1", http.StatusBadRequest},
+		{"?week=last", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(t, basic(u.Username, testPassword), "GET", "/api/report"+tt.query, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var report store.WeeklyReport
+			decode(t, w, &report)
+			if len(report.EXPByDay) != 7 || len(report.Habits) != 1 {
+				t.Errorf("report = %+v, want 7 days of read", report)
+			}
+		})
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	setFor(t, &store.LeaderboardIntegrity, false) // the levels below have no history
+	setFor(t, &leaderboardTTL, 0)
+	var hunters []*store.UserData
+	for i := 0; i < defaultLeaderboardSize+2; i++ {
+		u := newHunter(t)
+		u.Level = 1000 + i // above any other test's hunters
+		u.EXP = (u.Level - 1) * store.EXPPerLevel
+		if err := store.SaveUser(u); err != nil {
+			t.Fatal(err)
+		}
+		hunters = append(hunters, u)
+	}
+	if w := serve(t, nil, "GET", "/api/leaderboard", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("without credentials: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	// ?user= no longer picks whose rank is shown; it's always the caller's
+	w := serve(t, basic(hunters[0].Username, testPassword), "GET", "/api/leaderboard?user="+hunters[1].Username, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var st store.Standings
+	decode(t, w, &st)
+	if len(st.Top) != defaultLeaderboardSize || st.Top[0].Username != hunters[len(hunters)-1].Username {
+		t.Errorf("top = %+v, want %d hunters led by the highest level", st.Top, defaultLeaderboardSize)
+	}
+	if st.Self == nil || st.Self.Username != hunters[0].Username || st.Self.Rank != len(hunters) {
+		t.Errorf("self = %+v, want %s at rank %d", st.Self, hunters[0].Username, len(hunters))
+	}
+	if len(st.Around) == 0 {
+		t.Error("no neighbours around a hunter outside the top")
+	}
+}
+
+func TestLeaderboardCached(t *testing.T) {
+	setFor(t, &store.LeaderboardIntegrity, false)
+	setFor(t, &leaderboardTTL, time.Hour)
+	caller := newHunter(t)
+	leader := func() string {
+		t.Helper()
+		w := serve(t, basic(caller.Username, testPassword), "GET", "/api/leaderboard", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var st store.Standings
+		decode(t, w, &st)
+		return st.Top[0].Username
+	}
+	rankings.Lock()
+	rankings.at = time.Time{} // whatever an earlier test left
+	rankings.Unlock()
+	before := leader()
+
+	u := newHunter(t)
+	u.Level = 5000
+	u.EXP = (u.Level - 1) * store.EXPPerLevel
+	if err := store.SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	if got := leader(); got != before {
+		t.Errorf("leader = %s within the TTL, want the cached %s", got, before)
+	}
+	leaderboardTTL = 0
+	if got := leader(); got != u.Username {
+		t.Errorf("leader = %s once the TTL ran out, want %s", got, u.Username)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"math/rand"
 	"net/http"
 	"os"
@@ -15,8 +16,8 @@ import (
 )
 
 const (
-	apiURL     = "https://generativelanguage.googleapis.com/v1beta/models/gemini-3-flash-preview:generateContent"
-	apiTimeout = 10 * time.Second
+	apiURL            = "https://generativelanguage.googleapis.com/v1beta/models/gemini-3-flash-preview:generateContent"
+	defaultAPITimeout = 10 * time.Second
 )
 
 // getAPIKey returns the Gemini API key from environment variable
@@ -24,6 +25,20 @@ func getAPIKey() string {
 	return os.Getenv("GEMINI_API_KEY")
 }
 
+// verbose reports whether GEMINI_VERBOSE asks for prompts and responses to be logged
+func verbose() bool {
+	return os.Getenv("GEMINI_VERBOSE") != ""
+}
+
+// dryRun reports whether GEMINI_DRY_RUN asks to log the prompt and skip the API call
+func dryRun() bool {
+	return os.Getenv("GEMINI_DRY_RUN") != ""
+}
+
+// Timeout bounds each AI request. The server sets it from its config
+// (GEMINI_TIMEOUT).
+var Timeout = defaultAPITimeout
+
 // StatResponse represents the stat allocation from Gemini
 type StatResponse struct {
 	STR int `json:"str"`
@@ -56,19 +71,15 @@ type GeminiResponse struct {
 	} `json:"candidates"`
 }
 
-// GetLevelUpStats calls Gemini API to get stat allocation for a level-up
-// habits is a list of habit names for context
-// level is the new level the user has reached
-// Returns the stat increases (not totals)
-func GetLevelUpStats(habits []string, level int) (StatResponse, error) {
-	pointsToAllocate := 4 // Points per level-up
-
+// BuildPrompt returns the stat allocation prompt sent to Gemini for a hunter
+// who just reached level, with points stat points to distribute
+func BuildPrompt(habits []string, level, points int) string {
 	habitList := "None"
 	if len(habits) > 0 {
-		habitList = strings.Join(habits, ", ")
+		habitList = promptHabitList(habits)
 	}
 
-	prompt := fmt.Sprintf(`You are the SYSTEM in a Solo Leveling-inspired habit tracker game. A hunter has just leveled up to level %d.
+	return fmt.Sprintf(`You are the SYSTEM in a Solo Leveling-inspired habit tracker game. A hunter has just leveled up to level %d.
 
 Their daily quests (habits) include: %s
 
@@ -85,8 +96,97 @@ Consider:
 Respond with ONLY a valid JSON object, no markdown, no extra text:
 {"str": X, "vit": Y, "agi": Z, "int": W}
 
-Where X + Y + Z + W = %d. Each value must be 0 or greater.`, level, habitList, pointsToAllocate, pointsToAllocate)
+Where X + Y + Z + W = %d. Each value must be 0 or greater.`, level, habitList, points, points)
+}
+
+// MaxPromptHabits caps how many habit names go into a prompt, so hunters
+// with dozens of quests don't blow up its size and cost
+const MaxPromptHabits = 15
+
+// promptHabitList joins the first MaxPromptHabits names (callers pass the
+// most active first) and summarizes the rest as "and N more"
+func promptHabitList(habits []string) string {
+	if len(habits) <= MaxPromptHabits {
+		return strings.Join(habits, ", ")
+	}
+	return fmt.Sprintf("%s, and %d more", strings.Join(habits[:MaxPromptHabits], ", "), len(habits)-MaxPromptHabits)
+}
+
+// PointsPerLevel is how many stat points each level-up distributes
+const PointsPerLevel = 4
+
+// GetLevelUpStats asks the selected provider (see Allocator) for the stat
+// allocation of a level-up. habits is a list of habit names for context,
+// most recently active first (only MaxPromptHabits reach the prompt);
+// level is the new level the user has reached. Returns the stat increases
+// (not totals).
+func GetLevelUpStats(habits []string, level int) (StatResponse, error) {
+	return GetLevelUpStatsCtx(context.Background(), habits, level)
+}
+
+// GetLevelUpStatsCtx is GetLevelUpStats bounded by ctx. The result always
+// sums to PointsPerLevel: when the provider fails, a random allocation is
+// returned with the error.
+func GetLevelUpStatsCtx(ctx context.Context, habits []string, level int) (StatResponse, error) {
+	stats, err := Allocator.Allocate(ctx, habits, level, PointsPerLevel)
+	if err != nil {
+		return randomFallback(PointsPerLevel), err
+	}
+	stats.STR, stats.VIT, stats.AGI, stats.INT = max(stats.STR, 0), max(stats.VIT, 0), max(stats.AGI, 0), max(stats.INT, 0)
+	if stats.STR+stats.VIT+stats.AGI+stats.INT != PointsPerLevel {
+		// Normalize to ensure correct total
+		stats = normalizeStats(stats, PointsPerLevel)
+	}
+	if verbose() {
+		log.Printf("gemini: %s allocated %+v", Allocator, stats)
+	}
+	return stats, nil
+}
+
+// Gemini allocates stats with the Gemini API, keyed by GEMINI_API_KEY
+type Gemini struct{}
+
+func (Gemini) String() string { return "gemini" }
+
+// Allocate implements StatAllocator
+func (Gemini) Allocate(ctx context.Context, habits []string, level, points int) (StatResponse, error) {
+	prompt := BuildPrompt(habits, level, points)
+	if verbose() || dryRun() {
+		log.Printf("gemini: prompt for level %d:\n%s", level, prompt)
+	}
+	if dryRun() {
+		stats := randomFallback(points)
+		log.Printf("gemini: dry run, using fallback stats %+v", stats)
+		return stats, nil
+	}
+
+	responseText, err := generate(ctx, prompt)
+	if err != nil {
+		return StatResponse{}, err
+	}
+	return parseStats(responseText)
+}
+
+// parseStats extracts the stat allocation JSON from a model response
+func parseStats(responseText string) (StatResponse, error) {
+	match := jsonObject.FindString(responseText)
+	if match == "" {
+		return StatResponse{}, fmt.Errorf("no JSON found in response: %s", responseText)
+	}
+	var stats StatResponse
+	if err := json.Unmarshal([]byte(match), &stats); err != nil {
+		return StatResponse{}, fmt.Errorf("failed to parse stats JSON: %w", err)
+	}
+	return stats, nil
+}
+
+// jsonObject extracts a flat JSON object from a response, which the model
+// sometimes wraps in markdown code blocks or extra text
+var jsonObject = regexp.MustCompile(`\{[^}]+\}`)
 
+// generate sends prompt to Gemini and returns the trimmed text of the first
+// candidate, bounded by ctx and the API timeout
+func generate(ctx context.Context, prompt string) (string, error) {
 	reqBody := GeminiRequest{
 		Contents: []Content{
 			{
@@ -99,15 +199,15 @@ Where X + Y + Z + W = %d. Each value must be 0 or greater.`, level, habitList, p
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return randomFallback(pointsToAllocate), fmt.Errorf("failed to marshal request: %w", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return randomFallback(pointsToAllocate), fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -116,51 +216,36 @@ Where X + Y + Z + W = %d. Each value must be 0 or greater.`, level, habitList, p
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return randomFallback(pointsToAllocate), fmt.Errorf("API request failed: %w", err)
+		return "", fmt.Errorf("API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return randomFallback(pointsToAllocate), fmt.Errorf("failed to read response: %w", err)
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if verbose() {
+		log.Printf("gemini: raw response (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return randomFallback(pointsToAllocate), fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var geminiResp GeminiResponse
 	if err := json.Unmarshal(body, &geminiResp); err != nil {
-		return randomFallback(pointsToAllocate), fmt.Errorf("failed to parse response: %w", err)
+		return "", fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return randomFallback(pointsToAllocate), fmt.Errorf("empty response from API")
+		return "", fmt.Errorf("empty response from API")
 	}
 
 	responseText := geminiResp.Candidates[0].Content.Parts[0].Text
 	responseText = strings.TrimSpace(responseText)
 
-	// Extract JSON from response (handle markdown code blocks)
-	jsonRegex := regexp.MustCompile(`\{[^}]+\}`)
-	match := jsonRegex.FindString(responseText)
-	if match == "" {
-		return randomFallback(pointsToAllocate), fmt.Errorf("no JSON found in response: %s", responseText)
-	}
-
-	var stats StatResponse
-	if err := json.Unmarshal([]byte(match), &stats); err != nil {
-		return randomFallback(pointsToAllocate), fmt.Errorf("failed to parse stats JSON: %w", err)
-	}
-
-	// Validate the response
-	total := stats.STR + stats.VIT + stats.AGI + stats.INT
-	if total != pointsToAllocate {
-		// Normalize to ensure correct total
-		return normalizeStats(stats, pointsToAllocate), nil
-	}
-
-	return stats, nil
+	return responseText, nil
 }
 
 // randomFallback generates random stat allocation when API fails
@@ -0,0 +1,215 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setFor sets *p to v until the test ends
+func setFor[T any](t *testing.T, p *T, v T) {
+	t.Helper()
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+func sum(s StatResponse) int { return s.STR + s.VIT + s.AGI + s.INT }
+
+// stubAllocator answers with stats and err, or waits for ctx when block is set
+type stubAllocator struct {
+	stats StatResponse
+	err   error
+	block bool
+}
+
+func (stubAllocator) String() string { return "stub" }
+
+func (s stubAllocator) Allocate(ctx context.Context, _ []string, _, _ int) (StatResponse, error) {
+	if s.block {
+		<-ctx.Done()
+		return StatResponse{}, ctx.Err()
+	}
+	return s.stats, s.err
+}
+
+func TestPromptHabitList(t *testing.T) {
+	names := func(n int) []string {
+		var out []string
+		for i := range n {
+			out = append(out, fmt.Sprintf("quest%d", i))
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		habits []string
+		want   string
+	}{
+		{"none", nil, "include: None"},
+		{"few", []string{"read", "run"}, "include: read, run\n"},
+		{"at the cap", names(MaxPromptHabits), "quest14\n"},
+		{"over the cap", names(MaxPromptHabits + 5), "quest14, and 5 more\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := BuildPrompt(tt.habits, 7, PointsPerLevel)
+			if !strings.Contains(prompt, tt.want) {
+				t.Errorf("prompt missing %q:\n%s", tt.want, prompt)
+			}
+			if strings.Contains(prompt, fmt.Sprintf("quest%d", MaxPromptHabits)) {
+				t.Errorf("prompt names more than %d quests", MaxPromptHabits)
+			}
+		})
+	}
+}
+
+func TestNewAllocator(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", "gemini"},
+		{"gemini", "gemini"},
+		{" OpenAI ", "openai"},
+		{"local", "local"},
+		{"claude", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAllocator(tt.name)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("NewAllocator(%q) = %v, want an error", tt.name, a)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprint(a); got != tt.want {
+				t.Errorf("NewAllocator(%q) = %s, want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStats(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want StatResponse
+		err  bool
+	}{
+		{"bare", `{"str": 1, "vit": 2, "agi": 0, "int": 1}`, StatResponse{1, 2, 0, 1}, false},
+		{"markdown", "```json\n{\"str\": 4, \"vit\": 0, \"agi\": 0, \"int\": 0}\n```", StatResponse{4, 0, 0, 0}, false},
+		{"no JSON", "I can't help with that", StatResponse{}, true},
+		{"bad JSON", `{"str": one}`, StatResponse{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStats(tt.text)
+			if (err != nil) != tt.err || got != tt.want {
+				t.Errorf("parseStats() = %+v, %v; want %+v, error %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestLocalAllocateKeywords(t *testing.T) {
+	tests := []struct {
+		habits []string
+		want   StatResponse
+	}{
+		{[]string{"read a book", "study"}, StatResponse{INT: PointsPerLevel}},
+		{[]string{"gym"}, StatResponse{STR: PointsPerLevel}},
+	}
+	for _, tt := range tests {
+		got, err := Local{}.Allocate(t.Context(), tt.habits, 3, PointsPerLevel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Allocate(%q) = %+v, want %+v", tt.habits, got, tt.want)
+		}
+	}
+}
+
+func TestLocalAllocateSpreads(t *testing.T) {
+	for _, habits := range [][]string{{"call mom"}, nil} {
+		got, err := Local{}.Allocate(t.Context(), habits, 3, PointsPerLevel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (StatResponse{1, 1, 1, 1}); got != want {
+			t.Errorf("Allocate(%q) = %+v, want %+v", habits, got, want)
+		}
+	}
+}
+
+func TestGetLevelUpStats(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats StatResponse
+		want  StatResponse
+	}{
+		{"as allocated", StatResponse{1, 1, 2, 0}, StatResponse{1, 1, 2, 0}},
+		{"scaled down", StatResponse{8, 0, 0, 0}, StatResponse{4, 0, 0, 0}},
+		{"negatives dropped", StatResponse{-3, 4, 0, 0}, StatResponse{0, 4, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFor(t, &Allocator, StatAllocator(stubAllocator{stats: tt.stats}))
+			got, err := GetLevelUpStatsCtx(t.Context(), []string{"read"}, 5)
+			if err != nil || got != tt.want {
+				t.Errorf("GetLevelUpStatsCtx() = %+v, %v; want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetLevelUpStatsFallback(t *testing.T) {
+	for _, alloc := range []stubAllocator{{}, {err: errors.New("quota")}} {
+		setFor(t, &Allocator, StatAllocator(alloc))
+		got, err := GetLevelUpStatsCtx(t.Context(), []string{"read"}, 5)
+		if (err != nil) != (alloc.err != nil) {
+			t.Errorf("with %+v: error = %v", alloc, err)
+		}
+		if sum(got) != PointsPerLevel {
+			t.Errorf("with %+v: fallback %+v sums to %d, want %d", alloc, got, sum(got), PointsPerLevel)
+		}
+	}
+}
+
+func TestGetLevelUpStatsCanceled(t *testing.T) {
+	setFor(t, &Allocator, StatAllocator(stubAllocator{block: true}))
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	start := time.Now()
+	got, err := GetLevelUpStatsCtx(ctx, []string{"read"}, 5)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if sum(got) != PointsPerLevel {
+		t.Errorf("fallback %+v sums to %d, want %d", got, sum(got), PointsPerLevel)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("returned after %s", took)
+	}
+}
+
+func TestGetLevelUpStatsDeadline(t *testing.T) {
+	setFor(t, &Allocator, StatAllocator(stubAllocator{block: true}))
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	got, err := GetLevelUpStatsCtx(ctx, []string{"read"}, 5)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context.DeadlineExceeded", err)
+	}
+	if sum(got) != PointsPerLevel {
+		t.Errorf("fallback %+v sums to %d, want %d", got, sum(got), PointsPerLevel)
+	}
+}
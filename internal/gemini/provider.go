@@ -0,0 +1,176 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// StatAllocator distributes a level-up's stat points. Implementations may
+// return any non-negative split; GetLevelUpStatsCtx normalizes it to points
+// and falls back to a random split on error.
+type StatAllocator interface {
+	Allocate(ctx context.Context, habits []string, level, points int) (StatResponse, error)
+}
+
+// Allocator is the provider used for level-ups, chosen with NewAllocator
+var Allocator StatAllocator = Gemini{}
+
+// Providers lists the names NewAllocator accepts
+var Providers = []string{"gemini", "openai", "local"}
+
+// NewAllocator returns the provider named by SYSTEM_AI_PROVIDER; "" selects
+// Gemini, the original provider
+func NewAllocator(name string) (StatAllocator, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "gemini":
+		return Gemini{}, nil
+	case "openai":
+		return OpenAI{}, nil
+	case "local":
+		return Local{}, nil
+	}
+	return nil, fmt.Errorf("unknown AI provider %q (want %s)", name, strings.Join(Providers, ", "))
+}
+
+// Local allocates stats offline by matching quest names against keywords,
+// the same hints the AI prompt gives. It never fails.
+type Local struct{}
+
+func (Local) String() string { return "local" }
+
+// localKeywords maps words in quest names to the stats they favor
+var localKeywords = []struct {
+	words []string
+	stats []string
+}{
+	{[]string{"gym", "lift", "push", "pull", "squat", "strength", "weights", "plank"}, []string{"STR"}},
+	{[]string{"run", "walk", "bike", "cycle", "swim", "cardio", "workout", "exercise"}, []string{"STR", "VIT", "AGI"}},
+	{[]string{"stretch", "yoga", "dance", "sprint", "jump", "sport"}, []string{"AGI"}},
+	{[]string{"sleep", "meditate", "water", "drink", "eat", "cook", "vegetable", "rest"}, []string{"VIT"}},
+	{[]string{"read", "study", "learn", "write", "journal", "code", "language", "practice", "book"}, []string{"INT"}},
+}
+
+// Allocate implements StatAllocator. Points go to the stats the quests
+// favor most, ties broken in STR, VIT, AGI, INT order; with no matching
+// quests the split is even, rotating the remainder with the level.
+func (Local) Allocate(_ context.Context, habits []string, level, points int) (StatResponse, error) {
+	order := []string{"STR", "VIT", "AGI", "INT"}
+	weight := map[string]int{}
+	for _, h := range habits {
+		name := strings.ToLower(h)
+		for _, kw := range localKeywords {
+			for _, w := range kw.words {
+				if strings.Contains(name, w) {
+					for _, st := range kw.stats {
+						weight[st]++
+					}
+					break
+				}
+			}
+		}
+	}
+	alloc := map[string]int{}
+	total := weight["STR"] + weight["VIT"] + weight["AGI"] + weight["INT"]
+	if total == 0 {
+		for i := 0; i < points; i++ {
+			alloc[order[(level+i)%len(order)]]++
+		}
+	} else {
+		// One point at a time to the stat furthest below its share
+		for i := 0; i < points; i++ {
+			best, bestGap := "", 0.0
+			for _, st := range order {
+				gap := float64(weight[st])/float64(total)*float64(i+1) - float64(alloc[st])
+				if best == "" || gap > bestGap {
+					best, bestGap = st, gap
+				}
+			}
+			alloc[best]++
+		}
+	}
+	return StatResponse{STR: alloc["STR"], VIT: alloc["VIT"], AGI: alloc["AGI"], INT: alloc["INT"]}, nil
+}
+
+// OpenAI allocates stats with an OpenAI-style chat completions API. It is a
+// minimal client: OPENAI_API_KEY authenticates, OPENAI_BASE_URL points at any
+// compatible server (default https://api.openai.com/v1) and OPENAI_MODEL
+// picks the model (default gpt-4o-mini).
+type OpenAI struct{}
+
+func (OpenAI) String() string { return "openai" }
+
+// Allocate implements StatAllocator
+func (OpenAI) Allocate(ctx context.Context, habits []string, level, points int) (StatResponse, error) {
+	prompt := BuildPrompt(habits, level, points)
+	if verbose() || dryRun() {
+		log.Printf("openai: prompt for level %d:\n%s", level, prompt)
+	}
+	if dryRun() {
+		return randomFallback(points), nil
+	}
+
+	baseURL := strings.TrimSuffix(envOr("OPENAI_BASE_URL", "https://api.openai.com/v1"), "/")
+	body, err := json.Marshal(map[string]any{
+		"model":    envOr("OPENAI_MODEL", "gpt-4o-mini"),
+		"messages": []map[string]string{{"role": "user", "content": prompt}},
+	})
+	if err != nil {
+		return StatResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return StatResponse{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return StatResponse{}, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return StatResponse{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	if verbose() {
+		log.Printf("openai: raw response (status %d): %s", resp.StatusCode, raw)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return StatResponse{}, fmt.Errorf("API returned status %d: %s", resp.StatusCode, raw)
+	}
+
+	var chat struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(raw, &chat); err != nil {
+		return StatResponse{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(chat.Choices) == 0 {
+		return StatResponse{}, fmt.Errorf("empty response from API")
+	}
+	return parseStats(strings.TrimSpace(chat.Choices[0].Message.Content))
+}
+
+// envOr returns the environment variable key, or def when it is unset
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
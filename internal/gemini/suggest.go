@@ -0,0 +1,129 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"unicode"
+)
+
+// MaxSuggestionRunes caps the length of a suggested quest name
+const MaxSuggestionRunes = 40
+
+// fallbackQuests are suggested when the API is off or unavailable
+var fallbackQuests = []string{
+	"Drink 2L of water",
+	"Read 10 pages",
+	"20 push-ups",
+	"10 minute walk",
+	"Stretch for 5 minutes",
+	"Meditate 10 minutes",
+	"Journal 3 lines",
+	"No phone before breakfast",
+	"Practice a language 15 min",
+	"Tidy your desk",
+	"Sleep before midnight",
+	"Cook a home meal",
+	"Plank for 1 minute",
+	"Write down tomorrow's plan",
+	"Learn one new fact",
+}
+
+// SuggestQuest proposes one new daily quest that complements existing
+func SuggestQuest(existing []string) (string, error) {
+	return SuggestQuestCtx(context.Background(), existing)
+}
+
+// SuggestQuestCtx is SuggestQuest bounded by ctx. The result never duplicates
+// an existing quest; when the API is off, fails, or repeats one, a quest from
+// the curated fallback list is returned instead (with the error, if any).
+func SuggestQuestCtx(ctx context.Context, existing []string) (string, error) {
+	if dryRun() || getAPIKey() == "" {
+		return fallbackQuest(existing)
+	}
+
+	prompt := buildSuggestPrompt(existing)
+	if verbose() {
+		log.Printf("gemini: suggest prompt:\n%s", prompt)
+	}
+	responseText, err := generate(ctx, prompt)
+	if err != nil {
+		name, _ := fallbackQuest(existing)
+		return name, err
+	}
+
+	var resp struct {
+		Quest string `json:"quest"`
+	}
+	match := jsonObject.FindString(responseText)
+	if match == "" || json.Unmarshal([]byte(match), &resp) != nil {
+		name, _ := fallbackQuest(existing)
+		return name, fmt.Errorf("no quest found in response: %s", responseText)
+	}
+	name := cleanSuggestion(resp.Quest)
+	if name == "" || isExisting(name, existing) {
+		return fallbackQuest(existing)
+	}
+	return name, nil
+}
+
+func buildSuggestPrompt(existing []string) string {
+	habitList := "None"
+	if len(existing) > 0 {
+		habitList = strings.Join(existing, ", ")
+	}
+	return fmt.Sprintf(`You are the SYSTEM in a Solo Leveling-inspired habit tracker game. Suggest ONE new daily quest (habit) for a hunter.
+
+Their current daily quests: %s
+
+The new quest must be different from the current ones, complement them, be doable every day, and be at most %d characters.
+
+Respond with ONLY a valid JSON object, no markdown, no extra text:
+{"quest": "..."}`, habitList, MaxSuggestionRunes)
+}
+
+// cleanSuggestion keeps a suggestion to one printable line of at most
+// MaxSuggestionRunes, without surrounding quotes or punctuation
+func cleanSuggestion(name string) string {
+	if i := strings.IndexAny(name, "\r\n"); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsPrint(r) {
+			return r
+		}
+		return -1
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), "\"'`.*-")
+	name = strings.TrimSpace(name)
+	if runes := []rune(name); len(runes) > MaxSuggestionRunes {
+		name = strings.TrimSpace(string(runes[:MaxSuggestionRunes]))
+	}
+	return name
+}
+
+func isExisting(name string, existing []string) bool {
+	for _, e := range existing {
+		if strings.EqualFold(strings.TrimSpace(e), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackQuest picks a random curated quest the user doesn't have yet
+func fallbackQuest(existing []string) (string, error) {
+	var options []string
+	for _, q := range fallbackQuests {
+		if !isExisting(q, existing) {
+			options = append(options, q)
+		}
+	}
+	if len(options) == 0 {
+		return "", fmt.Errorf("no new quest to suggest")
+	}
+	return options[rand.Intn(len(options))], nil
+}
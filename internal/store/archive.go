@@ -0,0 +1,87 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// AutoArchiveStale moves habits missed on at least days consecutive past
+// days into Archived and returns them; days <= 0 disables it. The run of
+// misses starts after the habit's last completion or, for a habit never
+// completed, on the day it was added, so a brand-new habit has no misses
+// yet. The welcome quest is never archived.
+func (u *UserData) AutoArchiveStale(days int) []Habit {
+	if days <= 0 {
+		return nil
+	}
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var archived []Habit
+	for i := len(u.Habits) - 1; i >= 0; i-- {
+		h := u.Habits[i]
+		if h.ID == WelcomeQuestID {
+			continue
+		}
+		if u.missedRun(h, today) < days {
+			continue
+		}
+		u.removeHabitAt(i)
+		u.Archived = append(u.Archived, h)
+		archived = append([]Habit{h}, archived...)
+		Audit(u.Username, AuditEvent{Type: AuditHabitArchived, HabitID: h.ID, Habit: h.Name, Detail: fmt.Sprintf("after %d missed days", days)})
+	}
+	return archived
+}
+
+// missedRun counts the past days in a row, ending yesterday, on which h was
+// not completed, already existed and wasn't freshly restored. Caller holds u.mu.
+func (u *UserData) missedRun(h Habit, today string) int {
+	last := ""
+	for day, completions := range u.DailyCompletions {
+		if completions[h.ID] && day < today && day > last {
+			last = day
+		}
+	}
+	if u.DailyCompletions[today][h.ID] {
+		return 0
+	}
+	if !h.RestoredAt.IsZero() {
+		// A restore counts like a completion the day before it
+		if restored := addDays(u.dayKeyAt(h.RestoredAt), -1); restored > last {
+			last = restored
+		}
+	}
+	if last != "" {
+		return daysBetween(last, today) - 1
+	}
+	created := u.habitAddedDay(h)
+	if created == "" {
+		return 0 // unknown age: never stale
+	}
+	return daysBetween(created, today)
+}
+
+// RestoreHabit moves an archived habit back to the end of the quest list.
+// At MaxHabits it returns ErrHabitLimit.
+func (u *UserData) RestoreHabit(id string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.atHabitLimit() {
+		return fmt.Errorf("%w (%d)", ErrHabitLimit, MaxHabits)
+	}
+	for i, h := range u.Archived {
+		if h.ID != id {
+			continue
+		}
+		archived := make([]Habit, 0, len(u.Archived)-1)
+		archived = append(archived, u.Archived[:i]...)
+		u.Archived = append(archived, u.Archived[i+1:]...)
+		h.RestoredAt = time.Now()
+		u.Habits = append(u.Habits, h)
+		Audit(u.Username, AuditEvent{Type: AuditHabitRestored, HabitID: h.ID, Habit: h.Name})
+		return nil
+	}
+	return fmt.Errorf("unknown archived quest")
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAutoArchiveStale(t *testing.T) {
+	tests := []struct {
+		name     string
+		done     []int // offsets from today
+		restored int   // offset of a restore; 0 for none
+		archived bool
+	}{
+		{"never done", nil, 0, true},
+		{"done yesterday", []int{-1}, 0, false},
+		{"done today", []int{0}, 0, false},
+		{"last done a week ago", []int{-8}, 0, true},
+		{"last done six days ago", []int{-7}, 0, false},
+		{"restored yesterday", nil, -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("read", "run")
+			startedDaysAgo(u, 10)
+			stale := u.Habits[0]
+			u.DailyCompletions[today(u, 0)] = map[string]bool{u.Habits[1].ID: true} // run stays fresh
+			for _, d := range tt.done {
+				if u.DailyCompletions[today(u, d)] == nil {
+					u.DailyCompletions[today(u, d)] = map[string]bool{}
+				}
+				u.DailyCompletions[today(u, d)][stale.ID] = true
+			}
+			if tt.restored != 0 {
+				u.Habits[0].RestoredAt = time.Now().AddDate(0, 0, tt.restored)
+			}
+			archived := u.AutoArchiveStale(7)
+			if got := len(archived) == 1 && archived[0].ID == stale.ID; got != tt.archived {
+				t.Fatalf("AutoArchiveStale(7) = %+v, want read archived %v", archived, tt.archived)
+			}
+			if _, active := u.HabitByID(stale.ID); active == tt.archived {
+				t.Errorf("read still active %v", active)
+			}
+			if len(u.Archived) != len(archived) || len(u.Habits)+len(u.Archived) != 2 {
+				t.Errorf("quests %+v, archived %+v", u.Habits, u.Archived)
+			}
+		})
+	}
+}
+
+func TestAutoArchiveOff(t *testing.T) {
+	u := newUser("read")
+	if archived := u.AutoArchiveStale(0); archived != nil || len(u.Habits) != 1 {
+		t.Errorf("AutoArchiveStale(0) archived %+v", archived)
+	}
+}
+
+func TestRestoreHabit(t *testing.T) {
+	setFor(t, &MaxHabits, 2)
+	u := newUser("read", "run")
+	startedDaysAgo(u, 10)
+	read := u.Habits[0].ID
+	if archived := u.AutoArchiveStale(7); len(archived) != 2 {
+		t.Fatalf("archived %+v, want both quests", archived)
+	}
+	u.AddHabit("write")
+	u.AddHabit("stretch")
+	if err := u.RestoreHabit(read); !errors.Is(err, ErrHabitLimit) {
+		t.Errorf("restore at the limit: %v", err)
+	}
+	u.RemoveHabit(1)
+	if err := u.RestoreHabit("h_0"); err == nil {
+		t.Error("restored an unknown quest")
+	}
+	if err := u.RestoreHabit(read); err != nil {
+		t.Fatal(err)
+	}
+	h, ok := u.HabitByID(read)
+	if !ok || h.RestoredAt.IsZero() || len(u.Archived) != 1 {
+		t.Fatalf("after restoring: %+v, archived %+v", h, u.Archived)
+	}
+	if archived := u.AutoArchiveStale(7); len(archived) != 0 {
+		t.Errorf("a freshly restored quest was archived again: %+v", archived)
+	}
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Audit event types
+const (
+	AuditLogin           = "login"
+	AuditLoginFailed     = "login_failed"
+	AuditLockout         = "lockout"
+	AuditHabitAdded      = "habit_added"
+	AuditHabitRemoved    = "habit_removed"
+	AuditComplete        = "complete"
+	AuditUncomplete      = "uncomplete"
+	AuditLevelUp         = "level_up"
+	AuditStreak          = "streak"
+	AuditPrestige        = "prestige"
+	AuditMilestone       = "milestone"
+	AuditPasswordReset   = "password_reset"
+	AuditPasswordChanged = "password_changed"
+	AuditAdminEdit       = "admin_edit"
+	AuditShield          = "shield"
+	AuditHabitArchived   = "habit_archived"
+	AuditHabitRestored   = "habit_restored"
+	AuditTokenCreated    = "token_created"
+	AuditTokenRevoked    = "token_revoked"
+	AuditHardReset       = "hard_reset"
+	AuditSeason          = "season"
+	AuditSpotlight       = "spotlight"
+	AuditDayComplete     = "day_complete"
+)
+
+// MaxAuditBytes caps a user's audit log; past it the log is rotated to .log.1
+var MaxAuditBytes int64 = 256 * 1024
+
+// AuditEvent is one line of a user's append-only audit log
+type AuditEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	HabitID string    `json:"habit_id,omitempty"`
+	Habit   string    `json:"habit,omitempty"`
+	Day     string    `json:"day,omitempty"` // day key a completion applied to
+	EXP     int       `json:"exp,omitempty"`
+	Level   int       `json:"level,omitempty"`
+	Streak  int       `json:"streak,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+type auditRecord struct {
+	username string
+	event    AuditEvent
+	flushed  chan struct{} // set for flush markers instead of an event
+}
+
+var (
+	auditCh   = make(chan auditRecord, 256)
+	auditOnce sync.Once
+)
+
+// OnAudit, if set, is also handed every audited event, e.g. to fire
+// webhooks. It's called on the caller's goroutine, often with the user's
+// lock held, so it must return at once. The server sets it at startup.
+var OnAudit func(username string, ev AuditEvent)
+
+// Audit records an event for username (never the demo account). It never
+// blocks: if the writer falls behind the event is dropped, and write errors
+// are only logged.
+func Audit(username string, ev AuditEvent) {
+	if username == "" || IsDemo(username) {
+		return
+	}
+	auditOnce.Do(func() { go auditWriter() })
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if OnAudit != nil {
+		OnAudit(username, ev)
+	}
+	select {
+	case auditCh <- auditRecord{username: username, event: ev}:
+	default:
+	}
+}
+
+// flushAudit waits until every event queued before the call is written
+func flushAudit() {
+	auditOnce.Do(func() { go auditWriter() })
+	done := make(chan struct{})
+	auditCh <- auditRecord{flushed: done}
+	<-done
+}
+
+func auditWriter() {
+	for rec := range auditCh {
+		if rec.flushed != nil {
+			close(rec.flushed)
+			continue
+		}
+		if err := appendAudit(rec.username, rec.event); err != nil {
+			log.Printf("audit: %s: %v", rec.username, err)
+		}
+	}
+}
+
+func auditPath(username string) string {
+	return strings.TrimSuffix(userPath(username), ".json") + ".log"
+}
+
+func appendAudit(username string, ev AuditEvent) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	path := auditPath(username)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size()+int64(len(line)) > MaxAuditBytes {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ReadAudit returns the last n audit events for username, oldest first
+// (n <= 0 returns all of the current log). A missing log is not an error.
+func ReadAudit(username string, n int) ([]AuditEvent, error) {
+	flushAudit()
+	username = NormalizeUsername(username)
+	f, err := os.Open(auditPath(username))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []AuditEvent
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var ev AuditEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			continue // skip a torn or hand-edited line
+		}
+		events = append(events, ev)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if n > 0 && len(events) > n {
+		events = events[len(events)-n:]
+	}
+	return events, nil
+}
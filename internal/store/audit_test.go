@@ -0,0 +1,63 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestReadAudit(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	for i := 0; i < 5; i++ {
+		Audit("reader", AuditEvent{Type: AuditLogin, Detail: fmt.Sprint(i)})
+	}
+	tests := []struct {
+		n     int
+		first string
+		count int
+	}{
+		{0, "0", 5},
+		{3, "2", 3},
+		{10, "0", 5},
+	}
+	for _, tt := range tests {
+		events, err := ReadAudit("Reader", tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != tt.count || events[0].Detail != tt.first || events[0].Time.IsZero() {
+			t.Errorf("ReadAudit(%d) = %+v, want %d events from %s", tt.n, events, tt.count, tt.first)
+		}
+	}
+	if events, err := ReadAudit("nobody", 0); err != nil || events != nil {
+		t.Errorf("ReadAudit(nobody) = %v, %v", events, err)
+	}
+}
+
+func TestAuditRotates(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	setFor(t, &MaxAuditBytes, 200)
+	for i := 0; i < 10; i++ {
+		Audit("rotator", AuditEvent{Type: AuditLogin, Detail: fmt.Sprint(i)})
+	}
+	events, err := ReadAudit("rotator", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 || len(events) == 10 || events[len(events)-1].Detail != "9" {
+		t.Errorf("current log holds %d events, want only the latest after rotating", len(events))
+	}
+	if info, err := os.Stat(auditPath("rotator") + ".1"); err != nil || info.Size() > MaxAuditBytes {
+		t.Errorf("rotated log: %v", err)
+	}
+}
+
+func TestAuditSkipsDemo(t *testing.T) {
+	setFor(t, &DemoUser, "demo")
+	events := captureAudit(t)
+	Audit("demo", AuditEvent{Type: AuditLogin})
+	Audit("", AuditEvent{Type: AuditLogin})
+	if len(*events) != 0 {
+		t.Errorf("audited %+v", *events)
+	}
+}
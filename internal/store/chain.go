@@ -0,0 +1,85 @@
+package store
+
+import "fmt"
+
+// Quest chains: a quest with DependsOn set is locked each day until the
+// quest it depends on is done that day. A prerequisite that was deleted or
+// archived no longer locks anything. Unchecking a prerequisite leaves
+// quests already completed after it alone.
+
+// ChainLocked reports whether the habit can't be completed on day (a day
+// key) because its prerequisite isn't done yet. Completed quests are never
+// locked, so they can still be unchecked.
+func (u *UserData) ChainLocked(habitID, day string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.chainLocked(habitID, day)
+}
+
+// chainLocked is ChainLocked. Caller holds u.mu.
+func (u *UserData) chainLocked(habitID, day string) bool {
+	if u.DailyCompletions[day][habitID] {
+		return false
+	}
+	for _, h := range u.Habits {
+		if h.ID == habitID {
+			return h.DependsOn != "" && u.habitName(h.DependsOn) != "" && !u.DailyCompletions[day][h.DependsOn]
+		}
+	}
+	return false
+}
+
+// Prerequisite returns the active quest h depends on, if any
+func (u *UserData) Prerequisite(h Habit) (Habit, bool) {
+	if h.DependsOn == "" {
+		return Habit{}, false
+	}
+	return u.HabitByID(h.DependsOn)
+}
+
+// checkDependsOn reports whether habit id may depend on prereq: "" always
+// may, otherwise prereq must be another active quest whose own chain doesn't
+// lead back to id. Caller holds u.mu.
+func (u *UserData) checkDependsOn(id, prereq string) error {
+	if prereq == "" {
+		return nil
+	}
+	if u.habitName(prereq) == "" {
+		return fmt.Errorf("unknown prerequisite quest")
+	}
+	if u.chainLoops(id, prereq) {
+		return ErrChainCycle
+	}
+	return nil
+}
+
+// chainLoops reports whether following DependsOn from prereq reaches id
+// (or loops without it). Caller holds u.mu.
+func (u *UserData) chainLoops(id, prereq string) bool {
+	deps := make(map[string]string, len(u.Habits))
+	for _, h := range u.Habits {
+		deps[h.ID] = h.DependsOn
+	}
+	seen := map[string]bool{}
+	for cur := prereq; cur != ""; cur = deps[cur] {
+		if cur == id || seen[cur] {
+			return true
+		}
+		seen[cur] = true
+	}
+	return false
+}
+
+// PrerequisiteOptions returns the active quests habit id could depend on
+// without making a loop, in list order. Use "" for a quest not added yet.
+func (u *UserData) PrerequisiteOptions(id string) []Habit {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var opts []Habit
+	for _, h := range u.Habits {
+		if h.ID != WelcomeQuestID && !u.chainLoops(id, h.ID) {
+			opts = append(opts, h)
+		}
+	}
+	return opts
+}
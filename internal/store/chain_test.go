@@ -0,0 +1,88 @@
+package store
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChainLocked(t *testing.T) {
+	u := newUser("wake", "stretch", "run")
+	wake, stretch, run := u.Habits[0].ID, u.Habits[1].ID, u.Habits[2].ID
+	for _, edit := range []struct{ id, name, on string }{{stretch, "stretch", wake}, {run, "run", stretch}} {
+		if err := u.EditHabit(edit.id, Habit{Name: edit.name, DependsOn: edit.on}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	day := today(u, 0)
+	if !u.ChainLocked(stretch, day) || !u.ChainLocked(run, day) || u.ChainLocked(wake, day) {
+		t.Fatal("chain not locked in order")
+	}
+	if _, _, err := u.ToggleToday(run); !errors.Is(err, ErrQuestChained) {
+		t.Errorf("completing run first: %v", err)
+	}
+	mustToggle(t, u, wake, day)
+	if u.ChainLocked(stretch, day) || !u.ChainLocked(run, day) {
+		t.Error("completing wake should unlock stretch only")
+	}
+	mustToggle(t, u, stretch, day)
+	mustToggle(t, u, run, day)
+	mustToggle(t, u, wake, day) // unchecking a prerequisite leaves the rest done
+	if !u.CompletedToday(run) || u.ChainLocked(run, day) {
+		t.Error("unchecking wake undid run")
+	}
+	u.RemoveHabit(0)
+	if u.ChainLocked(stretch, today(u, 1)) {
+		t.Error("a deleted prerequisite still locks")
+	}
+}
+
+func TestChainCycle(t *testing.T) {
+	u := newUser("a", "b", "c")
+	a, b, c := u.Habits[0].ID, u.Habits[1].ID, u.Habits[2].ID
+	tests := []struct {
+		id, on string
+		err    error
+	}{
+		{b, a, nil},
+		{c, b, nil},
+		{a, c, ErrChainCycle},
+		{a, a, ErrChainCycle},
+	}
+	for _, tt := range tests {
+		h, _ := u.HabitByID(tt.id)
+		if err := u.EditHabit(tt.id, Habit{Name: h.Name, DependsOn: tt.on}); !errors.Is(err, tt.err) {
+			t.Errorf("%s depends on %s: %v, want %v", h.Name, tt.on, err, tt.err)
+		}
+	}
+	if err := u.EditHabit(a, Habit{Name: "a", DependsOn: "h_0"}); err == nil {
+		t.Error("EditHabit accepted an unknown prerequisite")
+	}
+	var opts []string
+	for _, h := range u.PrerequisiteOptions(a) {
+		opts = append(opts, h.Name)
+	}
+	if len(opts) != 0 {
+		t.Errorf("a could depend on %q, but b and c lead back to it", opts)
+	}
+}
+
+func TestToggleManyChain(t *testing.T) {
+	u := newUser("wake", "stretch")
+	wake, stretch := u.Habits[0].ID, u.Habits[1].ID
+	if err := u.EditHabit(stretch, Habit{Name: "stretch", DependsOn: wake}); err != nil {
+		t.Fatal(err)
+	}
+	if exp, _ := u.ToggleMany([]string{stretch}, true); exp != 0 {
+		t.Errorf("stretch alone paid %d EXP while locked", exp)
+	}
+	exp, _ := u.ToggleMany([]string{stretch, wake, stretch}, true)
+	if exp != 2*EXPPerQuest || !u.CompletedToday(stretch) {
+		t.Errorf("batch with the prerequisite after = %d EXP, stretch done %v", exp, u.CompletedToday(stretch))
+	}
+	if exp, _ := u.ToggleMany([]string{stretch, wake}, true); exp != 0 {
+		t.Errorf("repeating the batch paid %d EXP", exp)
+	}
+	if exp, _ := u.ToggleMany([]string{stretch, wake}, false); exp != -2*EXPPerQuest {
+		t.Errorf("unchecking both = %d EXP", exp)
+	}
+}
@@ -0,0 +1,112 @@
+package store
+
+import "time"
+
+// UncheckGrace is how long a completed quest can still be unchecked. Past
+// it the completion is locked in and its EXP committed, so a quest can't be
+// toggled back and forth for EXP. Under UncheckRefund and UncheckNoRefund, 0
+// (the default) never locks, and a completion made before its time was
+// recorded never locks either. UncheckLocked is stricter; see there. Set by
+// SYSTEM_UNCHECK_GRACE.
+var UncheckGrace time.Duration
+
+// What unchecking a completed quest does to the EXP it paid; see UncheckPolicy
+const (
+	UncheckRefund   = "refund"    // takes the EXP back, which can cost a level
+	UncheckNoRefund = "no-refund" // keeps the EXP; checking it again that day pays nothing more
+	// UncheckLocked makes completions final. UncheckGrace is the only window
+	// for taking one back, as an undo that refunds its EXP; with no grace
+	// there's none, and a completion locks as it's made. Once the window
+	// closes nothing unchecks it, a completion of unknown age included, and
+	// trying returns ErrCompletionFinal rather than the grace lock's
+	// ErrCompletionLocked.
+	UncheckLocked = "locked"
+)
+
+// UncheckPolicies are the valid UncheckPolicy values
+var UncheckPolicies = []string{UncheckRefund, UncheckNoRefund, UncheckLocked}
+
+// UncheckPolicy is what unchecking a completion does, for every hunter.
+// Unchecking always clears the checkmark, so the day can stop counting
+// toward the streak whatever the policy. Set by SYSTEM_UNCHECK_POLICY.
+var UncheckPolicy = UncheckRefund
+
+// CompletionTimes records when each completion was made, by day key then
+// habit ID
+type CompletionTimes map[string]map[string]time.Time
+
+// LockedIn reports whether the habit's completion on day (a day key) is past
+// UncheckGrace and can no longer be unchecked
+func (u *UserData) LockedIn(habitID, day string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.lockedIn(habitID, day, time.Now())
+}
+
+// lockedIn is LockedIn at now. Caller holds u.mu.
+func (u *UserData) lockedIn(habitID, day string, now time.Time) bool {
+	if !u.DailyCompletions[day][habitID] {
+		return false
+	}
+	at, timed := u.CompletedAt[day][habitID]
+	if UncheckPolicy == UncheckLocked {
+		if habitID == WelcomeQuestID {
+			return false
+		}
+		return !timed || now.Sub(at) >= UncheckGrace
+	}
+	return UncheckGrace > 0 && timed && now.Sub(at) >= UncheckGrace
+}
+
+// lockedErr is the error for unchecking a locked-in completion under the
+// current UncheckPolicy
+func lockedErr() error {
+	if UncheckPolicy == UncheckLocked {
+		return ErrCompletionFinal
+	}
+	return ErrCompletionLocked
+}
+
+// keepEXP records that the habit's completion on day was unchecked under
+// UncheckNoRefund, keeping the exp it paid. Caller holds u.mu.
+func (u *UserData) keepEXP(day, habitID string, exp int) {
+	if u.KeptEXP == nil {
+		u.KeptEXP = make(map[string]map[string]int)
+	}
+	if u.KeptEXP[day] == nil {
+		u.KeptEXP[day] = make(map[string]int)
+	}
+	u.KeptEXP[day][habitID] = exp
+}
+
+// takeKeptEXP returns and forgets the EXP an earlier unchecked completion of
+// the habit on day kept; ok is false if none did. Caller holds u.mu.
+func (u *UserData) takeKeptEXP(day, habitID string) (exp int, ok bool) {
+	exp, ok = u.KeptEXP[day][habitID]
+	if ok {
+		delete(u.KeptEXP[day], habitID)
+		if len(u.KeptEXP[day]) == 0 {
+			delete(u.KeptEXP, day)
+		}
+	}
+	return exp, ok
+}
+
+// recordCompletedAt notes when the habit was completed on day; a zero t
+// forgets it. Caller holds u.mu.
+func (u *UserData) recordCompletedAt(day, habitID string, t time.Time) {
+	if t.IsZero() {
+		delete(u.CompletedAt[day], habitID)
+		if len(u.CompletedAt[day]) == 0 {
+			delete(u.CompletedAt, day)
+		}
+		return
+	}
+	if u.CompletedAt == nil {
+		u.CompletedAt = make(CompletionTimes)
+	}
+	if u.CompletedAt[day] == nil {
+		u.CompletedAt[day] = make(map[string]time.Time)
+	}
+	u.CompletedAt[day][habitID] = t
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUncheckPolicy(t *testing.T) {
+	tests := []struct {
+		policy      string
+		afterUndo   int // EXP after checking then unchecking
+		previewRedo int
+		afterRedo   int
+	}{
+		{UncheckRefund, 0, EXPPerQuest, EXPPerQuest},
+		{UncheckNoRefund, EXPPerQuest, 0, EXPPerQuest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			setFor(t, &UncheckPolicy, tt.policy)
+			u := newUser("read")
+			id := u.Habits[0].ID
+			mustToggle(t, u, id, today(u, 0))
+			mustToggle(t, u, id, today(u, 0))
+			if u.EXP != tt.afterUndo {
+				t.Errorf("EXP after uncheck = %d, want %d", u.EXP, tt.afterUndo)
+			}
+			if p := u.PreviewCompletion(id); p.EXP != tt.previewRedo {
+				t.Errorf("preview of recheck = %+v, want %d EXP", p, tt.previewRedo)
+			}
+			mustToggle(t, u, id, today(u, 0))
+			if u.EXP != tt.afterRedo {
+				t.Errorf("EXP after recheck = %d, want %d", u.EXP, tt.afterRedo)
+			}
+		})
+	}
+}
+
+func TestUncheckLocked(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		grace  time.Duration
+		age    time.Duration // how long ago the completion was made; -1 for no recorded time
+		locked bool
+		err    error // unchecking it; nil when it unchecks
+	}{
+		{"locked, no grace, just made", UncheckLocked, 0, 0, true, ErrCompletionFinal},
+		{"locked, no grace, untimed", UncheckLocked, 0, -1, true, ErrCompletionFinal},
+		{"locked, within the grace", UncheckLocked, 10 * time.Minute, time.Minute, false, nil},
+		{"locked, past the grace", UncheckLocked, 10 * time.Minute, time.Hour, true, ErrCompletionFinal},
+		{"locked, grace but untimed", UncheckLocked, 10 * time.Minute, -1, true, ErrCompletionFinal},
+		{"refund, no grace", UncheckRefund, 0, time.Hour, false, nil},
+		{"refund, within the grace", UncheckRefund, 10 * time.Minute, time.Minute, false, nil},
+		{"refund, past the grace", UncheckRefund, 10 * time.Minute, time.Hour, true, ErrCompletionLocked},
+		{"refund, grace but untimed", UncheckRefund, 10 * time.Minute, -1, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFor(t, &UncheckPolicy, tt.policy)
+			setFor(t, &UncheckGrace, tt.grace)
+			u := newUser("read")
+			id, day := u.Habits[0].ID, today(u, 0)
+			mustToggle(t, u, id, day)
+			if tt.age < 0 {
+				u.recordCompletedAt(day, id, time.Time{})
+			} else {
+				u.CompletedAt[day][id] = time.Now().Add(-tt.age)
+			}
+			if got := u.LockedIn(id, day); got != tt.locked {
+				t.Errorf("LockedIn = %v, want %v", got, tt.locked)
+			}
+			_, _, err := u.ToggleToday(id)
+			if err != tt.err {
+				t.Fatalf("uncheck error = %v, want %v", err, tt.err)
+			}
+			if done := u.CompletedToday(id); done != tt.locked {
+				t.Errorf("completed after uncheck = %v, want %v", done, tt.locked)
+			}
+			if !tt.locked && u.EXP != 0 {
+				t.Errorf("EXP after uncheck = %d, want the completion refunded", u.EXP)
+			}
+			if tt.err == ErrCompletionFinal && !errors.Is(tt.err, ErrCompletionLocked) {
+				t.Error("ErrCompletionFinal doesn't match ErrCompletionLocked")
+			}
+		})
+	}
+}
+
+func TestUncheckCostsLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		exp    int // before the completion
+		want   bool
+	}{
+		{"stays in the level", UncheckRefund, 150, false},
+		{"drops a level", UncheckRefund, 195, true},
+		{"no refund", UncheckNoRefund, 195, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFor(t, &UncheckPolicy, tt.policy)
+			u := newUser("read", "run")
+			u.EXP, u.Level = tt.exp, levelForEXP(tt.exp)
+			mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+			if got := u.UncheckCostsLevel(u.Habits[0].ID, today(u, 0)); got != tt.want {
+				t.Errorf("UncheckCostsLevel = %v, want %v", got, tt.want)
+			}
+			if u.UncheckCostsLevel(u.Habits[1].ID, today(u, 0)) {
+				t.Error("an open quest can't cost a level")
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// DemoUser names a read-only showcase account; "" disables it. Anyone can
+// log in as it with any password. It is seeded fresh on every login and
+// never written to disk, so changes made during a demo vanish with the
+// session.
+var DemoUser = ""
+
+// demoDays is how much made-up history the demo account starts with
+const demoDays = 30
+
+// demoLevel is the level the demo account starts at
+const demoLevel = 12
+
+// demoHabits are the demo account's quests, with how often each is skipped
+// in the seeded history (every nth day; 0 never)
+var demoHabits = []struct {
+	name, icon, note string
+	skipEvery        int
+}{
+	{"Morning workout", "💪", "30 minutes, any sport", 6},
+	{"Read 20 pages", "📖", "", 4},
+	{"Meditate", "🧘", "10 minutes before work", 9},
+	{"Drink 2L of water", "💧", "", 0},
+	{"Practice guitar", "🎵", "Scales, then one song", 5},
+}
+
+// IsDemo reports whether username is the demo account. An account saved
+// under the same name isn't: it keeps its own password and data rather than
+// opening for anyone. The server won't start with DemoUser naming one, but
+// a record could still be put in place while it runs.
+func IsDemo(username string) bool {
+	return DemoUser != "" && username == DemoUser && !UserExists(username)
+}
+
+// DemoUserData builds a fresh demo account: a mid-game hunter with a month
+// of history, a current streak and the last few days perfect
+func DemoUserData() *UserData {
+	now := time.Now()
+	u := &UserData{
+		Username:         DemoUser,
+		Level:            demoLevel,
+		EXP:              (demoLevel-DefaultLevel)*EXPPerLevel + EXPPerLevel/3,
+		STR:              baseStats + demoLevel + 14,
+		VIT:              baseStats + demoLevel + 9,
+		AGI:              baseStats + demoLevel + 6,
+		INT:              baseStats + demoLevel + 15,
+		DailyCompletions: make(map[string]map[string]bool),
+		DayResetHour:     DefaultResetHour,
+		Keymap:           KeymapDefault,
+		Theme:            ThemeSystemBlue,
+		SortMode:         SortManual,
+		CompleteKey:      CompleteKeySpace,
+		CreatedAt:        now.AddDate(0, 0, -demoDays),
+		TutorialSeen:     true,
+		Shields:          1,
+	}
+	u.Level = levelForEXP(u.EXP)
+	start := now.AddDate(0, 0, -demoDays)
+	for i, d := range demoHabits {
+		created := start.Add(time.Duration(i) * time.Minute)
+		u.Habits = append(u.Habits, Habit{
+			ID:        fmt.Sprintf("h_%d", created.UnixNano()),
+			Name:      d.name,
+			Note:      d.note,
+			Icon:      d.icon,
+			CreatedAt: created,
+		})
+	}
+	today := u.TodayKey()
+	for day := demoDays; day >= 1; day-- {
+		key := addDays(today, -day)
+		completions := make(map[string]bool)
+		for i, d := range demoHabits {
+			// The last week is perfect so the demo shows a live streak
+			if day > 7 && d.skipEvery > 0 && day%d.skipEvery == 0 {
+				continue
+			}
+			completions[u.Habits[i].ID] = true
+		}
+		u.DailyCompletions[key] = completions
+		u.creditEXP(key, len(completions)*EXPPerQuest)
+	}
+	// A couple of today's quests are already done
+	u.DailyCompletions[today] = map[string]bool{u.Habits[0].ID: true, u.Habits[3].ID: true}
+	u.creditEXP(today, 2*EXPPerQuest)
+	u.RecomputeStreak()
+	return u
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDemoAccount(t *testing.T) {
+	setFor(t, &DemoUser, "demo")
+	events := captureAudit(t)
+	u, err := AuthUser("Demo", "any password at all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Username != "demo" || u.CurrentStreak < 7 || len(u.Habits) != len(demoHabits) {
+		t.Errorf("demo account: %s, streak %d, %d quests", u.Username, u.CurrentStreak, len(u.Habits))
+	}
+	mustToggle(t, u, u.Habits[1].ID, u.TodayKey())
+	if err := SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	if UserExists("demo") {
+		t.Error("the demo account was written to disk")
+	}
+	if again, _ := AuthUser("demo", ""); again.CompletedToday(u.Habits[1].ID) {
+		t.Error("a demo session's change survived to the next login")
+	}
+	if _, err := GenerateAPIToken(u, "widget"); err == nil {
+		t.Error("the demo account got an API token")
+	}
+	if _, err := CreateUser("demo", testPassword); !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("registering the demo name: %v", err)
+	}
+	if len(*events) != 0 {
+		t.Errorf("demo events audited: %+v", *events)
+	}
+}
+
+func TestDemoNameOfExistingAccount(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	u := newUser("read")
+	u.Username = "demo"
+	if err := SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	setFor(t, &DemoUser, "demo")
+	if IsDemo("demo") {
+		t.Error("a saved account counts as the demo")
+	}
+	if _, err := AuthUser("demo", "any password at all"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password for the real account: %v, want it refused", err)
+	}
+	got, err := AuthUser("demo", testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Habits) != 1 || got.Habits[0].Name != "read" {
+		t.Errorf("logged in to %d quests, want the real account's", len(got.Habits))
+	}
+}
+
+func TestDemoIntegrity(t *testing.T) {
+	setFor(t, &DemoUser, "demo")
+	u, err := AuthUser("demo", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, why := u.VerifyIntegrity(); !ok {
+		t.Errorf("demo history doesn't explain its EXP: %s", why)
+	}
+}
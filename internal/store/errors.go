@@ -0,0 +1,43 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned (possibly wrapped) by account operations; match them with errors.Is
+var (
+	ErrUsernameRequired   = errors.New("username required")
+	ErrUserNotFound       = errors.New("unknown user")
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrUsernameTaken      = errors.New("username already taken")
+	ErrWeakPassword       = errors.New("password does not meet the policy")
+	ErrRegistrationClosed = errors.New("registration is closed")
+	ErrCompletionLocked   = errors.New("quest is locked in for today")
+	ErrAccountLocked      = errors.New("too many failed logins")
+	ErrQuestChained       = errors.New("quest is locked until its prerequisite is done")
+	ErrChainCycle         = errors.New("quest chain would loop back on itself")
+	ErrHabitLimit         = errors.New("quest limit reached")
+
+	// ErrCompletionFinal is UncheckLocked's lock; it matches ErrCompletionLocked too
+	ErrCompletionFinal = fmt.Errorf("%w: completions are final on this server", ErrCompletionLocked)
+)
+
+// weakPasswordError explains which password rule failed while still
+// matching ErrWeakPassword
+type weakPasswordError string
+
+func (e weakPasswordError) Error() string { return string(e) }
+
+func (e weakPasswordError) Is(target error) bool { return target == ErrWeakPassword }
+
+// credentialsError is a failed login that names its cause (ErrUserNotFound
+// or ErrInvalidPassword) while still matching ErrInvalidCredentials
+type credentialsError struct{ err error }
+
+func (e credentialsError) Error() string { return e.err.Error() }
+
+func (e credentialsError) Unwrap() error { return e.err }
+
+func (e credentialsError) Is(target error) bool { return target == ErrInvalidCredentials }
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// LeaderboardIntegrity leaves users whose EXP fails VerifyIntegrity off the
+// leaderboard. Set by SYSTEM_LEADERBOARD_INTEGRITY; on by default.
+var LeaderboardIntegrity = true
+
+// integrityWarned remembers which users LoadUser already warned about, so a
+// leaderboard that loads everyone doesn't repeat the warning each time
+var integrityWarned sync.Map
+
+// VerifyIntegrity reports whether u's EXP could have been earned: no more
+// than each recorded completion paid (at most MaxQuestEXP, plus SpotlightBonus
+// on the spotlight) plus every bonus that may have been paid. EXP spent or
+// lost (shields, unchecks, hardcore penalties, prestige) only lowers it, so
+// anything under the cap passes. If not, why explains the gap.
+func (u *UserData) VerifyIntegrity() (ok bool, why string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if most := u.maxEarnableEXP(); u.EXP > most {
+		return false, fmt.Sprintf("%d EXP but history accounts for at most %d", u.EXP, most)
+	}
+	return true, ""
+}
+
+// maxEarnableEXP is the most EXP u's history could have paid. The welcome
+// bonus leaves no completion behind, so it is always allowed for, and
+// neither does a completion unchecked under UncheckNoRefund, so what those
+// kept is added too. Caller holds u.mu.
+func (u *UserData) maxEarnableEXP() int {
+	most := WelcomeQuestBonus
+	for day, completions := range u.DailyCompletions {
+		for id, done := range completions {
+			if done {
+				most += min(u.earnedEXP(day, id), maxCompletionEXP)
+			}
+		}
+	}
+	for _, kept := range u.KeptEXP {
+		for _, exp := range kept {
+			most += min(max(exp, 0), maxCompletionEXP)
+		}
+	}
+	for _, ms := range Milestones {
+		if u.ClaimedMilestones[ms.Days] {
+			most += ms.EXP
+		}
+	}
+	return most
+}
+
+// warnIntegrity logs, once per user, a record whose EXP fails
+// VerifyIntegrity. Loading never fails on it.
+func (u *UserData) warnIntegrity() {
+	if ok, why := u.VerifyIntegrity(); !ok {
+		if _, seen := integrityWarned.LoadOrStore(u.Username, true); !seen {
+			log.Printf("store: %s looks hand-edited: %s", u.Username, why)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package store
+
+import "testing"
+
+func TestVerifyIntegrity(t *testing.T) {
+	u := newUser("read", "run")
+	mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+	mustToggle(t, u, u.Habits[1].ID, today(u, 0))
+	if ok, why := u.VerifyIntegrity(); !ok {
+		t.Errorf("EXP earned by completions fails the check: %s", why)
+	}
+}
+
+func TestVerifyIntegrityKeptEXP(t *testing.T) {
+	setFor(t, &UncheckPolicy, UncheckNoRefund)
+	u := newUser("read")
+	u.QuestEXP = MaxQuestEXP // more than the welcome bonus allowance
+	mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+	mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+	if ok, why := u.VerifyIntegrity(); !ok {
+		t.Errorf("EXP kept by a no-refund uncheck fails the check: %s", why)
+	}
+}
+
+func TestVerifyIntegritySpotlight(t *testing.T) {
+	u := newUser("read", "run")
+	u.QuestEXP = MaxQuestEXP
+	u.SetSpotlight(u.Habits[0].ID)
+	mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+	mustToggle(t, u, u.Habits[1].ID, today(u, 0))
+	if ok, why := u.VerifyIntegrity(); !ok {
+		t.Errorf("a spotlight completion at the highest quest EXP fails the check: %s", why)
+	}
+}
+
+func TestVerifyIntegrityHandEdited(t *testing.T) {
+	u := newUser("read")
+	mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+	u.EXP += 500
+	if ok, _ := u.VerifyIntegrity(); ok {
+		t.Error("hand-edited EXP passes the check")
+	}
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LeaderboardEntry is the public view of a user on the leaderboard
+type LeaderboardEntry struct {
+	Rank          int    `json:"rank"`
+	Username      string `json:"username"`
+	Level         int    `json:"level"`
+	EXP           int    `json:"exp"`
+	CurrentStreak int    `json:"current_streak"`
+}
+
+// Standings is a leaderboard page: the top entries plus, when the querying
+// user isn't among them, a small window of neighbours around their rank
+type Standings struct {
+	Top    []LeaderboardEntry `json:"top"`
+	Self   *LeaderboardEntry  `json:"self,omitempty"`
+	Around []LeaderboardEntry `json:"around,omitempty"`
+	Total  int                `json:"total"`
+	Season int                `json:"season,omitempty"` // Current season's number; 0 with seasons off
+}
+
+// LeaderboardFile is the leaderboard as published to a static file for a
+// web page
+type LeaderboardFile struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	Top         []LeaderboardEntry `json:"top"`
+	Total       int                `json:"total"`
+	Season      int                `json:"season,omitempty"`
+}
+
+// WriteLeaderboard writes the top n users (n <= 0 for all) as JSON to path.
+// The file is replaced atomically, so a web server never serves half of it.
+func WriteLeaderboard(path string, n int) error {
+	st, err := Leaderboard(n, "")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(LeaderboardFile{GeneratedAt: time.Now().UTC(), Top: st.Top, Total: st.Total, Season: st.Season}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// leaderboardWindow is how many neighbours to show on each side of the user
+const leaderboardWindow = 2
+
+// ListUsers returns the usernames of all stored users, walking every shard
+func ListUsers() ([]string, error) {
+	shards, err := os.ReadDir(DataDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, shard := range shards {
+		if !shard.IsDir() || !isShardDir(shard.Name()) {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(DataDir, shard.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+				continue
+			}
+			names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Leaderboard returns the top n users ordered by level, then EXP, then
+// username (n <= 0 returns every user), along with the rank of username.
+// With seasons on, only the current season's level and EXP count.
+func Leaderboard(n int, username string) (Standings, error) {
+	start, season := CurrentSeason(time.Now())
+	entries, err := rankedEntries(start)
+	if err != nil {
+		return Standings{}, err
+	}
+	return Standings{Top: entries, Total: len(entries), Season: season}.Page(n, username), nil
+}
+
+// Page cuts a full ranking, as Leaderboard(0, "") returns it, down to the
+// top n plus username's rank and neighbours, as Leaderboard(n, username)
+// would. It lets a caller load the ranking once and serve it to many users.
+func (st Standings) Page(n int, username string) Standings {
+	entries := st.Top
+	st.Self, st.Around = nil, nil
+	if n > 0 && len(entries) > n {
+		st.Top = entries[:n]
+	}
+	username = NormalizeUsername(username)
+	for i := range entries {
+		if entries[i].Username != username {
+			continue
+		}
+		self := entries[i]
+		st.Self = &self
+		if i >= len(st.Top) {
+			lo := max(i-leaderboardWindow, len(st.Top))
+			hi := min(i+leaderboardWindow+1, len(entries))
+			st.Around = entries[lo:hi]
+		}
+		break
+	}
+	return st
+}
+
+// rankedEntries loads every user and sorts them into leaderboard order.
+// Users who haven't logged in since the season that began at season
+// rank as they will once CheckSeason resets them.
+func rankedEntries(season time.Time) ([]LeaderboardEntry, error) {
+	names, err := ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]LeaderboardEntry, 0, len(names))
+	for _, name := range names {
+		u, err := LoadUser(name)
+		if err != nil {
+			continue // skip unreadable records rather than failing the whole board
+		}
+		if LeaderboardIntegrity {
+			if ok, _ := u.VerifyIntegrity(); !ok {
+				continue // EXP the history can't explain
+			}
+		}
+		entry := LeaderboardEntry{
+			Username:      u.Username,
+			Level:         u.Level,
+			EXP:           u.EXP,
+			CurrentStreak: u.CurrentStreak,
+		}
+		if u.seasonStale(season) {
+			entry.Level, entry.EXP = DefaultLevel, 0
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Level != b.Level {
+			return a.Level > b.Level
+		}
+		if a.EXP != b.EXP {
+			return a.EXP > b.EXP
+		}
+		return a.Username < b.Username
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries, nil
+}
+
+// PublicUserData is the part of a hunter's record anyone may see: progress
+// and stats, never credentials, settings or completion history
+type PublicUserData struct {
+	Username      string `json:"username"`
+	Level         int    `json:"level"`
+	Prestige      int    `json:"prestige"`
+	Title         string `json:"title,omitempty"`
+	EXP           int    `json:"exp"`
+	STR           int    `json:"str"`
+	VIT           int    `json:"vit"`
+	AGI           int    `json:"agi"`
+	INT           int    `json:"int"`
+	CurrentStreak int    `json:"current_streak"`
+	LongestStreak int    `json:"longest_streak"`
+	Quests        int    `json:"quests"`
+}
+
+// PublicProfile loads the public view of username's record
+func PublicProfile(username string) (*PublicUserData, error) {
+	username = NormalizeUsername(username)
+	if username == "" {
+		return nil, ErrUsernameRequired
+	}
+	if strings.ContainsAny(username, `/\`) {
+		return nil, fmt.Errorf("%w %q", ErrUserNotFound, username)
+	}
+	u, err := LoadUser(username)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w %q", ErrUserNotFound, username)
+		}
+		return nil, err
+	}
+	return u.Public(), nil
+}
+
+// Public returns the public view of u
+func (u *UserData) Public() *PublicUserData {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return &PublicUserData{
+		Username:      u.Username,
+		Level:         u.Level,
+		Prestige:      u.PrestigeCount,
+		Title:         u.Title,
+		EXP:           u.EXP,
+		STR:           u.STR,
+		VIT:           u.VIT,
+		AGI:           u.AGI,
+		INT:           u.INT,
+		CurrentStreak: u.CurrentStreak,
+		LongestStreak: u.LongestStreak,
+		Quests:        len(u.Habits),
+	}
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// saveHunters saves a hunter per level given, named h0, h1, ..., each with
+// a quest completed today
+func saveHunters(t *testing.T, levels ...int) []*UserData {
+	t.Helper()
+	var users []*UserData
+	for i, level := range levels {
+		u := newUser("read")
+		u.Username = fmt.Sprintf("h%d", i)
+		u.Level, u.EXP = level, (level-1)*EXPPerLevel
+		u.DailyCompletions[today(u, 0)] = map[string]bool{u.Habits[0].ID: true}
+		if err := SaveUser(u); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, u)
+	}
+	return users
+}
+
+// usernames lists the entries' usernames in order
+func usernames(entries []LeaderboardEntry) []string {
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Username)
+	}
+	return names
+}
+
+func TestLeaderboard(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	setFor(t, &LeaderboardIntegrity, false)
+	saveHunters(t, 1, 9, 3, 7, 5, 8, 2, 6, 4)
+	tests := []struct {
+		name   string
+		n      int
+		user   string
+		top    []string
+		rank   int
+		around []string
+	}{
+		{"top three", 3, "", []string{"h1", "h5", "h3"}, 0, nil},
+		{"in the top", 3, "H5", []string{"h1", "h5", "h3"}, 2, nil},
+		{"below the top", 3, "h2", []string{"h1", "h5", "h3"}, 7, []string{"h4", "h8", "h2", "h6", "h0"}},
+		{"near the top", 3, "h7", []string{"h1", "h5", "h3"}, 4, []string{"h7", "h4", "h8"}},
+		{"last", 3, "h0", []string{"h1", "h5", "h3"}, 9, []string{"h2", "h6", "h0"}},
+		{"everyone", 0, "h0", []string{"h1", "h5", "h3", "h7", "h4", "h8", "h2", "h6", "h0"}, 9, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := Leaderboard(tt.n, tt.user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := usernames(st.Top); !slices.Equal(got, tt.top) || st.Total != 9 {
+				t.Errorf("top = %q of %d, want %q of 9", got, st.Total, tt.top)
+			}
+			if rank := 0; st.Self != nil {
+				rank = st.Self.Rank
+				if rank != tt.rank {
+					t.Errorf("rank = %d, want %d", rank, tt.rank)
+				}
+			} else if tt.rank != 0 {
+				t.Errorf("no rank, want %d", tt.rank)
+			}
+			if got := usernames(st.Around); !slices.Equal(got, tt.around) {
+				t.Errorf("around = %q, want %q", got, tt.around)
+			}
+		})
+	}
+}
+
+func TestLeaderboardIntegrity(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	users := saveHunters(t, 1, 1)
+	users[1].EXP = 90 // one completion can't pay that
+	if err := SaveUser(users[1]); err != nil {
+		t.Fatal(err)
+	}
+	st, err := Leaderboard(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := usernames(st.Top); !slices.Equal(got, []string{"h0"}) {
+		t.Errorf("leaderboard = %q, want the hand-edited h1 left off", got)
+	}
+	setFor(t, &LeaderboardIntegrity, false)
+	if st, _ := Leaderboard(0, ""); st.Total != 2 {
+		t.Errorf("%d hunters with the check off, want 2", st.Total)
+	}
+}
+
+func TestLeaderboardSeason(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	setFor(t, &LeaderboardIntegrity, false)
+	setFor(t, &SeasonStart, time.Now().AddDate(0, 0, -1))
+	users := saveHunters(t, 5, 2)
+	current, _ := CurrentSeason(time.Now())
+	users[1].SeasonStart = current
+	if err := SaveUser(users[1]); err != nil {
+		t.Fatal(err)
+	}
+	st, err := Leaderboard(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := usernames(st.Top); !slices.Equal(got, []string{"h1", "h0"}) || st.Top[1].Level != DefaultLevel || st.Season != 1 {
+		t.Errorf("season standings = %+v, want h0 ranked from level 1 until they log in", st)
+	}
+}
+
+func TestStandingsPage(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	setFor(t, &LeaderboardIntegrity, false)
+	saveHunters(t, 1, 9, 3, 7, 5, 8, 2, 6, 4)
+	full, err := Leaderboard(0, "h5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []string{"", "h5", "h2", "h0", "nobody"} {
+		want, err := Leaderboard(3, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := full.Page(3, user)
+		if !slices.Equal(got.Top, want.Top) || !slices.Equal(got.Around, want.Around) || got.Total != want.Total {
+			t.Errorf("Page(3, %q) = %+v, want %+v", user, got, want)
+		}
+		if (got.Self == nil) != (want.Self == nil) || got.Self != nil && *got.Self != *want.Self {
+			t.Errorf("Page(3, %q) self = %v, want %v", user, got.Self, want.Self)
+		}
+	}
+}
+
+func TestWriteLeaderboard(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	setFor(t, &LeaderboardIntegrity, false)
+	saveHunters(t, 2, 4, 3)
+	path := filepath.Join(t.TempDir(), "public", "leaderboard.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLeaderboard(path, 2); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file LeaderboardFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if got := usernames(file.Top); !slices.Equal(got, []string{"h1", "h2"}) || file.Total != 3 || file.GeneratedAt.IsZero() {
+		t.Errorf("published %+v", file)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("temp files left beside the leaderboard: %v", entries)
+	}
+}
+
+func TestPublicProfile(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	saveHunters(t, 3)
+	p, err := PublicProfile(" H0 ")
+	if err != nil || p.Username != "h0" || p.Level != 3 || p.Quests != 1 {
+		t.Errorf("PublicProfile = %+v, %v", p, err)
+	}
+	for _, name := range []string{"nobody", "../h0"} {
+		if _, err := PublicProfile(name); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("PublicProfile(%q) = %v, want ErrUserNotFound", name, err)
+		}
+	}
+	if _, err := PublicProfile(""); !errors.Is(err, ErrUsernameRequired) {
+		t.Errorf("PublicProfile(\"\") = %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package store
+
+import "sync"
+
+// Live is a hunter's record held open in memory. Every SSH session and API
+// request for the same hunter gets the same Live from Share, so their changes
+// go into one UserData and no save writes over another's. Hold it locked
+// while reading or changing User, and Release it when done.
+type Live struct {
+	sync.Mutex
+	User *UserData
+	refs int // guarded by liveMu
+}
+
+var (
+	liveMu sync.Mutex
+	shared = map[string]*Live{} // by username
+)
+
+// Share returns the open record for u's hunter. When nobody has it open, it
+// is reloaded from disk, so a save made since u was loaded isn't lost; u is
+// used as is when that fails, as for a record not written yet. The demo
+// account isn't shared: each login keeps its own.
+func Share(u *UserData) *Live {
+	if IsDemo(u.Username) {
+		return &Live{User: u}
+	}
+	liveMu.Lock()
+	defer liveMu.Unlock()
+	l, ok := shared[u.Username]
+	if !ok {
+		if fresh, err := LoadUser(u.Username); err == nil {
+			u = fresh
+		}
+		l = &Live{User: u}
+		shared[u.Username] = l
+	}
+	l.refs++
+	return l
+}
+
+// Release gives up a Live from Share. The record is dropped from memory once
+// its last user releases it; save first.
+func (l *Live) Release() {
+	liveMu.Lock()
+	defer liveMu.Unlock()
+	name := l.User.Username
+	if shared[name] != l {
+		return // the demo account, or already released
+	}
+	l.refs--
+	if l.refs <= 0 {
+		delete(shared, name)
+	}
+}
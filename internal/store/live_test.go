@@ -0,0 +1,50 @@
+package store
+
+import "testing"
+
+func TestShare(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	u := newUser("read")
+	u.Username = "sharer"
+	if err := SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := newUser() // as loaded by another request
+	stale.Username = u.Username
+
+	first := Share(u)
+	second := Share(stale)
+	if first != second || first.User != second.User {
+		t.Fatal("two holders of the same hunter got different records")
+	}
+	first.User.AddHabit("write")
+	if err := SaveUser(first.User); err != nil {
+		t.Fatal(err)
+	}
+	first.Release()
+	if again := Share(u); again != second {
+		t.Error("record dropped while still held")
+	} else {
+		again.Release()
+	}
+	second.Release()
+
+	// Nobody holds it now, so the next Share reads the save back from disk
+	// rather than trusting the copy it's given
+	next := Share(u)
+	defer next.Release()
+	if next == first || len(next.User.Habits) != 2 {
+		t.Errorf("Share after release = %d quests, want the saved 2", len(next.User.Habits))
+	}
+}
+
+func TestShareDemo(t *testing.T) {
+	setFor(t, &DemoUser, "demo")
+	a, b := Share(DemoUserData()), Share(DemoUserData())
+	defer a.Release()
+	defer b.Release()
+	if a == b {
+		t.Error("demo logins share a record; each should keep its own")
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Account lockout: LockoutAttempts failed logins within LockoutWindow lock
+// the username for LockoutFor. LockoutAttempts 0 turns it off. Set from the
+// server config.
+var (
+	LockoutAttempts = 5
+	LockoutWindow   = 15 * time.Minute
+	LockoutFor      = 15 * time.Minute
+)
+
+// lockedError is a login refused because the account is locked out; it
+// matches ErrAccountLocked and says when to try again
+type lockedError struct{ left time.Duration }
+
+func (e lockedError) Error() string {
+	mins := int((e.left + time.Minute - 1) / time.Minute)
+	return fmt.Sprintf("temporarily locked after too many failed logins; try again in %d min", max(mins, 1))
+}
+
+func (e lockedError) Is(target error) bool { return target == ErrAccountLocked }
+
+// lockouts tracks failures in memory, so a restart unlocks everyone. Unknown
+// usernames are tracked too, so a lockout doesn't reveal which names exist.
+var lockouts = &lockoutTracker{failures: map[string][]time.Time{}, until: map[string]time.Time{}}
+
+type lockoutTracker struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time // recent failed logins, oldest first
+	until    map[string]time.Time   // locked usernames and when they unlock
+}
+
+// left returns how long username stays locked at now; 0 if it isn't
+func (t *lockoutTracker) left(username string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until, ok := t.until[username]
+	if !ok {
+		return 0
+	}
+	if !now.Before(until) {
+		delete(t.until, username)
+		return 0
+	}
+	return until.Sub(now)
+}
+
+// fail records a failed login and reports whether it locked username
+func (t *lockoutTracker) fail(username string, now time.Time) bool {
+	if LockoutAttempts <= 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune(now)
+	recent := append(t.failures[username], now)
+	if len(recent) < LockoutAttempts {
+		t.failures[username] = recent
+		return false
+	}
+	delete(t.failures, username)
+	t.until[username] = now.Add(LockoutFor)
+	return true
+}
+
+// reset forgets username's failures after a successful login
+func (t *lockoutTracker) reset(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, username)
+}
+
+// prune drops failures older than LockoutWindow and expired locks, so
+// guessed usernames don't pile up. Caller holds t.mu.
+func (t *lockoutTracker) prune(now time.Time) {
+	cutoff := now.Add(-LockoutWindow)
+	for name, times := range t.failures {
+		i := 0
+		for i < len(times) && !times[i].After(cutoff) {
+			i++
+		}
+		if i == len(times) {
+			delete(t.failures, name)
+		} else {
+			t.failures[name] = times[i:]
+		}
+	}
+	for name, until := range t.until {
+		if !now.Before(until) {
+			delete(t.until, name)
+		}
+	}
+}
+
+// LockoutLeft returns how long username stays locked out of logging in; 0
+// if it isn't
+func LockoutLeft(username string) time.Duration {
+	return lockouts.left(NormalizeUsername(username), time.Now())
+}
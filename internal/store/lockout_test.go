@@ -0,0 +1,90 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockoutTracker(t *testing.T) {
+	setFor(t, &LockoutAttempts, 3)
+	setFor(t, &LockoutWindow, 10*time.Minute)
+	setFor(t, &LockoutFor, 15*time.Minute)
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	tests := []struct {
+		name   string
+		logins []int // minutes after start of failed logins; negative for a success
+		check  int
+		locked bool
+	}{
+		{"under the limit", []int{0, 1}, 2, false},
+		{"locked", []int{0, 1, 2}, 3, true},
+		{"unlocks", []int{0, 1, 2}, 17, false},
+		{"outside the window", []int{0, 5, 11}, 12, false},
+		{"success clears failures", []int{0, 1, -2, 3}, 4, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &lockoutTracker{failures: map[string][]time.Time{}, until: map[string]time.Time{}}
+			for _, m := range tt.logins {
+				if m < 0 {
+					tr.reset("hunter")
+				} else {
+					tr.fail("hunter", at(m))
+				}
+			}
+			if left := tr.left("hunter", at(tt.check)); (left > 0) != tt.locked {
+				t.Errorf("locked for %s at minute %d, want locked %v", left, tt.check, tt.locked)
+			}
+			if tr.left("someone else", at(tt.check)) != 0 {
+				t.Error("another username locked")
+			}
+		})
+	}
+}
+
+func TestAuthUserLockout(t *testing.T) {
+	setFor(t, &LockoutAttempts, 2)
+	u := newUser()
+	u.Username = "lockme"
+	if err := SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	events := captureAudit(t)
+	for i := 0; i < LockoutAttempts; i++ {
+		if _, err := AuthUser("lockme", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failed login %d: %v", i+1, err)
+		}
+	}
+	if _, err := AuthUser("LockMe", testPassword); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("right password while locked: %v", err)
+	}
+	if LockoutLeft("lockme") <= 0 {
+		t.Error("LockoutLeft = 0 while locked")
+	}
+	if n := countAudited(*events, AuditLockout); n != 1 {
+		t.Errorf("lockout audited %d times", n)
+	}
+	lockouts.mu.Lock()
+	delete(lockouts.until, "lockme")
+	lockouts.mu.Unlock()
+	if _, err := AuthUser("lockme", testPassword); err != nil {
+		t.Errorf("login after the lock expired: %v", err)
+	}
+}
+
+func TestAuthUserLockoutOff(t *testing.T) {
+	setFor(t, &LockoutAttempts, 0)
+	u := newUser()
+	u.Username = "neverlocked"
+	if err := SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		AuthUser("neverlocked", "wrong")
+	}
+	if _, err := AuthUser("neverlocked", testPassword); err != nil {
+		t.Errorf("login with lockouts off: %v", err)
+	}
+}
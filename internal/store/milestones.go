@@ -0,0 +1,46 @@
+package store
+
+import "fmt"
+
+// Milestone is a streak length that earns a one-time reward
+type Milestone struct {
+	Days  int    `json:"days"`
+	EXP   int    `json:"exp"`
+	Title string `json:"title"`
+}
+
+// Milestones is the streak reward schedule, shortest streak first
+var Milestones = []Milestone{
+	{Days: 7, EXP: 50, Title: "Persistent Hunter"},
+	{Days: 30, EXP: 200, Title: "Iron Will"},
+	{Days: 100, EXP: 500, Title: "Unbreakable"},
+	{Days: 365, EXP: 1000, Title: "Shadow Monarch"},
+}
+
+// CheckStreakMilestones awards every milestone the current streak has reached
+// that wasn't claimed before and returns the new ones. Each milestone pays
+// out once per account, so a streak that breaks and recovers past the same
+// length earns nothing again. Call it after UpdateStreak or RecomputeStreak.
+func (u *UserData) CheckStreakMilestones() []Milestone {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var claimed []Milestone
+	for _, ms := range Milestones {
+		if u.CurrentStreak < ms.Days || u.ClaimedMilestones[ms.Days] {
+			continue
+		}
+		if u.ClaimedMilestones == nil {
+			u.ClaimedMilestones = make(map[int]bool)
+		}
+		u.ClaimedMilestones[ms.Days] = true
+		leveledUp := u.addEXP(ms.EXP)
+		u.creditEXP(u.TodayKey(), ms.EXP)
+		u.Title = ms.Title
+		Audit(u.Username, AuditEvent{Type: AuditMilestone, EXP: u.EXP, Level: u.Level, Streak: ms.Days, Detail: fmt.Sprintf("+%d EXP, %s", ms.EXP, ms.Title)})
+		if leveledUp {
+			Audit(u.Username, AuditEvent{Type: AuditLevelUp, EXP: u.EXP, Level: u.Level})
+		}
+		claimed = append(claimed, ms)
+	}
+	return claimed
+}
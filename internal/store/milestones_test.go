@@ -0,0 +1,49 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheckStreakMilestones(t *testing.T) {
+	tests := []struct {
+		name       string
+		streak     int
+		claimed    map[int]bool
+		exp        int
+		wantDays   []int
+		wantLevel  int
+		wantLevels int // level_up events audited
+	}{
+		{name: "short of the first", streak: 6, wantLevel: 1},
+		{name: "first milestone", streak: 7, wantDays: []int{7}, wantLevel: 1},
+		{name: "already claimed", streak: 7, claimed: map[int]bool{7: true}, wantLevel: 1},
+		{name: "two at once", streak: 30, wantDays: []int{7, 30}, wantLevel: 3, wantLevels: 1},
+		{name: "bonus levels up", streak: 7, exp: 90, wantDays: []int{7}, wantLevel: 2, wantLevels: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser()
+			u.CurrentStreak, u.ClaimedMilestones = tt.streak, tt.claimed
+			u.EXP = tt.exp
+			events := captureAudit(t)
+			got := u.CheckStreakMilestones()
+			var days []int
+			for _, ms := range got {
+				days = append(days, ms.Days)
+			}
+			if !slices.Equal(days, tt.wantDays) {
+				t.Errorf("claimed %v, want %v", days, tt.wantDays)
+			}
+			if u.Level != tt.wantLevel {
+				t.Errorf("level = %d, want %d", u.Level, tt.wantLevel)
+			}
+			if n := countAudited(*events, AuditLevelUp); n != tt.wantLevels {
+				t.Errorf("level_up audited %d times, want %d", n, tt.wantLevels)
+			}
+			if again := u.CheckStreakMilestones(); len(again) != 0 {
+				t.Errorf("claimed %v a second time", again)
+			}
+		})
+	}
+}
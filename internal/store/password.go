@@ -0,0 +1,69 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicy is what CreateUser and SetPassword require of a password
+type PasswordPolicy struct {
+	MinLength   int  // in characters
+	MinClasses  int  // how many of lowercase, uppercase, digits and symbols must appear
+	BlockCommon bool // reject passwords on the commonPasswords list
+}
+
+// PasswordRules is the active policy. The default stays lax so private
+// instances keep working; public servers can tighten it at startup.
+var PasswordRules = PasswordPolicy{MinLength: 4}
+
+// commonPasswords are rejected (case-insensitively) when BlockCommon is set
+var commonPasswords = map[string]bool{
+	"password": true, "password1": true, "passw0rd": true, "123456": true,
+	"12345678": true, "123456789": true, "1234567890": true, "qwerty": true,
+	"qwerty123": true, "abc123": true, "111111": true, "123123": true,
+	"iloveyou": true, "letmein": true, "welcome": true, "monkey": true,
+	"dragon": true, "football": true, "baseball": true, "sunshine": true,
+	"princess": true, "admin": true, "admin123": true, "login": true,
+	"master": true, "shadow": true, "hunter": true, "hunter2": true,
+	"solo": true, "sololeveling": true, "system": true,
+}
+
+// Check returns an error describing the first rule password breaks; it
+// matches ErrWeakPassword
+func (p PasswordPolicy) Check(password string) error {
+	if n := len([]rune(password)); n < p.MinLength {
+		return weakPasswordError(fmt.Sprintf("password must be at least %d characters", p.MinLength))
+	}
+	if p.MinClasses > 1 && passwordClasses(password) < p.MinClasses {
+		return weakPasswordError(fmt.Sprintf("password must mix at least %d of: lowercase, uppercase, digits, symbols", p.MinClasses))
+	}
+	if p.BlockCommon && commonPasswords[strings.ToLower(password)] {
+		return weakPasswordError("that password is too common, choose another")
+	}
+	return nil
+}
+
+// passwordClasses counts the character classes present in password
+func passwordClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	n := 0
+	for _, has := range []bool{lower, upper, digit, symbol} {
+		if has {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	strict := PasswordPolicy{MinLength: 10, MinClasses: 3, BlockCommon: true}
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		reason   string // substring of the rejection; "" when accepted
+	}{
+		{"default", PasswordRules, "abcd", ""},
+		{"strict", strict, "Correct-horse-9", ""},
+		{"common allowed", PasswordPolicy{MinLength: 4}, "password1", ""},
+		{"too short", PasswordRules, "abc", "at least 4 characters"},
+		{"runes, not bytes", PasswordPolicy{MinLength: 4}, "ééé", "at least 4 characters"},
+		{"two classes", strict, "correcthorse9", "at least 3 of"},
+		{"common", PasswordPolicy{MinLength: 4, BlockCommon: true}, "Password1", "too common"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.password)
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("Check(%q) = %v, want it accepted", tt.password, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.reason) {
+				t.Fatalf("Check(%q) = %v, want it refused as %q", tt.password, err, tt.reason)
+			}
+			if !errors.Is(err, ErrWeakPassword) {
+				t.Errorf("error %v doesn't match ErrWeakPassword", err)
+			}
+		})
+	}
+}
+
+func TestSetPasswordPolicy(t *testing.T) {
+	setFor(t, &PasswordRules, PasswordPolicy{MinLength: 12})
+	u := newUser()
+	u.MustChangePassword = true
+	if err := u.SetPassword("short"); !errors.Is(err, ErrWeakPassword) || u.PasswordHash != testHash {
+		t.Errorf("SetPassword(short) = %v", err)
+	}
+	if !u.MustChangePassword {
+		t.Error("a refused password cleared MustChangePassword")
+	}
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// InQuietHours reports whether now falls in the user's quiet hours, when
+// reminders and near-reset urgency are not shown. Quests work as usual.
+func (u *UserData) InQuietHours(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return inQuietWindow(u.QuietStart, u.QuietEnd, now.Hour())
+}
+
+// inQuietWindow reports whether hour is in [start, end), wrapping past
+// midnight when end is before start (22-07 covers 22:00 to 06:59). An equal
+// start and end is an empty window: no quiet hours.
+func inQuietWindow(start, end, hour int) bool {
+	if start <= end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// UpdateQuietHours sets the quiet hours; start == end turns them off
+func (u *UserData) UpdateQuietHours(start, end int) error {
+	if start < 0 || start > 23 || end < 0 || end > 23 {
+		return fmt.Errorf("quiet hours must be 0-23, got %d-%d", start, end)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.QuietStart, u.QuietEnd = start, end
+	return nil
+}
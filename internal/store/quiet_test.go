@@ -0,0 +1,62 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInQuietHours(t *testing.T) {
+	tests := []struct {
+		start, end, hour int
+		want             bool
+	}{
+		{22, 7, 23, true},
+		{22, 7, 3, true},
+		{22, 7, 7, false},
+		{22, 7, 21, false},
+		{13, 15, 14, true},
+		{13, 15, 15, false},
+		{0, 0, 3, false}, // off
+	}
+	for _, tt := range tests {
+		u := newUser()
+		if err := u.UpdateQuietHours(tt.start, tt.end); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Date(2026, 10, 18, tt.hour, 30, 0, 0, time.Local)
+		if got := u.InQuietHours(now); got != tt.want {
+			t.Errorf("%02d-%02d at %02d:30 = %v, want %v", tt.start, tt.end, tt.hour, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateQuietHoursRange(t *testing.T) {
+	u := newUser()
+	for _, hours := range [][2]int{{-1, 7}, {22, 24}} {
+		if err := u.UpdateQuietHours(hours[0], hours[1]); err == nil {
+			t.Errorf("UpdateQuietHours(%d, %d) accepted", hours[0], hours[1])
+		}
+	}
+	if u.QuietStart != 0 || u.QuietEnd != 0 {
+		t.Errorf("rejected hours were set: %d-%d", u.QuietStart, u.QuietEnd)
+	}
+}
+
+func TestRemindersQuiet(t *testing.T) {
+	u := newUser("read")
+	hour := 9
+	u.Habits[0].ReminderHour = &hour
+	evening := time.Date(2026, 10, 18, 21, 0, 0, 0, time.Local)
+	if evening.After(time.Now()) {
+		evening = evening.AddDate(0, 0, -1)
+	}
+	if !u.ReminderDue(u.Habits[0], evening) {
+		t.Fatal("reminder not due outside quiet hours")
+	}
+	if err := u.UpdateQuietHours(20, 7); err != nil {
+		t.Fatal(err)
+	}
+	if u.ReminderDue(u.Habits[0], evening) {
+		t.Error("reminder due in quiet hours")
+	}
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HabitWeekStat is one habit's completion record for a week
+type HabitWeekStat struct {
+	HabitID   string `json:"habit_id"`
+	Name      string `json:"name"`
+	Completed int    `json:"completed"`
+	Eligible  int    `json:"eligible"` // days in the week the habit existed, up to today
+}
+
+// Rate returns the share of eligible days the habit was completed (0-1)
+func (s HabitWeekStat) Rate() float64 {
+	if s.Eligible == 0 {
+		return 0
+	}
+	return float64(s.Completed) / float64(s.Eligible)
+}
+
+// WeeklyReport summarizes one ISO week (Monday to Sunday) of quest days
+type WeeklyReport struct {
+	WeekStart     string          `json:"week_start"` // day key of Monday
+	WeekEnd       string          `json:"week_end"`   // day key of Sunday
+	DaysElapsed   int             `json:"days_elapsed"`
+	DaysCompleted int             `json:"days_completed"` // perfect days, as counted for the streak
+	TotalEXP      int             `json:"total_exp"`      // EXP earned in the week, bonuses included; the sum of EXPByDay
+	BestStreak    int             `json:"best_streak"`    // longest run of completed days inside the week
+	EXPByDay      []int           `json:"exp_by_day"`     // EXP earned each day, Monday first; 0 before DailyEXP was tracked
+	Habits        []HabitWeekStat `json:"habits"`
+}
+
+// WeeklySummary builds the report for the ISO week weekOffset weeks from the
+// current one (0 = this week, -1 = last week). Days after today are ignored,
+// and habits only count on days on or after the day they were added.
+func (u *UserData) WeeklySummary(weekOffset int) WeeklyReport {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	today, _ := time.ParseInLocation(DayKeyLayout, u.TodayKey(), time.Local)
+	// ISO weeks start on Monday
+	weekday := int(today.Weekday()+6) % 7
+	monday := today.AddDate(0, 0, -weekday+7*weekOffset)
+
+	report := WeeklyReport{
+		WeekStart: monday.Format(DayKeyLayout),
+		WeekEnd:   monday.AddDate(0, 0, 6).Format(DayKeyLayout),
+		Habits:    make([]HabitWeekStat, len(u.Habits)),
+		EXPByDay:  make([]int, 7),
+	}
+	for i, h := range u.Habits {
+		report.Habits[i] = HabitWeekStat{HabitID: h.ID, Name: h.Name}
+	}
+
+	streak := 0
+	for d := 0; d < 7; d++ {
+		day := monday.AddDate(0, 0, d)
+		if day.After(today) {
+			break
+		}
+		key := day.Format(DayKeyLayout)
+		report.DaysElapsed++
+		report.EXPByDay[d] = u.DailyEXP[key]
+		report.TotalEXP += u.DailyEXP[key]
+
+		completions := u.DailyCompletions[key]
+		for i, h := range u.Habits {
+			if created := u.habitCreatedDay(h); created != "" && created > key {
+				continue
+			}
+			report.Habits[i].Eligible++
+			if completions[h.ID] {
+				report.Habits[i].Completed++
+			}
+		}
+
+		if u.perfectDay(key) {
+			report.DaysCompleted++
+			streak++
+			if streak > report.BestStreak {
+				report.BestStreak = streak
+			}
+		} else {
+			streak = 0
+		}
+	}
+	return report
+}
+
+// habitCreatedDay derives the day key a habit was added on from its
+// time-based ID. Returns "" if the ID doesn't carry a timestamp.
+func (u *UserData) habitCreatedDay(h Habit) string {
+	nanos, err := strconv.ParseInt(strings.TrimPrefix(h.ID, "h_"), 10, 64)
+	if err != nil || !strings.HasPrefix(h.ID, "h_") {
+		return ""
+	}
+	return u.dayKeyAt(time.Unix(0, nanos))
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWeeklySummary(t *testing.T) {
+	u := newUser("read", "run")
+	read, run := u.Habits[0].ID, u.Habits[1].ID
+	monday := addDays(weekStart(u.TodayKey()), -7) // last week, all of it past
+	u.DailyCompletions[monday] = map[string]bool{read: true, run: true}
+	u.DailyCompletions[addDays(monday, 1)] = map[string]bool{read: true, run: true}
+	u.DailyCompletions[addDays(monday, 2)] = map[string]bool{read: true}
+	u.DailyCompletions[addDays(monday, 4)] = map[string]bool{read: true, run: true}
+
+	rep := u.WeeklySummary(-1)
+	if rep.WeekStart != monday || rep.WeekEnd != addDays(monday, 6) || rep.DaysElapsed != 7 {
+		t.Errorf("week %s to %s, %d days elapsed; want %s to %s, 7", rep.WeekStart, rep.WeekEnd, rep.DaysElapsed, monday, addDays(monday, 6))
+	}
+	if rep.DaysCompleted != 3 || rep.BestStreak != 2 {
+		t.Errorf("days completed %d, best streak %d; want 3 and 2", rep.DaysCompleted, rep.BestStreak)
+	}
+	want := []HabitWeekStat{
+		{HabitID: read, Name: "read", Completed: 4, Eligible: 7},
+		{HabitID: run, Name: "run", Completed: 3, Eligible: 7},
+	}
+	if !slices.Equal(rep.Habits, want) {
+		t.Errorf("habits = %+v, want %+v", rep.Habits, want)
+	}
+}
+
+func TestWeeklySummaryEXP(t *testing.T) {
+	u := newUser("read", "run")
+	day := today(u, 0)
+	mustToggle(t, u, u.Habits[0].ID, day)
+	mustToggle(t, u, u.Habits[1].ID, day)
+	u.UpdateStreak()
+	u.CurrentStreak = Milestones[0].Days
+	u.CheckStreakMilestones()
+
+	rep := u.WeeklySummary(0)
+	want := 2*EXPPerQuest + Milestones[0].EXP
+	if rep.TotalEXP != want {
+		t.Errorf("TotalEXP = %d, want %d with the milestone bonus", rep.TotalEXP, want)
+	}
+	sum := 0
+	for _, exp := range rep.EXPByDay {
+		sum += exp
+	}
+	if sum != rep.TotalEXP {
+		t.Errorf("EXPByDay sums to %d, TotalEXP is %d", sum, rep.TotalEXP)
+	}
+	if last := u.WeeklySummary(-1); last.TotalEXP != 0 || last.DaysElapsed != 7 {
+		t.Errorf("last week = %d EXP over %d days, want 0 over 7", last.TotalEXP, last.DaysElapsed)
+	}
+}
+
+func TestWeeklySummaryPerfectDays(t *testing.T) {
+	tests := []struct {
+		name     string
+		weekly   bool // make run a once-a-week quest, on track today
+		forfeit  bool
+		wantDays int
+	}{
+		{name: "one quest open", wantDays: 0},
+		{name: "weekly quest on track", weekly: true, wantDays: 1},
+		{name: "forfeited", weekly: true, forfeit: true, wantDays: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("read", "run")
+			day := today(u, 0)
+			runs := 0
+			if tt.weekly {
+				u.Habits[1].WeeklyTarget = 1
+				if day != weekStart(day) { // too late in the week to leave it all open
+					mustToggle(t, u, u.Habits[1].ID, today(u, -1))
+					runs++
+				}
+			}
+			if tt.forfeit {
+				u.ForfeitedDays = map[string]bool{day: true}
+			}
+			mustToggle(t, u, u.Habits[0].ID, day)
+			rep := u.WeeklySummary(0)
+			if rep.DaysCompleted != tt.wantDays || rep.BestStreak != tt.wantDays {
+				t.Errorf("days completed %d, best streak %d, want %d", rep.DaysCompleted, rep.BestStreak, tt.wantDays)
+			}
+			if got := rep.Habits[1].Completed; got != runs {
+				t.Errorf("run completed %d times, want %d", got, runs)
+			}
+			u.UpdateStreak()
+			if perfect := u.CurrentStreak > 0; perfect != (tt.wantDays > 0) {
+				t.Errorf("streak %d disagrees with the report", u.CurrentStreak)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// exportDir holds the copies ExportUser writes, outside the shard
+// directories so they're never mistaken for users
+func exportDir() string {
+	return filepath.Join(DataDir, "exports")
+}
+
+// ExportUser writes a copy of u's whole record to a timestamped file under
+// DataDir/exports and returns its path
+func ExportUser(u *UserData) (string, error) {
+	if IsDemo(u.Username) {
+		return "", fmt.Errorf("the demo account can't be exported")
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := os.MkdirAll(exportDir(), 0755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(exportDir(), u.Username+"-"+time.Now().Format("20060102-150405")+".json")
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// HardReset returns u to a new account's progress: no quests, history, EXP,
+// streaks, milestones, shields or past seasons, counting from the current
+// season. The login, API tokens and settings stay.
+func (u *UserData) HardReset() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.Habits = nil
+	u.Archived = nil
+	u.Level = DefaultLevel
+	u.EXP = 0
+	u.STR, u.VIT, u.AGI, u.INT = baseStats+DefaultLevel, baseStats+DefaultLevel, baseStats+DefaultLevel, baseStats+DefaultLevel
+	u.PrestigeCount = 0
+	u.CurrentStreak = 0
+	u.LongestStreak = 0
+	u.LastCompleteDay = ""
+	u.DailyCompletions = make(map[string]map[string]bool)
+	u.DailyEXP = nil
+	u.CompletionEXP = nil
+	u.CompletedAt = nil
+	u.KeptEXP = nil
+	u.SpotlightHabitID, u.SpotlightWeek = "", ""
+	u.ClaimedMilestones = nil
+	u.Title = ""
+	u.Shields = 0
+	u.ShieldedDays = nil
+	u.ForfeitedDays = nil
+	u.AnnouncedDay = ""
+	u.LifetimeEXP = 0
+	u.Seasons = nil
+	u.CreatedAt = time.Now()
+	u.SeasonStart, _ = CurrentSeason(u.CreatedAt)
+}
+
+// ResetWithExport exports u and, only if that succeeded, hard-resets it.
+// It returns the export's path; the caller saves u.
+func ResetWithExport(u *UserData) (string, error) {
+	path, err := ExportUser(u)
+	if err != nil {
+		return "", fmt.Errorf("export failed, nothing was reset: %w", err)
+	}
+	u.HardReset()
+	Audit(u.Username, AuditEvent{Type: AuditHardReset, Detail: filepath.Base(path)})
+	return path, nil
+}
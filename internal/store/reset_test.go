@@ -0,0 +1,39 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHardReset(t *testing.T) {
+	setFor(t, &SeasonStart, time.Now().AddDate(0, 0, -10))
+	setFor(t, &SeasonDays, 0)
+	u := newUser("read", "run")
+	mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+	u.UpdateStreak()
+	StartNewSeason(u)
+	mustToggle(t, u, u.Habits[1].ID, today(u, 0))
+	u.Shields = 2
+	u.Theme = ThemeHunterGreen
+
+	u.HardReset()
+	checks := []struct {
+		name string
+		ok   bool
+	}{
+		{"habits", len(u.Habits) == 0 && len(u.Archived) == 0},
+		{"level and EXP", u.Level == DefaultLevel && u.EXP == 0},
+		{"history", len(u.DailyCompletions) == 0 && len(u.DailyEXP) == 0},
+		{"streaks", u.CurrentStreak == 0 && u.LongestStreak == 0 && u.LastCompleteDay == ""},
+		{"shields", u.Shields == 0},
+		{"lifetime EXP", u.LifetimeEXP == 0 && u.TotalEXP() == 0},
+		{"past seasons", len(u.Seasons) == 0},
+		{"season start", u.SeasonStart.Equal(SeasonStart)},
+		{"settings kept", u.Theme == ThemeHunterGreen && u.PasswordHash == testHash},
+	}
+	for _, c := range checks {
+		if !c.ok {
+			t.Errorf("%s not reset as expected", c.name)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import "time"
+
+// A miss is a past quest day on which at least one habit existed and not
+// every habit existing that day was completed. Days before the first quest
+// existed are not misses, and a day covered by a streak shield still is one.
+// Today is never a miss since it isn't over.
+
+// isMiss reports whether day is a miss. Caller holds u.mu.
+func (u *UserData) isMiss(day string) bool {
+	existing := 0
+	for _, h := range u.Habits {
+		if created := u.habitCreatedDay(h); created != "" && created > day {
+			continue
+		}
+		existing++
+	}
+	return existing > 0 && !u.perfectDay(day)
+}
+
+// historyStart is the first day key worth scanning: account creation or the
+// earliest completion, whichever is earlier. "" when there is no history.
+// Caller holds u.mu.
+func (u *UserData) historyStart() string {
+	start := ""
+	if !u.CreatedAt.IsZero() {
+		start = u.dayKeyAt(u.CreatedAt)
+	}
+	for day, completions := range u.DailyCompletions {
+		for _, done := range completions {
+			if done && (start == "" || day < start) {
+				start = day
+			}
+		}
+	}
+	return start
+}
+
+// DaysSinceLastMiss counts the clean days since the most recent miss, up to
+// and excluding today. missed is false when the history has no miss at all;
+// days is then the length of that history.
+func (u *UserData) DaysSinceLastMiss() (days int, missed bool) {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	start := u.historyStart()
+	if start == "" || start >= today {
+		return 0, false
+	}
+	day, err := time.Parse(DayKeyLayout, today)
+	if err != nil {
+		return 0, false
+	}
+	for {
+		day = day.AddDate(0, 0, -1)
+		key := day.Format(DayKeyLayout)
+		if key < start {
+			return days, false
+		}
+		if u.isMiss(key) {
+			return days, true
+		}
+		days++
+	}
+}
+
+// BestComeback is the longest run of perfect days that began the day after
+// a miss; a run still going today counts, today included once it's perfect
+func (u *UserData) BestComeback() int {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	start := u.historyStart()
+	if start == "" {
+		return 0
+	}
+	day, err1 := time.Parse(DayKeyLayout, start)
+	end, err2 := time.Parse(DayKeyLayout, today)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	best, run := 0, 0
+	comeback, afterMiss := false, false
+	for ; !day.After(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format(DayKeyLayout)
+		switch {
+		case u.perfectDay(key):
+			if run == 0 {
+				comeback = afterMiss
+			}
+			run++
+			if comeback {
+				best = max(best, run)
+			}
+			afterMiss = false
+		case key < today && u.isMiss(key):
+			run, afterMiss = 0, true
+		default:
+			// Today in progress, or a day with no quests yet
+			run, afterMiss = 0, false
+		}
+	}
+	return best
+}
@@ -0,0 +1,34 @@
+package store
+
+import "testing"
+
+func TestResilience(t *testing.T) {
+	tests := []struct {
+		name      string
+		done      []int // days the quest was done, as offsets from today
+		sinceMiss int
+		missed    bool
+		comeback  int
+	}{
+		{"no history", nil, 0, true, 0},
+		{"never missed", []int{-6, -5, -4, -3, -2, -1}, 6, false, 0},
+		{"came back", []int{-6, -5, -3, -2, -1}, 3, true, 3},
+		{"came back through today", []int{-6, -5, -3, -2, -1, 0}, 3, true, 4},
+		{"missed yesterday", []int{-6, -4, -3, -2}, 0, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("read")
+			startedDaysAgo(u, 6)
+			for _, d := range tt.done {
+				u.DailyCompletions[today(u, d)] = map[string]bool{u.Habits[0].ID: true}
+			}
+			if days, missed := u.DaysSinceLastMiss(); days != tt.sinceMiss || missed != tt.missed {
+				t.Errorf("DaysSinceLastMiss() = %d, %v; want %d, %v", days, missed, tt.sinceMiss, tt.missed)
+			}
+			if got := u.BestComeback(); got != tt.comeback {
+				t.Errorf("BestComeback() = %d, want %d", got, tt.comeback)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// SeasonStart is when the first leaderboard season began; zero turns seasons
+// off. Set by SYSTEM_SEASON_START.
+var SeasonStart time.Time
+
+// SeasonDays is how long each season lasts; 0 makes SeasonStart a one-off
+// boundary. Set by SYSTEM_SEASON_DAYS.
+var SeasonDays = 0
+
+// SeasonRecord is a hunter's standing when a season ended
+type SeasonRecord struct {
+	Start time.Time `json:"start,omitzero"` // Zero for progress from before seasons
+	End   time.Time `json:"end"`
+	Level int       `json:"level"`
+	EXP   int       `json:"exp"`
+}
+
+// CurrentSeason returns when the season running at now began and its number,
+// counting from 1. Before SeasonStart, or with seasons off, it returns a zero
+// time and 0.
+func CurrentSeason(now time.Time) (start time.Time, number int) {
+	if SeasonStart.IsZero() || now.Before(SeasonStart) {
+		return time.Time{}, 0
+	}
+	if SeasonDays <= 0 {
+		return SeasonStart, 1
+	}
+	// Estimate, then settle on calendar days so DST doesn't move the boundary
+	n := int(now.Sub(SeasonStart).Hours()/24) / SeasonDays
+	for n > 0 && SeasonStart.AddDate(0, 0, n*SeasonDays).After(now) {
+		n--
+	}
+	for !SeasonStart.AddDate(0, 0, (n+1)*SeasonDays).After(now) {
+		n++
+	}
+	return SeasonStart.AddDate(0, 0, n*SeasonDays), n + 1
+}
+
+// TotalEXP is every EXP the user earned: past seasons plus this one
+func (u *UserData) TotalEXP() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.LifetimeEXP + u.EXP
+}
+
+// seasonStale reports whether u's level and EXP belong to a season before
+// the one that began at start. Caller holds u.mu.
+func (u *UserData) seasonStale(start time.Time) bool {
+	return !start.IsZero() && u.SeasonStart.Before(start)
+}
+
+// CheckSeason moves u into the current season if a boundary passed since
+// their progress was last counted, and returns the record of the season
+// that ended (nil if none did). Call at login, then save.
+func (u *UserData) CheckSeason() *SeasonRecord {
+	start, _ := CurrentSeason(time.Now())
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.seasonStale(start) {
+		return nil
+	}
+	if u.EXP == 0 && u.Level == DefaultLevel {
+		u.SeasonStart = start // nothing earned yet, so nothing to put on record
+		return nil
+	}
+	return u.startSeason(start, start) // the season ended at the boundary, not at this login
+}
+
+// StartNewSeason ends u's current season now, whatever the configured
+// boundaries: level and EXP go on record in u.Seasons and start over, with
+// the EXP added to LifetimeEXP. Stats return to their level-1 values plus
+// any prestige bonus; habits, history, streaks and prestige are kept. The
+// caller saves u.
+func StartNewSeason(u *UserData) SeasonRecord {
+	now := time.Now()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return *u.startSeason(now, now)
+}
+
+// startSeason archives u's season as ending at end and starts the one that
+// began at start. Caller holds u.mu.
+func (u *UserData) startSeason(start, end time.Time) *SeasonRecord {
+	rec := SeasonRecord{Start: u.SeasonStart, End: end, Level: u.Level, EXP: u.EXP}
+	u.Seasons = append(u.Seasons, rec)
+	u.LifetimeEXP += u.EXP
+	u.Level = DefaultLevel
+	u.EXP = 0
+	base := baseStats + DefaultLevel + u.PrestigeCount*PrestigeStatBonus
+	u.STR, u.VIT, u.AGI, u.INT = base, base, base, base
+	u.SeasonStart = start
+	Audit(u.Username, AuditEvent{Type: AuditSeason, Level: rec.Level, EXP: rec.EXP, Detail: fmt.Sprintf("season from %s", start.Format(time.DateOnly))})
+	return &rec
+}
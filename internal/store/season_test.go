@@ -0,0 +1,69 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCurrentSeason(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		name   string
+		start  time.Time
+		days   int
+		now    time.Time
+		begins time.Time
+		number int
+	}{
+		{"off", time.Time{}, 30, start, time.Time{}, 0},
+		{"before the first", start, 30, start.Add(-time.Hour), time.Time{}, 0},
+		{"first day", start, 30, start, start, 1},
+		{"one-off boundary", start, 0, start.AddDate(1, 0, 0), start, 1},
+		{"last moment of the first", start, 30, start.AddDate(0, 0, 30).Add(-time.Second), start, 1},
+		{"third", start, 30, start.AddDate(0, 0, 75), start.AddDate(0, 0, 60), 3},
+		{"across DST", start, 90, time.Date(2026, 4, 1, 0, 30, 0, 0, time.Local), start.AddDate(0, 0, 90), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFor(t, &SeasonStart, tt.start)
+			setFor(t, &SeasonDays, tt.days)
+			begins, number := CurrentSeason(tt.now)
+			if !begins.Equal(tt.begins) || number != tt.number {
+				t.Errorf("CurrentSeason(%s) = %s, %d; want %s, %d", tt.now, begins, number, tt.begins, tt.number)
+			}
+		})
+	}
+}
+
+func TestCheckSeason(t *testing.T) {
+	setFor(t, &SeasonStart, time.Now().AddDate(0, 0, -45))
+	setFor(t, &SeasonDays, 30)
+	current, _ := CurrentSeason(time.Now())
+
+	u := newUser("read")
+	u.SeasonStart = SeasonStart
+	u.Level, u.EXP, u.STR = 3, 250, 40
+	rec := u.CheckSeason()
+	if rec == nil || rec.Level != 3 || rec.EXP != 250 || !rec.End.Equal(current) {
+		t.Fatalf("CheckSeason() = %+v, want level 3 and 250 EXP on record, ended at %s", rec, current)
+	}
+	if u.Level != DefaultLevel || u.EXP != 0 || u.STR != baseStats+DefaultLevel || !u.SeasonStart.Equal(current) {
+		t.Errorf("after the season: level %d, %d EXP, STR %d, season %s", u.Level, u.EXP, u.STR, u.SeasonStart)
+	}
+	if u.TotalEXP() != 250 || len(u.Seasons) != 1 {
+		t.Errorf("lifetime EXP %d, %d seasons on record", u.TotalEXP(), len(u.Seasons))
+	}
+	if again := u.CheckSeason(); again != nil {
+		t.Errorf("second CheckSeason() = %+v, want nil", again)
+	}
+}
+
+func TestCheckSeasonNothingEarned(t *testing.T) {
+	setFor(t, &SeasonStart, time.Now().AddDate(0, 0, -45))
+	setFor(t, &SeasonDays, 30)
+	current, _ := CurrentSeason(time.Now())
+	fresh := newUser()
+	if rec := fresh.CheckSeason(); rec != nil || !fresh.SeasonStart.Equal(current) || len(fresh.Seasons) != 0 {
+		t.Errorf("a hunter with nothing earned got %+v, season %s", rec, fresh.SeasonStart)
+	}
+}
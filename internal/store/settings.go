@@ -0,0 +1,64 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Settings are the preferences the settings screen edits and saves together
+type Settings struct {
+	DayResetHour    int
+	Keymap          string
+	Theme           string
+	MuteBell        bool
+	HardcoreMode    bool
+	ShowTotalEXP    bool
+	HideNudge       bool
+	HideAlmostThere bool
+	QuietStart      int
+	QuietEnd        int
+	CompleteKey     string
+	QuestEXP        int
+	DateFormat      string
+}
+
+// UpdateSettings applies every field of s, or none of them: each is checked
+// as its own setter (UpdateKeymap, UpdateQuietHours, ...) would, and any that
+// fails leaves the record unchanged and comes back in the joined error
+func (u *UserData) UpdateSettings(s Settings) error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(s.DayResetHour >= 0 && s.DayResetHour <= 23, "reset hour must be between 0 and 23")
+	check(validKeymap(s.Keymap), "unknown keymap %q", s.Keymap)
+	check(validTheme(s.Theme), "unknown theme %q", s.Theme)
+	check(s.QuietStart >= 0 && s.QuietStart <= 23 && s.QuietEnd >= 0 && s.QuietEnd <= 23, "quiet hours must be 0-23, got %d-%d", s.QuietStart, s.QuietEnd)
+	check(validCompleteKey(s.CompleteKey), "unknown complete key %q", s.CompleteKey)
+	check(s.QuestEXP >= MinQuestEXP && s.QuestEXP <= MaxQuestEXP, "quest EXP must be %d-%d", MinQuestEXP, MaxQuestEXP)
+	check(validDateFormat(s.DateFormat), "unknown date format %q", s.DateFormat)
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.setDayResetHour(s.DayResetHour, time.Now())
+	u.Keymap = s.Keymap
+	u.Theme = s.Theme
+	u.MuteBell = s.MuteBell
+	u.HardcoreMode = s.HardcoreMode
+	u.ShowTotalEXP = s.ShowTotalEXP
+	u.HideNudge = s.HideNudge
+	u.HideAlmostThere = s.HideAlmostThere
+	u.QuietStart, u.QuietEnd = s.QuietStart, s.QuietEnd
+	u.CompleteKey = s.CompleteKey
+	u.QuestEXP = s.QuestEXP
+	if s.QuestEXP == EXPPerQuest {
+		u.QuestEXP = 0 // the default, as UpdateQuestEXP stores it
+	}
+	u.DateFormat = s.DateFormat
+	return nil
+}
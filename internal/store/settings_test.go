@@ -0,0 +1,75 @@
+package store
+
+import (
+	"strings"
+	"testing"
+)
+
+// validSettings differs from a new hunter's defaults in every field
+func validSettings() Settings {
+	return Settings{
+		DayResetHour: 0,
+		Keymap:       KeymapVim,
+		Theme:        ThemeHunterGreen,
+		MuteBell:     true,
+		QuietStart:   22,
+		QuietEnd:     7,
+		CompleteKey:  CompleteKeyEnter,
+		QuestEXP:     EXPPerQuest,
+		DateFormat:   DateFormatEU,
+	}
+}
+
+// wantSettingsRejected checks that UpdateSettings refuses s with an error
+// mentioning want and applies none of it
+func wantSettingsRejected(t *testing.T, s Settings, want string) {
+	t.Helper()
+	u := newUser("read")
+	if err := u.UpdateSettings(s); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("UpdateSettings error = %v, want one about %s", err, want)
+	}
+	if u.Keymap != KeymapDefault || u.Theme != ThemeSystemBlue || u.MuteBell || u.QuietStart != 0 {
+		t.Errorf("refused settings were partly applied: %s, %s, bell muted %v, quiet from %d", u.Keymap, u.Theme, u.MuteBell, u.QuietStart)
+	}
+}
+
+func TestUpdateSettings(t *testing.T) {
+	u := newUser("read")
+	if err := u.UpdateSettings(validSettings()); err != nil {
+		t.Fatal(err)
+	}
+	if u.Keymap != KeymapVim || u.Theme != ThemeHunterGreen || !u.MuteBell || u.QuietStart != 22 || u.QuietEnd != 7 ||
+		u.CompleteKey != CompleteKeyEnter || u.DateFormat != DateFormatEU || u.QuestEXP != 0 {
+		t.Errorf("UpdateSettings applied %+v", u)
+	}
+}
+
+func TestUpdateSettingsRejected(t *testing.T) {
+	s := validSettings()
+	s.DayResetHour = 24
+	wantSettingsRejected(t, s, "reset hour")
+
+	s = validSettings()
+	s.Keymap = "emacs"
+	wantSettingsRejected(t, s, "keymap")
+
+	s = validSettings()
+	s.Theme = "neon"
+	wantSettingsRejected(t, s, "theme")
+
+	s = validSettings()
+	s.QuietEnd = -1
+	wantSettingsRejected(t, s, "quiet hours")
+
+	s = validSettings()
+	s.CompleteKey = "x"
+	wantSettingsRejected(t, s, "complete key")
+
+	s = validSettings()
+	s.QuestEXP = MaxQuestEXP + 1
+	wantSettingsRejected(t, s, "quest EXP")
+
+	s = validSettings()
+	s.DateFormat = "klingon"
+	wantSettingsRejected(t, s, "date format")
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// User files live in shard directories named by a hash prefix of the
+// username, e.g. data/3f/alice.json, so no single directory grows huge.
+// shardPrefixLen hex digits give 256 shards.
+const shardPrefixLen = 2
+
+// shardOf returns the shard directory name for username
+func shardOf(username string) string {
+	sum := sha256.Sum256([]byte(username))
+	return hex.EncodeToString(sum[:])[:shardPrefixLen]
+}
+
+// isShardDir reports whether name looks like a shard directory
+func isShardDir(name string) bool {
+	if len(name) != shardPrefixLen {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil && strings.ToLower(name) == name
+}
+
+// MigrateFlatLayout moves user files (.json, .log, .log.1) left in the top of
+// DataDir by older versions into their shard directories, and returns how
+// many users were moved. A user whose sharded file already exists is left
+// alone and logged. It is safe to run on every start.
+func MigrateFlatLayout() (int, error) {
+	entries, err := os.ReadDir(DataDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	moved := 0
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		username := strings.TrimSuffix(e.Name(), ".json")
+		dest := userPath(username)
+		if _, err := os.Stat(dest); err == nil {
+			log.Printf("store: not migrating %s: %s already exists", e.Name(), dest)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return moved, err
+		}
+		// Move the logs first so a crash never leaves a sharded user
+		// whose history is still in the flat layout
+		oldLog := filepath.Join(DataDir, username+".log")
+		for _, suffix := range []string{"", ".1"} {
+			err := os.Rename(oldLog+suffix, auditPath(username)+suffix)
+			if err != nil && !os.IsNotExist(err) {
+				return moved, err
+			}
+		}
+		if err := os.Rename(filepath.Join(DataDir, e.Name()), dest); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
+}
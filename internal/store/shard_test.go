@@ -0,0 +1,96 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUserPathSharded(t *testing.T) {
+	tests := []struct {
+		username string
+		file     string
+	}{
+		{"alice", "alice.json"},
+		{"caf\u00e9", "caf\u00e9.json"},
+		{"cafe\u0301", "caf\u00e9.json"}, // stored under its NFC form
+		{"..", "default.json"},
+	}
+	for _, tt := range tests {
+		path := userPath(tt.username)
+		shard := filepath.Base(filepath.Dir(path))
+		if filepath.Base(path) != tt.file || !isShardDir(shard) || filepath.Dir(filepath.Dir(path)) != DataDir {
+			t.Errorf("userPath(%q) = %s, want DataDir/<shard>/%s", tt.username, path, tt.file)
+		}
+	}
+}
+
+func TestSaveLoadSharded(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	u := newUser("read")
+	u.Username = "roundtrip"
+	u.Level, u.EXP = 2, 150
+	if err := SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(DataDir, "roundtrip.json")); !os.IsNotExist(err) {
+		t.Errorf("record saved in the flat layout: %v", err)
+	}
+	loaded, err := LoadUser("roundtrip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Level != 2 || loaded.EXP != 150 || len(loaded.Habits) != 1 {
+		t.Errorf("loaded %+v", loaded)
+	}
+	if !UserExists("roundtrip") {
+		t.Error("UserExists doesn't find the sharded record")
+	}
+}
+
+func TestIsShardDir(t *testing.T) {
+	tests := map[string]bool{"3f": true, "00": true, "3F": false, "3": false, "3fa": false, "zz": false, ".git": false}
+	for name, want := range tests {
+		if got := isShardDir(name); got != want {
+			t.Errorf("isShardDir(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMigrateFlatLayout(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	write := func(path, data string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(DataDir, "alice.json"), `{"username": "alice"}`)
+	write(filepath.Join(DataDir, "alice.log"), "{}\n")
+	write(filepath.Join(DataDir, "alice.log.1"), "{}\n")
+	write(filepath.Join(DataDir, "bob.json"), `{"username": "bob", "level": 2}`)
+	write(userPath("bob"), `{"username": "bob"}`) // already migrated: left alone
+	write(filepath.Join(DataDir, "notes.txt"), "not a user")
+
+	moved, err := MigrateFlatLayout()
+	if err != nil || moved != 1 {
+		t.Fatalf("MigrateFlatLayout() = %d, %v; want 1 user moved", moved, err)
+	}
+	for _, path := range []string{userPath("alice"), auditPath("alice"), auditPath("alice") + ".1", filepath.Join(DataDir, "bob.json"), filepath.Join(DataDir, "notes.txt")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("after migrating: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(DataDir, "alice.json")); !os.IsNotExist(err) {
+		t.Errorf("alice.json still in the flat layout: %v", err)
+	}
+	if u, err := LoadUser("bob"); err != nil || u.Level != DefaultLevel {
+		t.Errorf("sharded bob replaced by the flat copy: %+v, %v", u, err)
+	}
+	if moved, err := MigrateFlatLayout(); err != nil || moved != 0 {
+		t.Errorf("second run = %d, %v; want nothing to do", moved, err)
+	}
+}
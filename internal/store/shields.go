@@ -0,0 +1,49 @@
+package store
+
+import "fmt"
+
+// Streak shields protect a streak from missed days. Each missed day uses up
+// one shield; a gap longer than the shields held breaks the streak as usual
+// and keeps the shields. Shields are bought with EXP from the current level,
+// so buying one never costs a level.
+const (
+	ShieldCost = 50 // EXP per shield
+	MaxShields = 3
+)
+
+// BuyShield spends ShieldCost EXP on a streak shield
+func (u *UserData) BuyShield() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.Shields >= MaxShields {
+		return fmt.Errorf("you already hold the maximum of %d shields", MaxShields)
+	}
+	if floor := (u.Level - 1) * EXPPerLevel; u.EXP-ShieldCost < floor {
+		return fmt.Errorf("a shield costs %d EXP from your current level; you have %d", ShieldCost, u.EXP-floor)
+	}
+	u.EXP -= ShieldCost
+	u.Shields++
+	Audit(u.Username, AuditEvent{Type: AuditShield, EXP: u.EXP, Level: u.Level, Detail: fmt.Sprintf("bought, now %d", u.Shields)})
+	return nil
+}
+
+// useShields covers missed days with shields when there are enough, marking
+// the days shielded and moving LastCompleteDay to yesterday so the streak
+// carries on. Returns the shields used. Caller holds u.mu.
+func (u *UserData) useShields(missed int) int {
+	if missed < 1 || u.Shields < missed {
+		return 0
+	}
+	if u.ShieldedDays == nil {
+		u.ShieldedDays = make(map[string]bool)
+	}
+	last := u.LastCompleteDay
+	for i := 0; i < missed; i++ {
+		last = addDays(last, 1)
+		u.ShieldedDays[last] = true
+	}
+	u.Shields -= missed
+	u.LastCompleteDay = last
+	Audit(u.Username, AuditEvent{Type: AuditShield, Streak: u.CurrentStreak, Detail: fmt.Sprintf("used %d, %d left", missed, u.Shields)})
+	return missed
+}
@@ -0,0 +1,93 @@
+package store
+
+import "testing"
+
+func TestBuyShield(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   int
+		exp     int
+		shields int
+		ok      bool
+	}{
+		{"affordable", 1, 60, 0, true},
+		{"exactly affordable", 2, 150, 0, true},
+		{"would cost a level", 2, 140, 0, false},
+		{"at the maximum", 1, 90, MaxShields, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser()
+			u.Level, u.EXP, u.Shields = tt.level, tt.exp, tt.shields
+			err := u.BuyShield()
+			if (err == nil) != tt.ok {
+				t.Fatalf("BuyShield() = %v, want ok %v", err, tt.ok)
+			}
+			wantEXP, wantShields := tt.exp, tt.shields
+			if tt.ok {
+				wantEXP, wantShields = tt.exp-ShieldCost, tt.shields+1
+			}
+			if u.EXP != wantEXP || u.Shields != wantShields || u.Level != tt.level {
+				t.Errorf("after buying: level %d, %d EXP, %d shields", u.Level, u.EXP, u.Shields)
+			}
+		})
+	}
+}
+
+func TestShieldsKeepStreak(t *testing.T) {
+	u := newUser("read")
+	u.Shields = 2
+	for _, d := range []int{-5, -4, -3} {
+		mustToggle(t, u, u.Habits[0].ID, today(u, d))
+	}
+	u.RecomputeStreak()
+	if u.CurrentStreak != 0 {
+		t.Fatalf("streak before shielding = %d", u.CurrentStreak)
+	}
+	u.CurrentStreak, u.LastCompleteDay = 3, today(u, -3)
+	if _, shielded := u.CheckStreakBreak(); shielded != 2 {
+		t.Fatalf("shielded %d days, want 2", shielded)
+	}
+	if !u.ShieldedDays[today(u, -2)] || !u.ShieldedDays[today(u, -1)] || u.LastCompleteDay != today(u, -1) {
+		t.Errorf("shielded days %v, last complete %s", u.ShieldedDays, u.LastCompleteDay)
+	}
+	mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+	u.UpdateStreak()
+	if u.CurrentStreak != 4 {
+		t.Errorf("streak after shielding and completing today = %d, want 4", u.CurrentStreak)
+	}
+	u.RecomputeStreak()
+	if u.CurrentStreak != 4 {
+		t.Errorf("recomputed streak = %d, want shielded days skipped, not counted", u.CurrentStreak)
+	}
+}
+
+func TestCheckStreakBreakShielded(t *testing.T) {
+	tests := []struct {
+		name     string
+		shields  int
+		streak   int // afterwards
+		shielded int
+		penalty  bool
+	}{
+		{"enough shields", 2, 5, 2, false},
+		{"spare shields", 3, 5, 2, false},
+		{"too few shields", 1, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("read")
+			u.HardcoreMode = true
+			u.Shields = tt.shields
+			u.EXP = 80
+			u.CurrentStreak, u.LastCompleteDay = 5, today(u, -3) // two days missed
+			penalty, shielded := u.CheckStreakBreak()
+			if u.CurrentStreak != tt.streak || (penalty > 0) != tt.penalty {
+				t.Errorf("streak %d, penalty %d; want %d, a penalty %v", u.CurrentStreak, penalty, tt.streak, tt.penalty)
+			}
+			if shielded != tt.shielded || u.Shields != tt.shields-tt.shielded {
+				t.Errorf("shielded %d days with %d shields left, want %d", shielded, u.Shields, tt.shielded)
+			}
+		})
+	}
+}
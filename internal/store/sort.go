@@ -0,0 +1,82 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Quest list orderings. Only SortManual shows Habits as stored; the others
+// order a copy, so switching back restores the original order.
+const (
+	SortManual     = "manual"     // the order quests were added in
+	SortName       = "name"       // alphabetical, ignoring case
+	SortStatus     = "status"     // incomplete today above complete
+	SortDifficulty = "difficulty" // lowest completion rate first
+)
+
+// SortModes lists the sort modes in the order the sort key cycles them
+var SortModes = []string{SortManual, SortName, SortStatus, SortDifficulty}
+
+// SortedHabits returns the habits in the user's SortMode. Ties keep their
+// stored order.
+func (u *UserData) SortedHabits() []Habit {
+	u.mu.Lock()
+	habits := append([]Habit(nil), u.Habits...)
+	mode := u.SortMode
+	u.mu.Unlock()
+
+	switch mode {
+	case SortName:
+		sort.SliceStable(habits, func(i, j int) bool {
+			return strings.ToLower(habits[i].Name) < strings.ToLower(habits[j].Name)
+		})
+	case SortStatus:
+		done := make(map[string]bool, len(habits))
+		for _, h := range habits {
+			done[h.ID] = u.CompletedToday(h.ID)
+		}
+		sort.SliceStable(habits, func(i, j int) bool {
+			return !done[habits[i].ID] && done[habits[j].ID]
+		})
+	case SortDifficulty:
+		rate := make(map[string]float64, len(habits))
+		for _, h := range habits {
+			rate[h.ID] = u.HabitCompletionRate(h.ID)
+		}
+		sort.SliceStable(habits, func(i, j int) bool {
+			return rate[habits[i].ID] < rate[habits[j].ID]
+		})
+	}
+	return habits
+}
+
+// UpdateSortMode sets the quest list ordering preference
+func (u *UserData) UpdateSortMode(mode string) error {
+	if !validSortMode(mode) {
+		return fmt.Errorf("unknown sort mode %q", mode)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.SortMode = mode
+	return nil
+}
+
+// NextSortMode returns the sort mode after mode in SortModes, wrapping around
+func NextSortMode(mode string) string {
+	for i, m := range SortModes {
+		if m == mode {
+			return SortModes[(i+1)%len(SortModes)]
+		}
+	}
+	return SortModes[0]
+}
+
+func validSortMode(mode string) bool {
+	for _, m := range SortModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
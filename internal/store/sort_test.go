@@ -0,0 +1,57 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSortedHabits(t *testing.T) {
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{SortManual, []string{"run", "Read", "write"}},
+		{SortName, []string{"Read", "run", "write"}},
+		{SortStatus, []string{"run", "write", "Read"}},
+		{SortDifficulty, []string{"write", "Read", "run"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			u := newUser("run", "Read", "write")
+			run, read := u.Habits[0].ID, u.Habits[1].ID
+			for _, d := range []int{-3, -2, -1} {
+				mustToggle(t, u, run, today(u, d))
+			}
+			mustToggle(t, u, read, today(u, 0))
+			if err := u.UpdateSortMode(tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, h := range u.SortedHabits() {
+				names = append(names, h.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("SortedHabits() = %q, want %q", names, tt.want)
+			}
+			if u.Habits[0].ID != run {
+				t.Error("sorting reordered the stored quests")
+			}
+		})
+	}
+}
+
+func TestSortModes(t *testing.T) {
+	mode := SortManual
+	for range SortModes {
+		mode = NextSortMode(mode)
+	}
+	if mode != SortManual {
+		t.Errorf("cycling every mode ends on %q", mode)
+	}
+	if got := NextSortMode("bogus"); got != SortModes[0] {
+		t.Errorf("NextSortMode(bogus) = %q", got)
+	}
+	if err := newUser().UpdateSortMode("bogus"); err == nil {
+		t.Error("UpdateSortMode accepted an unknown mode")
+	}
+}
@@ -0,0 +1,88 @@
+package store
+
+// SpotlightBonus is the extra EXP the spotlight quest pays on top of the
+// hunter's quest EXP
+const SpotlightBonus = 10
+
+// maxCompletionEXP is the most one completion can pay: the highest quest
+// EXP on the spotlight
+const maxCompletionEXP = MaxQuestEXP + SpotlightBonus
+
+// The spotlight is one quest per ISO week (Monday to Sunday) that pays
+// SpotlightBonus when completed on a day of that week. CheckSpotlight picks
+// one in turn each new week; the hunter can choose another with
+// SetSpotlight. A spotlight from an earlier week has lapsed.
+
+// Spotlight returns this week's spotlight quest; ok is false when there is
+// none, its week is over, or the quest was deleted or archived
+func (u *UserData) Spotlight() (h Habit, ok bool) {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.SpotlightWeek != weekStart(today) {
+		return Habit{}, false
+	}
+	for _, q := range u.Habits {
+		if q.ID == u.SpotlightHabitID {
+			return q, true
+		}
+	}
+	return Habit{}, false
+}
+
+// SetSpotlight makes the habit this week's spotlight; "" turns it off until
+// next week
+func (u *UserData) SetSpotlight(habitID string) {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.SpotlightHabitID = habitID
+	u.SpotlightWeek = weekStart(today)
+	if name := u.habitName(habitID); name != "" {
+		Audit(u.Username, AuditEvent{Type: AuditSpotlight, HabitID: habitID, Habit: name, Detail: "week of " + u.SpotlightWeek})
+	}
+}
+
+// CheckSpotlight starts a new week's spotlight if the last one was set in an
+// earlier week: the quest after the previous spotlight in list order, or the
+// first quest. The welcome quest is never picked. It returns the new
+// spotlight; ok is false if the week hasn't changed or there are no quests.
+// Call at login, then save.
+func (u *UserData) CheckSpotlight() (h Habit, ok bool) {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	week := weekStart(today)
+	if u.SpotlightWeek == week {
+		return Habit{}, false
+	}
+	var quests []Habit
+	for _, q := range u.Habits {
+		if q.ID != WelcomeQuestID {
+			quests = append(quests, q)
+		}
+	}
+	if len(quests) == 0 {
+		return Habit{}, false
+	}
+	next := 0
+	for i, q := range quests {
+		if q.ID == u.SpotlightHabitID {
+			next = (i + 1) % len(quests)
+		}
+	}
+	h = quests[next]
+	u.SpotlightHabitID, u.SpotlightWeek = h.ID, week
+	Audit(u.Username, AuditEvent{Type: AuditSpotlight, HabitID: h.ID, Habit: h.Name, Detail: "week of " + week})
+	return h, true
+}
+
+// spotlightBonus is the bonus a completion of the habit on day earns: the
+// SpotlightBonus if it was the spotlight of day's week, else 0. Caller
+// holds u.mu.
+func (u *UserData) spotlightBonus(day, habitID string) int {
+	if habitID == "" || habitID != u.SpotlightHabitID || u.SpotlightWeek != weekStart(day) {
+		return 0
+	}
+	return SpotlightBonus
+}
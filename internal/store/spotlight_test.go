@@ -0,0 +1,66 @@
+package store
+
+import "testing"
+
+func TestCheckSpotlight(t *testing.T) {
+	u := newUser("read", "run")
+	u.Habits = append([]Habit{welcomeQuest()}, u.Habits...)
+	h, ok := u.CheckSpotlight()
+	if !ok || h.ID != u.Habits[1].ID {
+		t.Fatalf("first spotlight = %+v, %v; want read, skipping the welcome quest", h, ok)
+	}
+	if _, ok := u.CheckSpotlight(); ok {
+		t.Error("a second spotlight was picked in the same week")
+	}
+	u.SpotlightWeek = addDays(u.SpotlightWeek, -7)
+	if _, ok := u.Spotlight(); ok {
+		t.Error("last week's spotlight is still shown")
+	}
+	if h, ok := u.CheckSpotlight(); !ok || h.ID != u.Habits[2].ID {
+		t.Errorf("next week's spotlight = %+v, want run", h)
+	}
+	u.SpotlightWeek = addDays(u.SpotlightWeek, -7)
+	if h, ok := u.CheckSpotlight(); !ok || h.ID != u.Habits[1].ID {
+		t.Errorf("the week after = %+v, want read again", h)
+	}
+}
+
+func TestSpotlightBonus(t *testing.T) {
+	u := newUser("read", "run")
+	read, run := u.Habits[0].ID, u.Habits[1].ID
+	u.SpotlightHabitID, u.SpotlightWeek = read, "2026-10-12" // a Monday
+	tests := []struct {
+		day, habit string
+		want       int
+	}{
+		{"2026-10-12", read, SpotlightBonus},
+		{"2026-10-18", read, SpotlightBonus},
+		{"2026-10-19", read, 0},
+		{"2026-10-11", read, 0},
+		{"2026-10-14", run, 0},
+	}
+	for _, tt := range tests {
+		if got := u.spotlightBonus(tt.day, tt.habit); got != tt.want {
+			t.Errorf("spotlightBonus(%s, %s) = %d, want %d", tt.day, tt.habit, got, tt.want)
+		}
+	}
+}
+
+func TestSpotlightPays(t *testing.T) {
+	u := newUser("read", "run")
+	read, run := u.Habits[0].ID, u.Habits[1].ID
+	u.SetSpotlight(read)
+	if p := u.PreviewCompletion(read); p.EXP != EXPPerQuest+SpotlightBonus {
+		t.Errorf("spotlight preview = %d EXP", p.EXP)
+	}
+	mustToggle(t, u, read, today(u, 0))
+	mustToggle(t, u, run, today(u, 0))
+	if want := 2*EXPPerQuest + SpotlightBonus; u.EXP != want || u.EXPOn(today(u, 0)) != want {
+		t.Errorf("EXP = %d (%d today), want %d", u.EXP, u.EXPOn(today(u, 0)), want)
+	}
+	u.SetSpotlight(run) // moving the spotlight doesn't change what read paid
+	mustToggle(t, u, read, today(u, 0))
+	if u.EXP != EXPPerQuest {
+		t.Errorf("EXP after unchecking read = %d, want its bonus refunded too", u.EXP)
+	}
+}
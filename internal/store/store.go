@@ -1,103 +1,691 @@
 package store
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/unicode/norm"
 )
 
 const (
 	EXPPerQuest      = 10
 	EXPPerLevel      = 100
-	DataDir          = "data"
 	DefaultLevel     = 1
 	DefaultResetHour = 4 // 4 AM
+	MaxNoteRunes     = 80
 )
 
+// DataDir holds every user's record and audit log. Set it before any other
+// store call; the server takes it from its config.
+var DataDir = "data"
+
+// LevelCap is the highest reachable level; 0 means no cap. Users at the cap
+// can Prestige.
+var LevelCap = 0
+
+// RevealLoginErrors makes AuthUser say whether the username or the password
+// was wrong. Off by default: both fail as ErrInvalidCredentials, so a login
+// form can't be used to find out which accounts exist.
+var RevealLoginErrors = false
+
+// AllowRegister lets new accounts be created; when false CreateUser returns
+// ErrRegistrationClosed and only existing users can log in
+var AllowRegister = true
+
+// MaxHabits caps how many active quests a user can have; 0 means no cap.
+// Archived quests don't count.
+var MaxHabits = 0
+
+// PrestigeStatBonus is the permanent bonus to every stat per prestige
+const PrestigeStatBonus = 2
+
+// baseStats is every stat's value before level and prestige bonuses
+const baseStats = 10
+
+// BackfillDays is how many days back a quest can be completed after the fact
+var BackfillDays = 7
+
+// HardcorePenaltyPercent is the share of EXP lost when a hardcore streak
+// breaks after one missed day; it doubles for each further missed day.
+var HardcorePenaltyPercent = 5
+
+// Keymap presets for navigation keys
+const (
+	KeymapDefault = "default" // arrows and hjkl
+	KeymapVim     = "vim"     // hjkl only
+	KeymapArrows  = "arrows"  // arrows only, frees hjkl
+)
+
+// Keymaps lists the selectable keymaps in settings order
+var Keymaps = []string{KeymapDefault, KeymapVim, KeymapArrows}
+
+// Keys that complete the selected quest; the other one opens its detail view
+const (
+	CompleteKeySpace = "space"
+	CompleteKeyEnter = "enter"
+)
+
+// CompleteKeys lists the selectable completion keys in settings order
+var CompleteKeys = []string{CompleteKeySpace, CompleteKeyEnter}
+
+// Date formats for showing days and times. Only display changes: day keys
+// in DailyCompletions and the other maps stay in DayKeyLayout.
+const (
+	DateFormatISO = "iso" // 2026-10-18, 15:04
+	DateFormatUS  = "us"  // 10/18/2026, 3:04 PM
+	DateFormatEU  = "eu"  // 18/10/2026, 15:04
+)
+
+// DateFormats lists the selectable date formats in settings order
+var DateFormats = []string{DateFormatISO, DateFormatUS, DateFormatEU}
+
+// Color themes for the TUI
+const (
+	ThemeSystemBlue    = "system-blue" // the original Solo Leveling palette
+	ThemeShadowMonarch = "shadow-monarch"
+	ThemeHunterGreen   = "hunter-green"
+	ThemeMonochrome    = "monochrome"
+)
+
+// Themes lists the selectable themes in settings order
+var Themes = []string{ThemeSystemBlue, ThemeShadowMonarch, ThemeHunterGreen, ThemeMonochrome}
+
 type Habit struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
+	Note string `json:"note,omitempty"` // Short context shown in the quest detail view
+	Icon string `json:"icon,omitempty"` // One of HabitIcons, shown before the name
+
+	ReminderHour *int `json:"reminder_hour,omitempty"` // Hour (0-23) the quest is due by; nil for none
+	WeeklyTarget int  `json:"weekly_target,omitempty"` // Completions wanted per week, up to MaxWeeklyTarget; 0 for every day. See WeeklyProgress
+
+	Color     string `json:"color,omitempty"`      // One of HabitColors, used for the name in the quest list; "" for none
+	DependsOn string `json:"depends_on,omitempty"` // ID of the quest that unlocks this one each day; see ChainLocked
+
+	CreatedAt  time.Time `json:"created_at"`           // Start of the completion-rate window
+	RestoredAt time.Time `json:"restored_at,omitzero"` // Last restore from the archive; restarts the missed-day count
 }
 
+// HabitIcons are the icons offered in the add/edit form, in picker order.
+// All are double-width emoji so quest names stay aligned.
+var HabitIcons = []string{"🎯", "💪", "🏃", "📖", "🧘", "💧", "🥗", "💤", "🧠", "🎵", "🧹", "💰"}
+
+// DefaultHabitIcon is given to new quests and to quests saved before icons existed
+var DefaultHabitIcon = HabitIcons[0]
+
+// HabitColors are the quest colors offered in the add/edit form, in picker
+// order. "" is no color, the default and what quests saved before colors
+// existed get.
+var HabitColors = []string{"", "red", "orange", "yellow", "green", "cyan", "blue", "purple", "pink"}
+
 type UserData struct {
-	Username         string                     `json:"username"`
-	PasswordHash     string                     `json:"password_hash"`
-	Habits           []Habit                    `json:"habits"`
-	Level            int                        `json:"level"`
-	EXP              int                        `json:"exp"`
-	STR              int                        `json:"str"`               // Strength
-	VIT              int                        `json:"vit"`               // Vitality
-	AGI              int                        `json:"agi"`               // Agility
-	INT              int                        `json:"int"`               // Intelligence
-	CurrentStreak    int                        `json:"current_streak"`    // Days in a row completing all quests
-	LongestStreak    int                        `json:"longest_streak"`    // Personal best streak
-	LastCompleteDay  string                     `json:"last_complete_day"` // Last day all quests completed
-	DailyCompletions map[string]map[string]bool `json:"daily_completions"`
-	DayResetHour     int                        `json:"day_reset_hour"` // Hour (0-23) when daily quests reset
-	mu               sync.Mutex                 `json:"-"`
+	Username           string                     `json:"username"`
+	PasswordHash       string                     `json:"password_hash"`
+	Habits             []Habit                    `json:"habits"`
+	Level              int                        `json:"level"`
+	EXP                int                        `json:"exp"`
+	STR                int                        `json:"str"`               // Strength
+	VIT                int                        `json:"vit"`               // Vitality
+	AGI                int                        `json:"agi"`               // Agility
+	INT                int                        `json:"int"`               // Intelligence
+	PrestigeCount      int                        `json:"prestige"`          // Times the user reset from the level cap
+	CurrentStreak      int                        `json:"current_streak"`    // Days in a row completing all quests
+	LongestStreak      int                        `json:"longest_streak"`    // Personal best streak
+	LastCompleteDay    string                     `json:"last_complete_day"` // Last day all quests completed
+	DailyCompletions   map[string]map[string]bool `json:"daily_completions"`
+	DailyEXP           map[string]int             `json:"daily_exp,omitempty"`      // EXP earned from quests and bonuses per day key; empty before this was tracked
+	QuestEXP           int                        `json:"quest_exp,omitempty"`      // EXP per completed quest chosen by the user; 0 for EXPPerQuest
+	CompletionEXP      map[string]map[string]int  `json:"completion_exp,omitempty"` // EXP paid for completions that didn't pay EXPPerQuest, by day then habit ID
+	CompletedAt        CompletionTimes            `json:"completed_at,omitempty"`   // When each completion was made; see UncheckGrace
+	DayResetHour       int                        `json:"day_reset_hour"`           // Hour (0-23) when daily quests reset
+	Keymap             string                     `json:"keymap"`                   // Navigation key preset (KeymapDefault, ...)
+	Theme              string                     `json:"theme"`                    // Color theme (ThemeSystemBlue, ...)
+	SortMode           string                     `json:"sort_mode"`                // Quest list ordering (SortManual, ...)
+	CompleteKey        string                     `json:"complete_key"`             // Key that completes a quest (CompleteKeySpace, ...)
+	DateFormat         string                     `json:"date_format,omitempty"`    // How dates and times are shown (DateFormatISO, ...); "" for ISO
+	MuteBell           bool                       `json:"mute_bell"`                // Don't ring the terminal bell on level-up
+	ShowTotalEXP       bool                       `json:"show_total_exp,omitempty"` // Show total EXP toward the next level instead of EXP within the level
+	HideNudge          bool                       `json:"hide_nudge,omitempty"`     // Don't highlight the last quest left for a perfect day
+	SelectedHabit      string                     `json:"selected_habit,omitempty"` // Quest under the cursor when last seen; restored at login
+	HardcoreMode       bool                       `json:"hardcore_mode"`            // Lose EXP when a streak breaks
+	QuietStart         int                        `json:"quiet_start,omitempty"`    // Hour (0-23) quiet hours begin; equal to QuietEnd for none
+	QuietEnd           int                        `json:"quiet_end,omitempty"`      // Hour (0-23) quiet hours end, exclusive
+	CreatedAt          time.Time                  `json:"created_at"`               // Zero for accounts made before this was tracked
+	TutorialSeen       bool                       `json:"tutorial_seen"`
+	MustChangePassword bool                       `json:"must_change_password,omitempty"` // Set by an admin reset; forces a new password at next login
+	ClaimedMilestones  map[int]bool               `json:"claimed_milestones,omitempty"`   // Streak milestones already rewarded, by days
+	Title              string                     `json:"title,omitempty"`                // From the highest claimed milestone
+	Shields            int                        `json:"shields,omitempty"`              // Streak shields held, up to MaxShields
+	Archived           []Habit                    `json:"archived,omitempty"`             // Habits set aside; their history stays in DailyCompletions
+	ShieldedDays       map[string]bool            `json:"shielded_days,omitempty"`        // Missed days a shield covered
+	ForfeitedDays      map[string]bool            `json:"forfeited_days,omitempty"`       // Days an open quest was deleted on; they can't be perfect days
+	APITokens          []TokenInfo                `json:"api_tokens,omitempty"`           // Hashed tokens for the HTTP API
+	HideAlmostThere    bool                       `json:"hide_almost_there,omitempty"`    // Don't flag being one quest from the next level
+	KeptEXP            map[string]map[string]int  `json:"kept_exp,omitempty"`             // EXP kept by completions unchecked under UncheckNoRefund, by day then habit ID
+	AnnouncedDay       string                     `json:"announced_day,omitempty"`        // Last day audited as AuditDayComplete, so a recheck doesn't announce it again
+
+	SpotlightHabitID string `json:"spotlight_habit,omitempty"` // Quest paying SpotlightBonus in SpotlightWeek; see Spotlight
+	SpotlightWeek    string `json:"spotlight_week,omitempty"`  // Day key of the Monday starting the spotlight's week
+
+	SeasonStart time.Time      `json:"season_start,omitzero"`  // Start of the leaderboard season Level and EXP count toward; see CheckSeason
+	LifetimeEXP int            `json:"lifetime_exp,omitempty"` // EXP earned in seasons that have ended; TotalEXP adds this season's
+	Seasons     []SeasonRecord `json:"seasons,omitempty"`      // Standing at the end of each past season, oldest first
+	mu          sync.Mutex     `json:"-"`
 }
 
+// DayKeyLayout is the time layout of DailyCompletions keys
+const DayKeyLayout = "2006-01-02"
+
 func (u *UserData) TodayKey() string {
-	now := time.Now()
-	// If current time is before reset hour, use previous calendar day
-	if now.Hour() < u.DayResetHour {
-		now = now.Add(-24 * time.Hour)
+	return u.dayKeyAt(time.Now())
+}
+
+// dayKeyAt returns the quest day that t falls in, honoring the reset hour
+func (u *UserData) dayKeyAt(t time.Time) string {
+	return DayKeyAt(u.DayResetHour, t)
+}
+
+// DayKeyAt returns the quest day that t falls in for a reset at hour
+func DayKeyAt(hour int, t time.Time) string {
+	// If the time is before reset hour, use previous calendar day
+	if t.Hour() < hour {
+		t = t.Add(-24 * time.Hour)
 	}
-	return now.Format("2006-01-02")
+	return t.Format(DayKeyLayout)
 }
 
 func (u *UserData) CompletedToday(habitID string) bool {
+	return u.CompletedOn(habitID, u.TodayKey())
+}
+
+// CompletedOn reports whether the habit was completed on the given day key
+func (u *UserData) CompletedOn(habitID, day string) bool {
 	u.mu.Lock()
 	defer u.mu.Unlock()
 	if u.DailyCompletions == nil {
 		return false
 	}
-	today := u.TodayKey()
-	day, ok := u.DailyCompletions[today]
+	completions, ok := u.DailyCompletions[day]
 	if !ok {
 		return false
 	}
-	return day[habitID]
+	return completions[habitID]
 }
 
-func (u *UserData) ToggleToday(habitID string) (gainedEXP bool, leveledUp bool) {
+// MissedOn reports whether the habit was left incomplete on a finished day:
+// one before today, on or after the day the habit was added
+func (u *UserData) MissedOn(habitID, day string) bool {
+	if day >= u.TodayKey() {
+		return false
+	}
+	h, ok := u.HabitByID(habitID)
+	if !ok {
+		return false
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if created := u.habitCreatedDay(h); created != "" && created > day {
+		return false
+	}
+	return !u.questMet(h, day)
+}
+
+// ToggleToday flips the habit's completion for today. Unchecking a
+// completion that is locked in (see UncheckGrace) changes nothing and
+// returns ErrCompletionLocked, or ErrCompletionFinal under UncheckLocked;
+// completing a quest whose prerequisite isn't done yet returns
+// ErrQuestChained.
+func (u *UserData) ToggleToday(habitID string) (gainedEXP, leveledUp bool, err error) {
 	u.mu.Lock()
 	defer u.mu.Unlock()
+	day := u.TodayKey()
+	if u.lockedIn(habitID, day, time.Now()) {
+		return false, false, lockedErr()
+	}
+	if u.chainLocked(habitID, day) {
+		return false, false, ErrQuestChained
+	}
+	gainedEXP, leveledUp = u.toggleOnDay(habitID, day)
+	return gainedEXP, leveledUp, nil
+}
+
+// ToggleOnDay toggles a habit on a past day (backfill) with the same EXP math
+// as ToggleToday, and the same locks. The day must not be in the future or
+// more than BackfillDays ago. Call RecomputeStreak afterwards so the streak reflects the change.
+func (u *UserData) ToggleOnDay(habitID, dayKey string) (gainedEXP, leveledUp bool, err error) {
 	today := u.TodayKey()
-	if u.DailyCompletions == nil {
-		u.DailyCompletions = make(map[string]map[string]bool)
+	if _, err := time.Parse(DayKeyLayout, dayKey); err != nil {
+		return false, false, fmt.Errorf("invalid day %q", dayKey)
+	}
+	if dayKey > today {
+		return false, false, fmt.Errorf("can't complete quests in the future")
+	}
+	if daysBetween(dayKey, today) > BackfillDays {
+		return false, false, fmt.Errorf("can only backfill the last %d days", BackfillDays)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.lockedIn(habitID, dayKey, time.Now()) {
+		return false, false, lockedErr()
 	}
-	if u.DailyCompletions[today] == nil {
-		u.DailyCompletions[today] = make(map[string]bool)
+	if u.chainLocked(habitID, dayKey) {
+		return false, false, ErrQuestChained
 	}
-	was := u.DailyCompletions[today][habitID]
-	u.DailyCompletions[today][habitID] = !was
+	gainedEXP, leveledUp = u.toggleOnDay(habitID, dayKey)
+	return gainedEXP, leveledUp, nil
+}
+
+// toggleOnDay flips the habit's completion for day and adjusts EXP and
+// level. Caller holds u.mu.
+func (u *UserData) toggleOnDay(habitID, day string) (gainedEXP bool, leveledUp bool) {
+	if habitID == WelcomeQuestID {
+		if u.habitName(habitID) == "" {
+			return false, false
+		}
+		return true, u.completeWelcomeQuest()
+	}
+	was := u.DailyCompletions[day][habitID]
+	defer func() {
+		evType := AuditComplete
+		if !gainedEXP {
+			evType = AuditUncomplete
+		}
+		Audit(u.Username, AuditEvent{Type: evType, HabitID: habitID, Habit: u.habitName(habitID), Day: day, EXP: u.EXP, Level: u.Level})
+		if leveledUp {
+			Audit(u.Username, AuditEvent{Type: AuditLevelUp, EXP: u.EXP, Level: u.Level})
+		}
+	}()
 	gainedEXP = !was // only gain EXP when marking complete
 	if gainedEXP {
-		u.EXP += EXPPerQuest
-		for u.EXP >= u.Level*EXPPerLevel {
-			u.Level++
-			leveledUp = true
+		leveledUp = u.addEXP(u.markDone(day, habitID))
+	} else {
+		u.removeEXP(u.markUndone(day, habitID))
+	}
+	return gainedEXP, leveledUp
+}
+
+// markDone records the habit as completed on day and returns the EXP the
+// completion pays; adding it to EXP and level is up to the caller. A
+// completion unchecked earlier that day under UncheckNoRefund kept its EXP,
+// so checking it again pays nothing. Caller holds u.mu.
+func (u *UserData) markDone(day, habitID string) int {
+	if u.DailyCompletions == nil {
+		u.DailyCompletions = make(map[string]map[string]bool)
+	}
+	if u.DailyCompletions[day] == nil {
+		u.DailyCompletions[day] = make(map[string]bool)
+	}
+	u.DailyCompletions[day][habitID] = true
+	if kept, ok := u.takeKeptEXP(day, habitID); ok {
+		u.recordCompletionEXP(day, habitID, kept) // still what the completion paid, should it be refunded later
+		u.recordCompletedAt(day, habitID, time.Now())
+		return 0
+	}
+	exp := u.questEXP() + u.spotlightBonus(day, habitID)
+	u.recordCompletionEXP(day, habitID, exp)
+	u.recordCompletedAt(day, habitID, time.Now())
+	u.creditEXP(day, exp)
+	return exp
+}
+
+// markUndone clears the habit's completion on day and returns the EXP the
+// completion paid, whatever QuestEXP is now, for the caller to take back.
+// Under UncheckNoRefund the EXP is kept instead and it returns 0. Caller
+// holds u.mu.
+func (u *UserData) markUndone(day, habitID string) int {
+	exp := u.earnedEXP(day, habitID)
+	u.DailyCompletions[day][habitID] = false
+	u.recordCompletionEXP(day, habitID, EXPPerQuest)
+	u.recordCompletedAt(day, habitID, time.Time{})
+	if UncheckPolicy == UncheckNoRefund {
+		u.keepEXP(day, habitID, exp)
+		return 0
+	}
+	u.creditEXP(day, -exp)
+	return exp
+}
+
+// removeEXP takes n EXP back, never below 0, dropping levels to match.
+// Caller holds u.mu.
+func (u *UserData) removeEXP(n int) {
+	u.EXP = max(u.EXP-n, 0)
+	for u.Level > 1 && u.EXP < (u.Level-1)*EXPPerLevel {
+		u.Level--
+	}
+}
+
+// ToggleMany sets today's completion of every habit in habitIDs to
+// complete under one lock, recomputes the level once, and returns the net
+// change in EXP and level. Habits already in that state are left alone, so
+// repeating a call changes nothing. Unknown IDs, the welcome quest (see
+// ToggleToday), completions locked in by UncheckGrace and quests whose
+// prerequisite stays open are skipped; a prerequisite completed in the same
+// batch counts whatever the order. Call UpdateStreak afterwards.
+func (u *UserData) ToggleMany(habitIDs []string, complete bool) (expDelta, levelDelta int) {
+	day := u.TodayKey()
+	now := time.Now()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	beforeEXP, beforeLevel := u.EXP, u.Level
+	pending := make([]string, 0, len(habitIDs))
+	seen := make(map[string]bool, len(habitIDs))
+	for _, id := range habitIDs {
+		if !seen[id] {
+			seen[id] = true
+			pending = append(pending, id)
 		}
+	}
+	var changed []string
+	paid := 0
+	for progress := true; progress; {
+		progress = false
+		waiting := pending[:0]
+		for _, id := range pending {
+			switch {
+			case id == WelcomeQuestID || u.habitName(id) == "" || u.DailyCompletions[day][id] == complete:
+				continue
+			case !complete && u.lockedIn(id, day, now):
+				continue
+			case complete && u.chainLocked(id, day):
+				waiting = append(waiting, id) // its prerequisite may come later in the batch
+				continue
+			}
+			if complete {
+				paid += u.markDone(day, id)
+			} else {
+				paid -= u.markUndone(day, id)
+			}
+			changed = append(changed, id)
+			progress = true
+		}
+		pending = waiting
+	}
+	if paid > 0 {
+		u.addEXP(paid)
 	} else {
-		u.EXP -= EXPPerQuest
-		if u.EXP < 0 {
-			u.EXP = 0
+		u.removeEXP(-paid)
+	}
+	evType := AuditComplete
+	if !complete {
+		evType = AuditUncomplete
+	}
+	for _, id := range changed {
+		Audit(u.Username, AuditEvent{Type: evType, HabitID: id, Habit: u.habitName(id), Day: day, EXP: u.EXP, Level: u.Level})
+	}
+	if u.Level > beforeLevel {
+		Audit(u.Username, AuditEvent{Type: AuditLevelUp, EXP: u.EXP, Level: u.Level})
+	}
+	return u.EXP - beforeEXP, u.Level - beforeLevel
+}
+
+// UncheckCostsLevel reports whether unchecking the habit on day (a day key)
+// would drop the user a level. It changes nothing.
+func (u *UserData) UncheckCostsLevel(habitID, day string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if habitID == WelcomeQuestID || !u.DailyCompletions[day][habitID] || UncheckPolicy == UncheckNoRefund {
+		return false
+	}
+	return u.Level > 1 && u.EXP-u.earnedEXP(day, habitID) < (u.Level-1)*EXPPerLevel
+}
+
+// Bounds for a user's QuestEXP
+const (
+	MinQuestEXP = 1
+	MaxQuestEXP = 50
+)
+
+// EffectiveQuestEXP is the EXP the next completed quest pays: the user's
+// QuestEXP if set, else EXPPerQuest
+func (u *UserData) EffectiveQuestEXP() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.questEXP()
+}
+
+// questEXP is EffectiveQuestEXP for a caller holding u.mu
+func (u *UserData) questEXP() int {
+	if u.QuestEXP == 0 {
+		return EXPPerQuest
+	}
+	return u.QuestEXP
+}
+
+// UpdateQuestEXP sets the EXP each completed quest pays from now on. EXP
+// already earned is left alone, and unchecking an earlier completion takes
+// back what it paid at the time.
+func (u *UserData) UpdateQuestEXP(exp int) error {
+	if exp < MinQuestEXP || exp > MaxQuestEXP {
+		return fmt.Errorf("quest EXP must be %d-%d", MinQuestEXP, MaxQuestEXP)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if exp == EXPPerQuest {
+		exp = 0
+	}
+	u.QuestEXP = exp
+	return nil
+}
+
+// earnedEXP is what the habit's completion on day paid. Caller holds u.mu.
+func (u *UserData) earnedEXP(day, habitID string) int {
+	if exp, ok := u.CompletionEXP[day][habitID]; ok {
+		return exp
+	}
+	return EXPPerQuest
+}
+
+// recordCompletionEXP notes that the habit's completion on day paid exp.
+// Only amounts other than EXPPerQuest are stored. Caller holds u.mu.
+func (u *UserData) recordCompletionEXP(day, habitID string, exp int) {
+	if exp == EXPPerQuest {
+		delete(u.CompletionEXP[day], habitID)
+		if len(u.CompletionEXP[day]) == 0 {
+			delete(u.CompletionEXP, day)
 		}
-		for u.Level > 1 && u.EXP < (u.Level-1)*EXPPerLevel {
-			u.Level--
+		return
+	}
+	if u.CompletionEXP == nil {
+		u.CompletionEXP = make(map[string]map[string]int)
+	}
+	if u.CompletionEXP[day] == nil {
+		u.CompletionEXP[day] = make(map[string]int)
+	}
+	u.CompletionEXP[day][habitID] = exp
+}
+
+// creditEXP adds n (negative to reverse) to the EXP earned on day. A day's
+// total never goes below zero, since completions from before DailyEXP was
+// tracked can be unchecked too. Caller holds u.mu.
+func (u *UserData) creditEXP(day string, n int) {
+	total := u.DailyEXP[day] + n
+	if total <= 0 {
+		delete(u.DailyEXP, day)
+		return
+	}
+	if u.DailyEXP == nil {
+		u.DailyEXP = make(map[string]int)
+	}
+	u.DailyEXP[day] = total
+}
+
+// EXPOn returns the EXP earned on day (a day key)
+func (u *UserData) EXPOn(day string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.DailyEXP[day]
+}
+
+// addEXP adds n EXP and levels up as far as it reaches, stopping at the
+// level cap. Caller holds u.mu.
+func (u *UserData) addEXP(n int) (leveledUp bool) {
+	level := u.levelAfter(n)
+	u.EXP += n
+	leveledUp = level > u.Level
+	u.Level = level
+	return leveledUp
+}
+
+// levelAfter is the level gaining n more EXP would reach, capped like
+// addEXP. Caller holds u.mu.
+func (u *UserData) levelAfter(n int) int {
+	level := u.Level
+	for u.EXP+n >= level*EXPPerLevel && !(LevelCap > 0 && level >= LevelCap) {
+		level++
+	}
+	return level
+}
+
+// EXPPreview is what completing a quest today would do, worked out without
+// changing anything
+type EXPPreview struct {
+	Done  bool // already completed today; nothing to gain
+	EXP   int  // EXP the completion would pay
+	Level int  // level the hunter would be at afterwards
+}
+
+// PreviewCompletion mirrors the EXP math of completing the habit with
+// ToggleToday: the hunter's quest EXP plus any spotlight bonus (or the
+// welcome bonus) and any level it would reach, level cap included. A quest
+// unchecked earlier today under UncheckNoRefund already paid, so it previews 0.
+func (u *UserData) PreviewCompletion(habitID string) EXPPreview {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.DailyCompletions[today][habitID] {
+		return EXPPreview{Done: true, Level: u.Level}
+	}
+	exp := u.questEXP() + u.spotlightBonus(today, habitID)
+	if _, kept := u.KeptEXP[today][habitID]; kept {
+		exp = 0 // see markDone
+	}
+	if habitID == WelcomeQuestID {
+		exp = WelcomeQuestBonus
+	}
+	return EXPPreview{EXP: exp, Level: u.levelAfter(exp)}
+}
+
+// HabitStreak counts the consecutive days the habit was completed, ending
+// today if it's done already, otherwise ending yesterday
+func (u *UserData) HabitStreak(habitID string) int {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	day, err := time.ParseInLocation(DayKeyLayout, today, time.UTC)
+	if err != nil {
+		return 0
+	}
+	if !u.DailyCompletions[today][habitID] {
+		// Today isn't over yet, so it doesn't break the streak
+		day = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for u.DailyCompletions[day.Format(DayKeyLayout)][habitID] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
+// ReminderDue reports whether h has a reminder that has passed in the
+// current quest day while h is still incomplete. Reminder hours before the
+// reset hour belong to the end of the quest day (e.g. 02:00 after a 04:00
+// reset is the following night). Reminders are never due in quiet hours.
+func (u *UserData) ReminderDue(h Habit, now time.Time) bool {
+	if h.ReminderHour == nil || u.CompletedToday(h.ID) || u.InQuietHours(now) {
+		return false
+	}
+	day, err := time.ParseInLocation(DayKeyLayout, u.dayKeyAt(now), now.Location())
+	if err != nil {
+		return false
+	}
+	hour := *h.ReminderHour
+	if hour < u.DayResetHour {
+		day = day.AddDate(0, 0, 1)
+	}
+	due := time.Date(day.Year(), day.Month(), day.Day(), hour, 0, 0, 0, now.Location())
+	return !now.Before(due)
+}
+
+// habitStart guesses when a habit saved without CreatedAt began: its first
+// completion, else account creation, else now
+func (u *UserData) habitStart(habitID string) time.Time {
+	first := ""
+	for day, completions := range u.DailyCompletions {
+		if completions[habitID] && (first == "" || day < first) {
+			first = day
 		}
 	}
-	return gainedEXP, leveledUp
+	if t, err := time.ParseInLocation(DayKeyLayout, first, time.Local); err == nil {
+		// Noon keeps the day stable whatever the reset hour
+		return t.Add(12 * time.Hour)
+	}
+	if !u.CreatedAt.IsZero() {
+		return u.CreatedAt
+	}
+	return time.Now()
+}
+
+// HabitCompletionRate is the share of days since the habit was created,
+// today included, on which it was completed, from 0 to 1
+func (u *UserData) HabitCompletionRate(habitID string) float64 {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var created time.Time
+	for _, h := range u.Habits {
+		if h.ID == habitID {
+			created = h.CreatedAt
+		}
+	}
+	if created.IsZero() {
+		return 0
+	}
+	start := u.dayKeyAt(created)
+	done := 0
+	for day, completions := range u.DailyCompletions {
+		if completions[habitID] && day <= today {
+			done++
+			// A backfill can predate CreatedAt; widen the window to match
+			if day < start {
+				start = day
+			}
+		}
+	}
+	first, err1 := time.Parse(DayKeyLayout, start)
+	last, err2 := time.Parse(DayKeyLayout, today)
+	if err1 != nil || err2 != nil || last.Before(first) {
+		return 0
+	}
+	days := int(last.Sub(first).Hours()/24) + 1
+	return float64(done) / float64(days)
 }
 
-// AllQuestsCompletedToday checks if all habits are completed for today
+// TodayProgress returns how many of u's quests are done today, out of all
+func (u *UserData) TodayProgress() (done, total int) {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, h := range u.Habits {
+		if u.DailyCompletions[today][h.ID] {
+			done++
+		}
+	}
+	return done, len(u.Habits)
+}
+
+// AllQuestsCompletedToday checks if all habits are completed for today; a
+// weekly quest still on track for its target needn't be
 func (u *UserData) AllQuestsCompletedToday() bool {
 	if len(u.Habits) == 0 {
 		return false
@@ -105,135 +693,761 @@ func (u *UserData) AllQuestsCompletedToday() bool {
 	today := u.TodayKey()
 	u.mu.Lock()
 	defer u.mu.Unlock()
-	if u.DailyCompletions == nil || u.DailyCompletions[today] == nil {
+	if u.DailyCompletions == nil || u.DailyCompletions[today] == nil {
+		return false
+	}
+	for _, h := range u.Habits {
+		if !u.questMet(h, today) {
+			return false
+		}
+	}
+	return true
+}
+
+// LastQuestLeft returns the one quest still needed today; weekly quests on
+// track for their target aren't. ok is false when every quest is done or
+// more than one is left.
+func (u *UserData) LastQuestLeft() (h Habit, ok bool) {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, q := range u.Habits {
+		if u.questMet(q, today) {
+			continue
+		}
+		if ok {
+			return Habit{}, false
+		}
+		h, ok = q, true
+	}
+	return h, ok
+}
+
+// UpdateStreak updates the streak based on completion status.
+// Returns the EXP lost if a hardcore streak break was found.
+func (u *UserData) UpdateStreak() (penalty, shielded int) {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	defer u.auditStreak(u.CurrentStreak)
+	penalty, shielded = u.breakStaleStreak(today)
+
+	// Check if all quests completed today
+	allComplete := u.perfectDay(today)
+
+	if !allComplete {
+		// If today was complete but now isn't (unchecked a quest)
+		if u.LastCompleteDay == today {
+			u.LastCompleteDay = ""
+			u.CurrentStreak--
+			if u.CurrentStreak < 0 {
+				u.CurrentStreak = 0
+			}
+		}
+		return penalty, shielded
+	}
+
+	// All quests completed today
+	if u.LastCompleteDay == today {
+		// Already counted today
+		return penalty, shielded
+	}
+
+	// Check if yesterday was the last complete day (streak continues)
+	yesterday := time.Now()
+	if yesterday.Hour() < u.DayResetHour {
+		yesterday = yesterday.Add(-24 * time.Hour)
+	}
+	yesterday = yesterday.Add(-24 * time.Hour)
+	yesterdayKey := yesterday.Format("2006-01-02")
+
+	if u.LastCompleteDay == yesterdayKey {
+		// Streak continues
+		u.CurrentStreak++
+	} else if u.LastCompleteDay == "" {
+		// First completion or streak was broken
+		u.CurrentStreak = 1
+	} else {
+		// Streak broken, start fresh
+		u.CurrentStreak = 1
+	}
+
+	u.LastCompleteDay = today
+	if u.CurrentStreak > u.LongestStreak {
+		u.LongestStreak = u.CurrentStreak
+	}
+	if u.AnnouncedDay != today {
+		u.AnnouncedDay = today
+		Audit(u.Username, AuditEvent{Type: AuditDayComplete, Day: today, EXP: u.EXP, Level: u.Level, Streak: u.CurrentStreak})
+	}
+	return penalty, shielded
+}
+
+// RecomputeStreak rebuilds the current streak from completion history, e.g.
+// after backfilling a past day. A day counts when every habit that existed
+// then was completed; today only counts once it's complete.
+func (u *UserData) RecomputeStreak() {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	defer u.auditStreak(u.CurrentStreak)
+	day, err := time.ParseInLocation(DayKeyLayout, today, time.UTC)
+	if err != nil {
+		return
+	}
+	if !u.perfectDay(today) {
+		day = day.AddDate(0, 0, -1)
+	}
+	last := day.Format(DayKeyLayout)
+	streak := 0
+	for {
+		key := day.Format(DayKeyLayout)
+		if u.perfectDay(key) {
+			streak++
+		} else if !u.ShieldedDays[key] {
+			break
+		}
+		// Shielded days neither count toward nor break the streak
+		day = day.AddDate(0, 0, -1)
+	}
+	u.CurrentStreak = streak
+	u.LastCompleteDay = ""
+	if streak > 0 {
+		u.LastCompleteDay = last
+	}
+	if u.CurrentStreak > u.LongestStreak {
+		u.LongestStreak = u.CurrentStreak
+	}
+}
+
+// perfectDay reports whether every habit existing on day was completed that
+// day, or is a weekly quest still on track (see questMet), with at least one
+// completion, and the day wasn't forfeited by deleting an open quest.
+// Caller holds u.mu.
+func (u *UserData) perfectDay(day string) bool {
+	if u.ForfeitedDays[day] {
+		return false
+	}
+	done := false
+	for _, h := range u.Habits {
+		if created := u.habitCreatedDay(h); created != "" && created > day {
+			continue
+		}
+		if !u.questMet(h, day) {
+			return false
+		}
+		done = done || u.DailyCompletions[day][h.ID]
+	}
+	return done
+}
+
+// CheckStreakBreak ends a streak whose last complete day is before yesterday,
+// e.g. on login after some days away, unless shields cover the gap. Returns
+// the hardcore EXP penalty, if any, and the shields used.
+func (u *UserData) CheckStreakBreak() (penalty, shielded int) {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	defer u.auditStreak(u.CurrentStreak)
+	return u.breakStaleStreak(today)
+}
+
+// auditStreak logs a streak event if the streak changed from prev. Caller holds u.mu.
+func (u *UserData) auditStreak(prev int) {
+	if u.CurrentStreak != prev {
+		Audit(u.Username, AuditEvent{Type: AuditStreak, Streak: u.CurrentStreak, Detail: fmt.Sprintf("from %d", prev)})
+	}
+}
+
+// breakStaleStreak resets the current streak if one or more days were missed
+// since LastCompleteDay and applies the hardcore penalty, unless enough
+// shields cover the missed days. Caller holds u.mu.
+func (u *UserData) breakStaleStreak(today string) (penalty, shielded int) {
+	if u.CurrentStreak == 0 || u.LastCompleteDay == "" {
+		return 0, 0
+	}
+	missed := daysBetween(u.LastCompleteDay, today) - 1
+	if missed < 1 {
+		return 0, 0
+	}
+	if shielded = u.useShields(missed); shielded > 0 {
+		return 0, shielded
+	}
+	u.CurrentStreak = 0
+	if !u.HardcoreMode {
+		return 0, 0
+	}
+	penalty = u.hardcorePenalty(missed)
+	u.EXP -= penalty
+	if penalty > 0 {
+		Audit(u.Username, AuditEvent{Type: AuditStreak, EXP: u.EXP, Level: u.Level, Detail: fmt.Sprintf("hardcore penalty -%d EXP after %d missed days", penalty, missed)})
+	}
+	return penalty, 0
+}
+
+// HardcorePenalty previews the EXP a streak break after missedDays would cost
+func (u *UserData) HardcorePenalty(missedDays int) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.hardcorePenalty(missedDays)
+}
+
+// hardcorePenalty never takes EXP below the current level's floor, so a
+// penalty can't cost a level. Caller holds u.mu.
+func (u *UserData) hardcorePenalty(missedDays int) int {
+	if missedDays < 1 {
+		return 0
+	}
+	pct := HardcorePenaltyPercent
+	for i := 1; i < missedDays && pct < 100; i++ {
+		pct *= 2
+	}
+	if pct > 100 {
+		pct = 100
+	}
+	penalty := u.EXP * pct / 100
+	if floor := (u.Level - 1) * EXPPerLevel; u.EXP-penalty < floor {
+		penalty = u.EXP - floor
+	}
+	if penalty < 0 {
+		penalty = 0
+	}
+	return penalty
+}
+
+// daysBetween returns the number of days from day key a to day key b
+func daysBetween(a, b string) int {
+	ta, errA := time.ParseInLocation(DayKeyLayout, a, time.UTC)
+	tb, errB := time.ParseInLocation(DayKeyLayout, b, time.UTC)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	return int(tb.Sub(ta).Hours() / 24)
+}
+
+// addDays returns the day key n days after day key day
+func addDays(day string, n int) string {
+	t, err := time.ParseInLocation(DayKeyLayout, day, time.UTC)
+	if err != nil {
+		return day
+	}
+	return t.AddDate(0, 0, n).Format(DayKeyLayout)
+}
+
+// AtLevelCap reports whether the user has reached LevelCap and can prestige
+func (u *UserData) AtLevelCap() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.atLevelCap()
+}
+
+func (u *UserData) atLevelCap() bool {
+	return LevelCap > 0 && u.Level >= LevelCap
+}
+
+// Prestige resets level, EXP and stats for a user at the level cap, in
+// exchange for a prestige count and a permanent stat bonus. Habits, history
+// and streaks are kept.
+func (u *UserData) Prestige() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.atLevelCap() {
+		return fmt.Errorf("reach level %d to prestige", LevelCap)
+	}
+	u.PrestigeCount++
+	u.Level = DefaultLevel
+	u.EXP = 0
+	base := baseStats + DefaultLevel + u.PrestigeCount*PrestigeStatBonus
+	u.STR, u.VIT, u.AGI, u.INT = base, base, base, base
+	Audit(u.Username, AuditEvent{Type: AuditPrestige, Level: u.Level, Detail: fmt.Sprintf("prestige %d", u.PrestigeCount)})
+	return nil
+}
+
+// levelForEXP is the level a total of exp EXP reaches on the level curve,
+// held at LevelCap when one is set
+func levelForEXP(exp int) int {
+	level := DefaultLevel + exp/EXPPerLevel
+	if LevelCap > 0 && level > LevelCap {
+		level = LevelCap
+	}
+	return level
+}
+
+func (u *UserData) EXPForNextLevel() int {
+	return u.Level * EXPPerLevel
+}
+
+func (u *UserData) EXPInCurrentLevel() int {
+	base := (u.Level - 1) * EXPPerLevel
+	return u.EXP - base
+}
+
+// EXPProgress is how far the user is toward the next level in the basis
+// they chose (see EXPProgressFor)
+func (u *UserData) EXPProgress() (have, need int) {
+	return EXPProgressFor(u.Level, u.EXP, u.ShowTotalEXP)
+}
+
+// EXPProgressFor is progress toward the level after level: EXP within the
+// level out of EXPPerLevel, or with total, all EXP out of the total the
+// next level needs
+func EXPProgressFor(level, exp int, total bool) (have, need int) {
+	if total {
+		return exp, level * EXPPerLevel
+	}
+	return exp - (level-1)*EXPPerLevel, EXPPerLevel
+}
+
+// NearLevelUp reports whether one more quest would reach the next level: a
+// quest is still open today and the EXP left to the level is no more than a
+// quest pays. False at the level cap.
+func (u *UserData) NearLevelUp() bool {
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	left := u.Level*EXPPerLevel - u.EXP
+	if u.atLevelCap() || left <= 0 || left > u.questEXP() {
+		return false
+	}
+	for _, h := range u.Habits {
+		if !u.DailyCompletions[today][h.ID] {
+			return true
+		}
+	}
+	return false
+}
+
+// NextResetTime returns the exact time of the next day reset
+func (u *UserData) NextResetTime() time.Time {
+	return NextResetAt(u.DayResetHour, time.Now())
+}
+
+// NextResetAt returns the first reset at hour strictly after now, without
+// touching any saved setting (used to preview a candidate reset hour)
+func NextResetAt(hour int, now time.Time) time.Time {
+	// Create today's reset time
+	todayReset := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	// If we've already passed today's reset, use tomorrow's
+	if now.After(todayReset) || now.Equal(todayReset) {
+		return todayReset.AddDate(0, 0, 1)
+	}
+	return todayReset
+}
+
+// TimeUntilReset returns the duration until the next day reset
+func (u *UserData) TimeUntilReset() time.Duration {
+	return time.Until(u.NextResetTime())
+}
+
+// UpdateDayResetHour updates the reset hour with validation. If the new
+// hour makes the current moment fall in a different quest day (e.g. moving
+// a 00:00 reset to 08:00 at 03:00 turns today into yesterday), the day in
+// progress keeps its completions: they move to the new day key, merged with
+// any already there, so nothing is orphaned under a day that is now in the
+// future or no longer current. A day merged into one the streak already
+// counted isn't counted again.
+func (u *UserData) UpdateDayResetHour(hour int) error {
+	if hour < 0 || hour > 23 {
+		return fmt.Errorf("reset hour must be between 0 and 23")
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.setDayResetHour(hour, time.Now())
+	return nil
+}
+
+// setDayResetHour is UpdateDayResetHour for a valid hour. Caller holds u.mu.
+func (u *UserData) setDayResetHour(hour int, now time.Time) {
+	from, to := u.dayKeyAt(now), DayKeyAt(hour, now)
+	u.DayResetHour = hour
+	if from == to {
+		return
+	}
+	// Whether the day today lands on already counted toward the streak, as
+	// a complete yesterday would have
+	landedCounted := u.perfectDay(to)
+	if moved := u.DailyCompletions[from]; len(moved) > 0 {
+		if u.DailyCompletions[to] == nil {
+			u.DailyCompletions[to] = make(map[string]bool, len(moved))
+		}
+		for id, done := range moved {
+			if done && !u.DailyCompletions[to][id] {
+				u.DailyCompletions[to][id] = true
+				u.recordCompletionEXP(to, id, u.earnedEXP(from, id))
+				u.recordCompletedAt(to, id, u.CompletedAt[from][id])
+			}
+		}
+		delete(u.DailyCompletions, from)
+		delete(u.CompletionEXP, from)
+		delete(u.CompletedAt, from)
+	}
+	if exp := u.DailyEXP[from]; exp > 0 {
+		delete(u.DailyEXP, from)
+		u.creditEXP(to, exp)
+	}
+	if kept := u.KeptEXP[from]; len(kept) > 0 {
+		for id, exp := range kept {
+			if !u.DailyCompletions[to][id] {
+				u.keepEXP(to, id, exp)
+			}
+		}
+		delete(u.KeptEXP, from)
+	}
+	if u.ForfeitedDays[from] {
+		delete(u.ForfeitedDays, from)
+		u.ForfeitedDays[to] = true
+	}
+	if u.LastCompleteDay == from {
+		// Today counted too. Merged into yesterday the two are one day, so
+		// the streak loses the one it gained for today.
+		if landedCounted && to == addDays(from, -1) && u.CurrentStreak > 1 {
+			u.CurrentStreak--
+		}
+		u.LastCompleteDay = to
+	}
+	if u.AnnouncedDay == from {
+		u.AnnouncedDay = to
+	}
+}
+
+// UpdateKeymap sets the navigation keymap preference
+func (u *UserData) UpdateKeymap(keymap string) error {
+	if !validKeymap(keymap) {
+		return fmt.Errorf("unknown keymap %q", keymap)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.Keymap = keymap
+	return nil
+}
+
+// UpdateCompleteKey sets which key completes a quest
+func (u *UserData) UpdateCompleteKey(key string) error {
+	if !validCompleteKey(key) {
+		return fmt.Errorf("unknown complete key %q", key)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.CompleteKey = key
+	return nil
+}
+
+// UpdateDateFormat sets how dates and times are shown
+func (u *UserData) UpdateDateFormat(format string) error {
+	if !validDateFormat(format) {
+		return fmt.Errorf("unknown date format %q", format)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.DateFormat = format
+	return nil
+}
+
+// UpdateTheme sets the color theme preference
+func (u *UserData) UpdateTheme(theme string) error {
+	if !validTheme(theme) {
+		return fmt.Errorf("unknown theme %q", theme)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.Theme = theme
+	return nil
+}
+
+// NeedsTutorial reports whether to show the first-run tutorial: only for
+// accounts created with CreatedAt tracked, with no quests but the welcome
+// quest, that haven't seen it
+func (u *UserData) NeedsTutorial() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	quests := len(u.Habits)
+	if u.habitName(WelcomeQuestID) != "" {
+		quests--
+	}
+	return !u.TutorialSeen && !u.CreatedAt.IsZero() && quests == 0
+}
+
+// MarkTutorialSeen records that the tutorial was dismissed
+func (u *UserData) MarkTutorialSeen() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.TutorialSeen = true
+}
+
+// SetHardcoreMode opts in to (or out of) EXP penalties for broken streaks
+func (u *UserData) SetHardcoreMode(on bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.HardcoreMode = on
+}
+
+// SetShowTotalEXP picks total EXP (true) or EXP within the level (false)
+// for the EXP bar and label
+func (u *UserData) SetShowTotalEXP(total bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.ShowTotalEXP = total
+}
+
+// SetSelectedHabit remembers the quest under the cursor and reports whether
+// that changed anything
+func (u *UserData) SetSelectedHabit(id string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.SelectedHabit == id {
 		return false
 	}
-	for _, h := range u.Habits {
-		if !u.DailyCompletions[today][h.ID] {
-			return false
-		}
-	}
+	u.SelectedHabit = id
 	return true
 }
 
-// UpdateStreak updates the streak based on completion status
-func (u *UserData) UpdateStreak() {
-	today := u.TodayKey()
+// SetHideNudge hides (true) or shows (false) the last-quest nudge
+func (u *UserData) SetHideNudge(hide bool) {
 	u.mu.Lock()
 	defer u.mu.Unlock()
+	u.HideNudge = hide
+}
 
-	// Check if all quests completed today
-	allComplete := true
-	if len(u.Habits) == 0 {
-		allComplete = false
-	} else if u.DailyCompletions == nil || u.DailyCompletions[today] == nil {
-		allComplete = false
-	} else {
-		for _, h := range u.Habits {
-			if !u.DailyCompletions[today][h.ID] {
-				allComplete = false
-				break
-			}
-		}
-	}
+// SetHideAlmostThere hides (true) or shows (false) the almost-there flag
+func (u *UserData) SetHideAlmostThere(hide bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.HideAlmostThere = hide
+}
 
-	if !allComplete {
-		// If today was complete but now isn't (unchecked a quest)
-		if u.LastCompleteDay == today {
-			u.LastCompleteDay = ""
-			u.CurrentStreak--
-			if u.CurrentStreak < 0 {
-				u.CurrentStreak = 0
-			}
+// SetMuteBell turns the level-up bell off (true) or on (false)
+func (u *UserData) SetMuteBell(mute bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.MuteBell = mute
+}
+
+func validKeymap(keymap string) bool {
+	for _, k := range Keymaps {
+		if k == keymap {
+			return true
 		}
-		return
 	}
+	return false
+}
 
-	// All quests completed today
-	if u.LastCompleteDay == today {
-		// Already counted today
-		return
+func validCompleteKey(key string) bool {
+	for _, k := range CompleteKeys {
+		if k == key {
+			return true
+		}
 	}
+	return false
+}
 
-	// Check if yesterday was the last complete day (streak continues)
-	yesterday := time.Now()
-	if yesterday.Hour() < u.DayResetHour {
-		yesterday = yesterday.Add(-24 * time.Hour)
+// validDateFormat accepts "" too, which stands for DateFormatISO
+func validDateFormat(format string) bool {
+	if format == "" {
+		return true
 	}
-	yesterday = yesterday.Add(-24 * time.Hour)
-	yesterdayKey := yesterday.Format("2006-01-02")
-
-	if u.LastCompleteDay == yesterdayKey {
-		// Streak continues
-		u.CurrentStreak++
-	} else if u.LastCompleteDay == "" {
-		// First completion or streak was broken
-		u.CurrentStreak = 1
-	} else {
-		// Streak broken, start fresh
-		u.CurrentStreak = 1
+	for _, f := range DateFormats {
+		if f == format {
+			return true
+		}
 	}
+	return false
+}
 
-	u.LastCompleteDay = today
-	if u.CurrentStreak > u.LongestStreak {
-		u.LongestStreak = u.CurrentStreak
+func validTheme(theme string) bool {
+	for _, t := range Themes {
+		if t == theme {
+			return true
+		}
 	}
+	return false
 }
 
-func (u *UserData) EXPForNextLevel() int {
-	return u.Level * EXPPerLevel
+// AddHabit appends a quest named name. It doesn't check MaxHabits; callers
+// adding on a user's behalf check AtHabitLimit first.
+func (u *UserData) AddHabit(name string) Habit {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.addHabit(name)
 }
 
-func (u *UserData) EXPInCurrentLevel() int {
-	base := (u.Level - 1) * EXPPerLevel
-	return u.EXP - base
+// addHabit appends a quest with a fresh ID. Caller holds u.mu.
+func (u *UserData) addHabit(name string) Habit {
+	id := fmt.Sprintf("h_%d", time.Now().UnixNano())
+	for u.hasHabitID(id) {
+		id += "x" // two adds within the clock's resolution
+	}
+	h := Habit{ID: id, Name: name, Icon: DefaultHabitIcon, CreatedAt: time.Now()}
+	u.Habits = append(u.Habits, h)
+	Audit(u.Username, AuditEvent{Type: AuditHabitAdded, HabitID: h.ID, Habit: h.Name})
+	return h
 }
 
-// NextResetTime returns the exact time of the next day reset
-func (u *UserData) NextResetTime() time.Time {
-	now := time.Now()
-	// Create today's reset time
-	todayReset := time.Date(now.Year(), now.Month(), now.Day(), u.DayResetHour, 0, 0, 0, now.Location())
-	// If we've already passed today's reset, use tomorrow's
-	if now.After(todayReset) || now.Equal(todayReset) {
-		return todayReset.Add(24 * time.Hour)
+// hasHabitID reports whether an active or archived quest has id. Caller
+// holds u.mu.
+func (u *UserData) hasHabitID(id string) bool {
+	for _, list := range [][]Habit{u.Habits, u.Archived} {
+		for _, h := range list {
+			if h.ID == id {
+				return true
+			}
+		}
 	}
-	return todayReset
+	return false
 }
 
-// TimeUntilReset returns the duration until the next day reset
-func (u *UserData) TimeUntilReset() time.Duration {
-	return time.Until(u.NextResetTime())
+// AddHabits adds a quest for each name, as pasted into the add form one per
+// line. Blank names are ignored. A name matching an existing quest or an
+// earlier one in names (ignoring case), or past MaxHabits, is skipped and
+// returned in skipped.
+func (u *UserData) AddHabits(names []string) (added int, skipped []string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	seen := make(map[string]bool, len(u.Habits)+len(names))
+	for _, h := range u.Habits {
+		seen[strings.ToLower(h.Name)] = true
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		key := strings.ToLower(name)
+		if seen[key] || u.atHabitLimit() {
+			skipped = append(skipped, name)
+			continue
+		}
+		seen[key] = true
+		u.addHabit(name)
+		added++
+	}
+	return added, skipped
 }
 
-// UpdateDayResetHour updates the reset hour with validation
-func (u *UserData) UpdateDayResetHour(hour int) error {
-	if hour < 0 || hour > 23 {
-		return fmt.Errorf("reset hour must be between 0 and 23")
-	}
+// AtHabitLimit reports whether u has MaxHabits active quests and can't add
+// another
+func (u *UserData) AtHabitLimit() bool {
 	u.mu.Lock()
 	defer u.mu.Unlock()
-	u.DayResetHour = hour
-	return nil
+	return u.atHabitLimit()
 }
 
-func (u *UserData) AddHabit(name string) Habit {
+func (u *UserData) atHabitLimit() bool {
+	return MaxHabits > 0 && len(u.Habits) >= MaxHabits
+}
+
+// EditHabit updates the editable fields of the habit with the given ID: only
+// Name, Note, Icon, Color, ReminderHour, WeeklyTarget and DependsOn are taken
+// from changes. A
+// DependsOn that would make a chain loop back on itself returns
+// ErrChainCycle and changes nothing. The ID,
+// CreatedAt and RestoredAt are never touched, so a renamed quest keeps its
+// completion history, streak and completion rate. Don't replace the whole
+// Habit here; DailyCompletions is keyed by the ID.
+func (u *UserData) EditHabit(id string, changes Habit) error {
+	name := strings.TrimSpace(changes.Name)
+	if name == "" {
+		return fmt.Errorf("quest name required")
+	}
+	if h := changes.ReminderHour; h != nil && (*h < 0 || *h > 23) {
+		return fmt.Errorf("reminder hour must be 0-23")
+	}
+	if changes.WeeklyTarget < 0 || changes.WeeklyTarget > MaxWeeklyTarget {
+		return fmt.Errorf("weekly target must be 0-%d", MaxWeeklyTarget)
+	}
 	u.mu.Lock()
 	defer u.mu.Unlock()
-	id := fmt.Sprintf("h_%d", time.Now().UnixNano())
-	h := Habit{ID: id, Name: name}
-	u.Habits = append(u.Habits, h)
-	return h
+	if err := u.checkDependsOn(id, changes.DependsOn); err != nil {
+		return err
+	}
+	for i := range u.Habits {
+		if u.Habits[i].ID == id {
+			u.Habits[i].DependsOn = changes.DependsOn
+			u.Habits[i].Name = name
+			u.Habits[i].Note = CleanNote(changes.Note)
+			u.Habits[i].Icon = CleanIcon(changes.Icon)
+			u.Habits[i].Color = CleanColor(changes.Color)
+			u.Habits[i].ReminderHour = changes.ReminderHour
+			u.Habits[i].WeeklyTarget = changes.WeeklyTarget
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown quest")
+}
+
+// CleanNote flattens a note to one line and caps it at MaxNoteRunes
+func CleanNote(note string) string {
+	note = strings.Join(strings.Fields(note), " ")
+	if runes := []rune(note); len(runes) > MaxNoteRunes {
+		note = string(runes[:MaxNoteRunes])
+	}
+	return note
+}
+
+// CleanColor returns color if it is one of HabitColors, else "" (no color)
+func CleanColor(color string) string {
+	for _, c := range HabitColors {
+		if c == color {
+			return color
+		}
+	}
+	return ""
+}
+
+// CleanIcon returns icon if it is one of HabitIcons, else DefaultHabitIcon
+func CleanIcon(icon string) string {
+	for _, i := range HabitIcons {
+		if i == icon {
+			return icon
+		}
+	}
+	return DefaultHabitIcon
 }
 
-func (u *UserData) RemoveHabit(index int) bool {
+// RemoveHabit deletes the habit at index. Deleting a quest still open today
+// would otherwise let the rest of the list make a perfect day, so that
+// forfeits today's perfect day (forfeited reports it) unless the quest was
+// only added today. Call UpdateStreak afterwards: deleting an open quest
+// added today can complete the day.
+func (u *UserData) RemoveHabit(index int) (removed, forfeited bool) {
+	today := u.TodayKey()
 	u.mu.Lock()
 	defer u.mu.Unlock()
 	if index < 0 || index >= len(u.Habits) {
-		return false
+		return false, false
 	}
-	u.Habits = append(u.Habits[:index], u.Habits[index+1:]...)
-	return true
+	h := u.removeHabitAt(index)
+	Audit(u.Username, AuditEvent{Type: AuditHabitRemoved, HabitID: h.ID, Habit: h.Name})
+	if h.ID == WelcomeQuestID || u.questMet(h, today) || u.habitAddedDay(h) == today {
+		return true, false
+	}
+	if u.ForfeitedDays == nil {
+		u.ForfeitedDays = make(map[string]bool)
+	}
+	u.ForfeitedDays[today] = true
+	return true, true
+}
+
+// habitAddedDay is the day key h was added on, from its ID or CreatedAt;
+// "" if neither tells. Caller holds u.mu.
+func (u *UserData) habitAddedDay(h Habit) string {
+	if created := u.habitCreatedDay(h); created != "" {
+		return created
+	}
+	if !h.CreatedAt.IsZero() {
+		return u.dayKeyAt(h.CreatedAt)
+	}
+	return ""
+}
+
+// removeHabitAt takes the habit at index out of Habits. It builds a new
+// slice rather than shifting in place, so a copy of the old Habits slice held
+// elsewhere never sees its elements move underneath it. Caller holds u.mu.
+func (u *UserData) removeHabitAt(index int) Habit {
+	removed := u.Habits[index]
+	habits := make([]Habit, 0, len(u.Habits)-1)
+	habits = append(habits, u.Habits[:index]...)
+	u.Habits = append(habits, u.Habits[index+1:]...)
+	return removed
 }
 
 func (u *UserData) HabitByIndex(i int) (Habit, bool) {
@@ -245,6 +1459,27 @@ func (u *UserData) HabitByIndex(i int) (Habit, bool) {
 	return u.Habits[i], true
 }
 
+// habitName returns the name of the habit with id, or "" if it's gone. Caller holds u.mu.
+func (u *UserData) habitName(id string) string {
+	for _, h := range u.Habits {
+		if h.ID == id {
+			return h.Name
+		}
+	}
+	return ""
+}
+
+func (u *UserData) HabitByID(id string) (Habit, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, h := range u.Habits {
+		if h.ID == id {
+			return h, true
+		}
+	}
+	return Habit{}, false
+}
+
 // ApplyLevelUpStats adds the given stat increases to the user's stats
 func (u *UserData) ApplyLevelUpStats(str, vit, agi, intel int) {
 	u.mu.Lock()
@@ -255,7 +1490,9 @@ func (u *UserData) ApplyLevelUpStats(str, vit, agi, intel int) {
 	u.INT += intel
 }
 
-// GetHabitNames returns a list of all habit names
+// GetHabitNames returns a list of all habit names.
+// The slice is a fresh copy, safe to hand to a goroutine (e.g. the async
+// level-up call) while habits keep being added or removed.
 func (u *UserData) GetHabitNames() []string {
 	u.mu.Lock()
 	defer u.mu.Unlock()
@@ -266,12 +1503,131 @@ func (u *UserData) GetHabitNames() []string {
 	return names
 }
 
+// RecentHabitDays is how far back RecentHabitNames looks for completions
+const RecentHabitDays = 14
+
+// RecentHabitNames returns every habit name, most completed over the last
+// RecentHabitDays days first; ties keep the quest list order. Callers that
+// can only include a few names (the level-up prompt) keep the active ones.
+func (u *UserData) RecentHabitNames() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	today, _ := time.ParseInLocation(DayKeyLayout, u.TodayKey(), time.Local)
+	counts := make(map[string]int, len(u.Habits))
+	for d := 0; d < RecentHabitDays; d++ {
+		key := today.AddDate(0, 0, -d).Format(DayKeyLayout)
+		for id, done := range u.DailyCompletions[key] {
+			if done {
+				counts[id]++
+			}
+		}
+	}
+	habits := make([]Habit, len(u.Habits))
+	copy(habits, u.Habits)
+	sort.SliceStable(habits, func(i, j int) bool {
+		return counts[habits[i].ID] > counts[habits[j].ID]
+	})
+	names := make([]string, len(habits))
+	for i, h := range habits {
+		names[i] = h.Name
+	}
+	return names
+}
+
+// DataRoot, if set, is the directory DataDir has to lie within once
+// symlinks are followed, so a stray link can't send user records elsewhere
+var DataRoot = ""
+
+// CheckDataDir makes sure DataDir is usable before anything is served: it is
+// created if missing, must be a directory (itself or through a symlink)
+// rather than a file, must resolve inside DataRoot when one is set, and must
+// be writable. A misconfigured deployment then fails at startup with a
+// clear error instead of on every save.
+func CheckDataDir() error {
+	info, err := os.Stat(DataDir) // follows symlinks
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if os.IsNotExist(err) {
+		if li, lerr := os.Lstat(DataDir); lerr == nil && li.Mode()&os.ModeSymlink != 0 {
+			target, _ := os.Readlink(DataDir)
+			return fmt.Errorf("%s is a symlink to %s, which doesn't exist", DataDir, target)
+		}
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is a file, not a directory", DataDir)
+	}
+	if DataRoot != "" {
+		if err := checkWithinRoot(DataDir, DataRoot); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(DataDir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(DataDir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", DataDir, err)
+	}
+	name := f.Name()
+	_, err = f.Write([]byte("ok"))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	os.Remove(name)
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", DataDir, err)
+	}
+	return nil
+}
+
+// checkWithinRoot reports an error unless path, with symlinks followed,
+// is root or inside it. Path need not exist yet.
+func checkWithinRoot(path, root string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return fmt.Errorf("data root: %w", err)
+	}
+	realRoot, err = filepath.Abs(realRoot)
+	if err != nil {
+		return err
+	}
+	real, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(realRoot, real); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s resolves to %s, outside the allowed data root %s", path, real, realRoot)
+	}
+	return nil
+}
+
+// resolvePath returns path made absolute with symlinks followed. Missing
+// trailing components, which MkdirAll is about to create, are kept as
+// written.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var missing []string
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		real, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return filepath.Join(append([]string{real}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) || filepath.Dir(dir) == dir {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(dir)}, missing...)
+	}
+}
+
 func userPath(username string) string {
-	safe := filepath.Clean(username)
+	safe := filepath.Clean(norm.NFC.String(username))
 	if safe == "" || safe == "." || safe == ".." {
 		safe = "default"
 	}
-	return filepath.Join(DataDir, safe+".json")
+	return filepath.Join(DataDir, shardOf(safe), safe+".json")
 }
 
 func LoadUser(username string) (*UserData, error) {
@@ -284,17 +1640,58 @@ func LoadUser(username string) (*UserData, error) {
 	if err := json.Unmarshal(data, &u); err != nil {
 		return nil, err
 	}
+	u.Username = NormalizeUsername(u.Username) // saved before NFC; see MigrateUsernames
 	if u.DailyCompletions == nil {
 		u.DailyCompletions = make(map[string]map[string]bool)
 	}
 	if u.Level < 1 {
 		u.Level = DefaultLevel
 	}
+	if u.EXP < 0 {
+		u.EXP = 0
+	}
+	if want := levelForEXP(u.EXP); u.Level != want {
+		log.Printf("store: %s: level %d does not match %d EXP, corrected to %d", username, u.Level, u.EXP, want)
+		u.Level = want
+	}
 	if u.DayResetHour < 0 || u.DayResetHour > 23 {
 		u.DayResetHour = DefaultResetHour
 	}
+	if !validKeymap(u.Keymap) {
+		u.Keymap = KeymapDefault
+	}
+	if !validTheme(u.Theme) {
+		u.Theme = ThemeSystemBlue
+	}
+	if !validSortMode(u.SortMode) {
+		u.SortMode = SortManual
+	}
+	if !validCompleteKey(u.CompleteKey) {
+		u.CompleteKey = CompleteKeySpace
+	}
+	if !validDateFormat(u.DateFormat) {
+		u.DateFormat = ""
+	}
+	if u.QuestEXP < 0 || u.QuestEXP > MaxQuestEXP {
+		u.QuestEXP = 0
+	}
+	if u.QuietStart < 0 || u.QuietStart > 23 || u.QuietEnd < 0 || u.QuietEnd > 23 {
+		u.QuietStart, u.QuietEnd = 0, 0
+	}
+	for i := range u.Habits {
+		u.Habits[i].Icon = CleanIcon(u.Habits[i].Icon)
+		u.Habits[i].Color = CleanColor(u.Habits[i].Color)
+		if h := u.Habits[i].ReminderHour; h != nil && (*h < 0 || *h > 23) {
+			u.Habits[i].ReminderHour = nil
+		}
+		if t := u.Habits[i].WeeklyTarget; t < 0 || t > MaxWeeklyTarget {
+			u.Habits[i].WeeklyTarget = 0
+		}
+		if u.Habits[i].CreatedAt.IsZero() {
+			u.Habits[i].CreatedAt = u.habitStart(u.Habits[i].ID)
+		}
+	}
 	// Initialize stats with base values for backwards compatibility
-	const baseStats = 10
 	if u.STR == 0 {
 		u.STR = baseStats + u.Level
 	}
@@ -307,6 +1704,7 @@ func LoadUser(username string) (*UserData, error) {
 	if u.INT == 0 {
 		u.INT = baseStats + u.Level
 	}
+	u.warnIntegrity()
 	return &u, nil
 }
 
@@ -316,40 +1714,79 @@ func UserExists(username string) bool {
 	return err == nil
 }
 
+// dummyHash is compared against for unknown usernames, so they take as long
+// to reject as a wrong password
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err) // only fails for passwords over 72 bytes
+	}
+	return hash
+})
+
+// loginFailed is the error for a failed login: err itself if
+// RevealLoginErrors is set, otherwise ErrInvalidCredentials either way
+func loginFailed(err error) error {
+	if RevealLoginErrors {
+		return credentialsError{err}
+	}
+	return ErrInvalidCredentials
+}
+
+// AuthUser checks username and password and loads the user. Wrong usernames
+// and wrong passwords both match ErrInvalidCredentials and take about as long.
+// Too many failures lock the username for a while (ErrAccountLocked).
 func AuthUser(username, password string) (*UserData, error) {
-	username = strings.TrimSpace(strings.ToLower(username))
+	username = NormalizeUsername(username)
 	if username == "" {
-		return nil, fmt.Errorf("username required")
+		return nil, ErrUsernameRequired
+	}
+	if IsDemo(username) {
+		return DemoUserData(), nil // any password, fresh state every time
+	}
+	now := time.Now()
+	if left := lockouts.left(username, now); left > 0 {
+		return nil, lockedError{left: left} // not counted, so waiting it out is enough
 	}
 	u, err := LoadUser(username)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("unknown user")
+			_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+			lockouts.fail(username, now)
+			return nil, loginFailed(fmt.Errorf("%w %q", ErrUserNotFound, username))
 		}
 		return nil, err
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
-		return nil, fmt.Errorf("invalid password")
+		Audit(u.Username, AuditEvent{Type: AuditLoginFailed})
+		if lockouts.fail(username, now) {
+			Audit(u.Username, AuditEvent{Type: AuditLockout})
+		}
+		return nil, loginFailed(ErrInvalidPassword)
 	}
+	lockouts.reset(username)
+	Audit(u.Username, AuditEvent{Type: AuditLogin})
 	return u, nil
 }
 
 func CreateUser(username, password string) (*UserData, error) {
-	username = strings.TrimSpace(strings.ToLower(username))
+	if !AllowRegister {
+		return nil, ErrRegistrationClosed
+	}
+	username = NormalizeUsername(username)
 	if username == "" {
-		return nil, fmt.Errorf("username required")
+		return nil, ErrUsernameRequired
 	}
-	if len(password) < 4 {
-		return nil, fmt.Errorf("password must be at least 4 characters")
+	if err := PasswordRules.Check(password); err != nil {
+		return nil, err
 	}
-	if UserExists(username) {
-		return nil, fmt.Errorf("username already taken")
+	if UserExists(username) || IsDemo(username) {
+		return nil, fmt.Errorf("%w: %q", ErrUsernameTaken, username)
 	}
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
 	}
-	const baseStats = 10
 	u := &UserData{
 		Username:         username,
 		PasswordHash:     string(hash),
@@ -362,6 +1799,15 @@ func CreateUser(username, password string) (*UserData, error) {
 		INT:              baseStats + DefaultLevel,
 		DailyCompletions: make(map[string]map[string]bool),
 		DayResetHour:     DefaultResetHour,
+		Keymap:           KeymapDefault,
+		Theme:            ThemeSystemBlue,
+		SortMode:         SortManual,
+		CompleteKey:      CompleteKeySpace,
+		CreatedAt:        time.Now(),
+	}
+	u.SeasonStart, _ = CurrentSeason(u.CreatedAt)
+	if WelcomeQuestEnabled {
+		u.Habits = append(u.Habits, welcomeQuest())
 	}
 	if err := SaveUser(u); err != nil {
 		return nil, err
@@ -369,7 +1815,57 @@ func CreateUser(username, password string) (*UserData, error) {
 	return u, nil
 }
 
+// SetPassword replaces the user's password and clears MustChangePassword
+func (u *UserData) SetPassword(password string) error {
+	if err := PasswordRules.Check(password); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.PasswordHash = string(hash)
+	u.MustChangePassword = false
+	Audit(u.Username, AuditEvent{Type: AuditPasswordChanged})
+	return nil
+}
+
+// ResetPassword gives username a random temporary password, returned so an
+// admin can pass it on, and forces a password change at the next login
+func ResetPassword(username string) (string, error) {
+	username = NormalizeUsername(username)
+	u, err := LoadUser(username)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%w %q", ErrUserNotFound, username)
+		}
+		return "", err
+	}
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	temp := hex.EncodeToString(buf)
+	hash, err := bcrypt.GenerateFromPassword([]byte(temp), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	u.PasswordHash = string(hash)
+	u.MustChangePassword = true
+	if err := SaveUser(u); err != nil {
+		return "", err
+	}
+	Audit(u.Username, AuditEvent{Type: AuditPasswordReset})
+	flushAudit() // resets come from the admin CLI, which exits right after
+	return temp, nil
+}
+
 func SaveUser(u *UserData) error {
+	if IsDemo(u.Username) {
+		return nil // the demo account lives only in memory
+	}
 	u.mu.Lock()
 	defer u.mu.Unlock()
 	path := userPath(u.Username)
@@ -380,5 +1876,33 @@ func SaveUser(u *UserData) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return writeFileAtomic(path, data, 0644)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a half-written record
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
 }
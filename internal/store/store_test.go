@@ -0,0 +1,935 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testPassword is newUser's password, hashed at the lowest cost to keep
+// tests fast
+const testPassword = "Correct-horse-9"
+
+var testHash = func() string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		panic(err)
+	}
+	return string(hash)
+}()
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "store-test")
+	if err != nil {
+		panic(err)
+	}
+	DataDir = dir
+	code := m.Run()
+	flushAudit()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newUser returns an in-memory hunter with the given quests and a midnight
+// reset, so day keys are calendar dates. Nothing is saved.
+func newUser(habits ...string) *UserData {
+	u := &UserData{
+		Username:         "hunter",
+		PasswordHash:     testHash,
+		Level:            DefaultLevel,
+		DailyCompletions: make(map[string]map[string]bool),
+		Keymap:           KeymapDefault,
+		Theme:            ThemeSystemBlue,
+		SortMode:         SortManual,
+		CompleteKey:      CompleteKeySpace,
+	}
+	for _, name := range habits {
+		u.AddHabit(name)
+	}
+	startedDaysAgo(u, 30)
+	return u
+}
+
+// startedDaysAgo backdates u's account and quests to n days ago
+func startedDaysAgo(u *UserData, n int) {
+	u.CreatedAt = time.Now().AddDate(0, 0, -n)
+	for i := range u.Habits {
+		// IDs carry the day a habit was added; see habitCreatedDay
+		u.Habits[i].ID = fmt.Sprintf("h_%d", u.CreatedAt.UnixNano()+int64(i))
+		u.Habits[i].CreatedAt = u.CreatedAt
+	}
+}
+
+// setFor sets *p to v until the test ends
+func setFor[T any](t *testing.T, p *T, v T) {
+	t.Helper()
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+// useDataDir points DataDir at dir until the test ends. The audit writer
+// reads DataDir, so queued events are written out before each switch.
+func useDataDir(t *testing.T, dir string) {
+	t.Helper()
+	flushAudit()
+	setFor(t, &DataDir, dir)
+	t.Cleanup(flushAudit)
+}
+
+// today is u's current day key, offset by n days
+func today(u *UserData, n int) string {
+	return addDays(u.TodayKey(), n)
+}
+
+func mustToggle(t *testing.T, u *UserData, habitID, day string) {
+	t.Helper()
+	if _, _, err := u.ToggleOnDay(habitID, day); err != nil {
+		t.Fatalf("toggle %s on %s: %v", habitID, day, err)
+	}
+}
+
+// resetHourToYesterday returns a reset hour that turns a midnight-reset
+// today into yesterday. None does in the day's last hour, so the test skips.
+func resetHourToYesterday(t *testing.T) int {
+	t.Helper()
+	h := time.Now().Hour()
+	if h == 23 {
+		t.Skip("no reset hour moves today after 23:00")
+	}
+	return h + 1
+}
+
+func TestUpdateDayResetHourMovesToday(t *testing.T) {
+	setFor(t, &UncheckPolicy, UncheckNoRefund)
+	u := newUser("read", "run")
+	read, run := u.Habits[0].ID, u.Habits[1].ID
+	from := today(u, 0)
+	mustToggle(t, u, read, from)
+	mustToggle(t, u, run, from)
+	mustToggle(t, u, run, from) // unchecked, keeping its EXP
+	exp := u.EXPOn(from)
+
+	if err := u.UpdateDayResetHour(resetHourToYesterday(t)); err != nil {
+		t.Fatal(err)
+	}
+	to := u.TodayKey()
+	if to == from {
+		t.Fatalf("today is still %s", from)
+	}
+	if !u.DailyCompletions[to][read] || u.DailyCompletions[from][read] {
+		t.Errorf("completion not moved from %s to %s: %v", from, to, u.DailyCompletions)
+	}
+	if u.EXPOn(to) != exp || u.EXPOn(from) != 0 {
+		t.Errorf("EXP earned today moved as %d on %s and %d on %s, want %d on %s", u.EXPOn(from), from, u.EXPOn(to), to, exp, to)
+	}
+	if _, ok := u.KeptEXP[to][run]; !ok || len(u.KeptEXP[from]) > 0 {
+		t.Errorf("kept EXP not moved from %s to %s: %v", from, to, u.KeptEXP)
+	}
+	if p := u.PreviewCompletion(run); p.EXP != 0 {
+		t.Errorf("recheck after the move previews %d EXP, want 0", p.EXP)
+	}
+}
+
+func TestUpdateDayResetHourKeepsStreak(t *testing.T) {
+	// A 5:00 reset moved to 23:00 turns today into yesterday, except in the
+	// hours where both resets give the same day; there 0:00 to the next
+	// hour stands in
+	before, after := 5, 23
+	if h := time.Now().Hour(); h < before || h >= after {
+		before, after = 0, resetHourToYesterday(t)
+	}
+	tests := []struct {
+		name        string
+		todayDone   bool
+		streakAfter int
+	}{
+		{"today complete too", true, 1},
+		{"today open", false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("read")
+			u.DayResetHour = before
+			read := u.Habits[0].ID
+			mustToggle(t, u, read, today(u, -1))
+			u.RecomputeStreak() // yesterday counted: the streak is 1
+			if tt.todayDone {
+				mustToggle(t, u, read, today(u, 0))
+				u.UpdateStreak()
+			}
+
+			if err := u.UpdateDayResetHour(after); err != nil {
+				t.Fatal(err)
+			}
+			u.UpdateStreak()
+			if u.CurrentStreak != tt.streakAfter || u.LastCompleteDay != u.TodayKey() {
+				t.Errorf("streak = %d through %s, want %d through %s", u.CurrentStreak, u.LastCompleteDay, tt.streakAfter, u.TodayKey())
+			}
+		})
+	}
+}
+
+// captureAudit collects the events audited until the test ends
+func captureAudit(t *testing.T) *[]AuditEvent {
+	t.Helper()
+	var events []AuditEvent
+	setFor(t, &OnAudit, func(_ string, ev AuditEvent) { events = append(events, ev) })
+	return &events
+}
+
+// countAudited counts the events of type typ
+func countAudited(events []AuditEvent, typ string) int {
+	n := 0
+	for _, ev := range events {
+		if ev.Type == typ {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDayCompleteAnnouncedOnce(t *testing.T) {
+	u := newUser("read", "run")
+	events := captureAudit(t)
+	day := today(u, 0)
+	toggle := func(id string) {
+		mustToggle(t, u, id, day)
+		u.UpdateStreak()
+	}
+	toggle(u.Habits[0].ID)
+	toggle(u.Habits[1].ID)
+	toggle(u.Habits[1].ID) // unchecked: the day is open again
+	toggle(u.Habits[1].ID)
+	if n := countAudited(*events, AuditDayComplete); n != 1 {
+		t.Errorf("day_complete audited %d times, want 1", n)
+	}
+	if u.CurrentStreak != 1 {
+		t.Errorf("streak = %d, want 1", u.CurrentStreak)
+	}
+}
+
+// TestGetHabitNamesConcurrent is meant for -race: the names handed to the
+// level-up goroutine must not share memory with habits still being edited
+func TestGetHabitNamesConcurrent(t *testing.T) {
+	u := newUser("read", "run", "write")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			u.AddHabit(fmt.Sprintf("quest %d", i))
+			u.RemoveHabit(0)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			names := u.GetHabitNames()
+			for j := range names {
+				names[j] += "!" // the caller owns the copy
+			}
+		}
+	}()
+	wg.Wait()
+	for _, name := range u.GetHabitNames() {
+		if len(name) > 0 && name[len(name)-1] == '!' {
+			t.Errorf("habit renamed through GetHabitNames' result: %q", name)
+		}
+	}
+}
+
+func TestEditHabitKeepsHistory(t *testing.T) {
+	u := newUser("read", "run")
+	u.QuestEXP = 25 // completions record what they paid in CompletionEXP
+	h := u.Habits[0]
+	days := []string{today(u, -2), today(u, -1), today(u, 0)}
+	for _, day := range days {
+		mustToggle(t, u, h.ID, day)
+	}
+	paid := make(map[string]int)
+	for _, day := range days {
+		paid[day] = u.earnedEXP(day, h.ID)
+	}
+	streak, rate := u.HabitStreak(h.ID), u.HabitCompletionRate(h.ID)
+
+	changes := h
+	changes.ID = "h_replaced" // ignored: only the editable fields are taken
+	changes.Name = "read 20 pages"
+	if err := u.EditHabit(h.ID, changes); err != nil {
+		t.Fatal(err)
+	}
+	edited, ok := u.HabitByID(h.ID)
+	if !ok || edited.Name != "read 20 pages" {
+		t.Fatalf("quest %s after the rename: %+v, found %v", h.ID, edited, ok)
+	}
+	if _, ok := u.HabitByID("h_replaced"); ok {
+		t.Error("EditHabit took the ID from changes")
+	}
+	for _, day := range days {
+		if !u.DailyCompletions[day][h.ID] {
+			t.Errorf("completion on %s lost", day)
+		}
+		if exp := u.earnedEXP(day, h.ID); exp != paid[day] {
+			t.Errorf("completion on %s paid %d after the rename, want %d", day, exp, paid[day])
+		}
+		if _, ok := u.CompletionEXP[day][h.ID]; !ok {
+			t.Errorf("CompletionEXP for %s on %s lost", h.ID, day)
+		}
+	}
+	if got := u.HabitStreak(h.ID); got != streak || got != len(days) {
+		t.Errorf("streak after the rename = %d, want %d", got, len(days))
+	}
+	if got := u.HabitCompletionRate(h.ID); got != rate {
+		t.Errorf("completion rate after the rename = %v, want %v", got, rate)
+	}
+}
+
+func TestHardcorePenalty(t *testing.T) {
+	setFor(t, &HardcorePenaltyPercent, 5)
+	tests := []struct {
+		name   string
+		level  int
+		exp    int
+		missed int
+		want   int
+	}{
+		{"no miss", 1, 80, 0, 0},
+		{"one day", 1, 80, 1, 4},
+		{"doubles each day", 1, 80, 3, 16},
+		{"capped at everything", 1, 80, 10, 80},
+		{"never costs a level", 3, 210, 3, 10},
+		{"at the level floor", 3, 200, 5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser()
+			u.Level, u.EXP = tt.level, tt.exp
+			if got := u.HardcorePenalty(tt.missed); got != tt.want {
+				t.Errorf("HardcorePenalty(%d) = %d, want %d", tt.missed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckStreakBreak(t *testing.T) {
+	tests := []struct {
+		name     string
+		hardcore bool
+		last     int // offset of the last complete day
+		streak   int // afterwards
+		penalty  bool
+	}{
+		{"yesterday", false, -1, 5, false},
+		{"missed a day", false, -2, 0, false},
+		{"hardcore yesterday", true, -1, 5, false},
+		{"hardcore", true, -3, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("read")
+			u.HardcoreMode = tt.hardcore
+			u.EXP = 80
+			u.CurrentStreak, u.LastCompleteDay = 5, today(u, tt.last)
+			penalty, _ := u.CheckStreakBreak()
+			if u.CurrentStreak != tt.streak {
+				t.Errorf("streak = %d, want %d", u.CurrentStreak, tt.streak)
+			}
+			if (penalty > 0) != tt.penalty || u.EXP != 80-penalty {
+				t.Errorf("penalty = %d, EXP %d, want a penalty %v", penalty, u.EXP, tt.penalty)
+			}
+			if u.Level != levelForEXP(u.EXP) {
+				t.Errorf("level %d doesn't match %d EXP after the penalty", u.Level, u.EXP)
+			}
+		})
+	}
+}
+
+func TestHabitStreak(t *testing.T) {
+	tests := []struct {
+		name string
+		days []int // offsets from today the quest was done
+		want int
+	}{
+		{"never", nil, 0},
+		{"today only", []int{0}, 1},
+		{"today not done yet", []int{-2, -1}, 2},
+		{"through today", []int{-2, -1, 0}, 3},
+		{"gap yesterday", []int{-3, -2, 0}, 1},
+		{"gap before yesterday", []int{-4, -3, -1}, 1},
+		{"missed yesterday, today open", []int{-3, -2}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("read")
+			for _, d := range tt.days {
+				u.DailyCompletions[today(u, d)] = map[string]bool{u.Habits[0].ID: true}
+			}
+			if got := u.HabitStreak(u.Habits[0].ID); got != tt.want {
+				t.Errorf("HabitStreak = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHabitStreakPerQuest(t *testing.T) {
+	u := newUser("read", "run")
+	read, run := u.Habits[0].ID, u.Habits[1].ID
+	for _, d := range []int{-2, -1, 0} {
+		mustToggle(t, u, read, today(u, d))
+	}
+	mustToggle(t, u, run, today(u, -1))
+	if got := u.HabitStreak(read); got != 3 {
+		t.Errorf("read streak = %d, want 3", got)
+	}
+	if got := u.HabitStreak(run); got != 1 {
+		t.Errorf("run streak = %d, want 1 while today is open", got)
+	}
+	u.RecomputeStreak()
+	if u.CurrentStreak != 1 {
+		t.Errorf("overall streak = %d, want 1: only yesterday had both done", u.CurrentStreak)
+	}
+}
+
+func TestToggleOnDay(t *testing.T) {
+	u := newUser("read")
+	tests := []struct {
+		name string
+		day  string
+		ok   bool
+	}{
+		{"today", today(u, 0), true},
+		{"yesterday", today(u, -1), true},
+		{"oldest backfill", today(u, -BackfillDays), true},
+		{"too old", today(u, -BackfillDays-1), false},
+		{"tomorrow", today(u, 1), false},
+		{"not a day", "yesterday", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gained, _, err := u.ToggleOnDay(u.Habits[0].ID, tt.day)
+			if (err == nil) != tt.ok {
+				t.Fatalf("ToggleOnDay(%s) error = %v, want ok %v", tt.day, err, tt.ok)
+			}
+			if done := u.CompletedOn(u.Habits[0].ID, tt.day); done != tt.ok || gained != tt.ok {
+				t.Errorf("done %v, gained EXP %v, want %v", done, gained, tt.ok)
+			}
+		})
+	}
+	if u.EXP != 3*EXPPerQuest {
+		t.Errorf("EXP = %d, want three completions' worth", u.EXP)
+	}
+}
+
+func TestRecomputeStreak(t *testing.T) {
+	tests := []struct {
+		name   string
+		days   []int // offsets from today the quest was done
+		streak int
+	}{
+		{"none", nil, 0},
+		{"today", []int{0}, 1},
+		{"yesterday, today still open", []int{-1}, 1},
+		{"backfilled run", []int{-3, -2, -1, 0}, 4},
+		{"gap", []int{-3, -1, 0}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("read")
+			for _, d := range tt.days {
+				mustToggle(t, u, u.Habits[0].ID, today(u, d))
+			}
+			u.RecomputeStreak()
+			if u.CurrentStreak != tt.streak || u.HabitStreak(u.Habits[0].ID) != tt.streak {
+				t.Errorf("streak = %d, quest streak %d, want %d", u.CurrentStreak, u.HabitStreak(u.Habits[0].ID), tt.streak)
+			}
+		})
+	}
+}
+
+func TestLoadUserFixesLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     int
+		exp       int
+		wantLevel int
+		wantEXP   int
+	}{
+		{"consistent", 3, 250, 3, 250},
+		{"level too high", 7, 250, 3, 250},
+		{"level too low", 1, 450, 5, 450},
+		{"no level", 0, 120, 2, 120},
+		{"negative EXP", 4, -30, 1, 0},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser()
+			u.Username = fmt.Sprintf("mismatched%d", i)
+			u.Level, u.EXP = tt.level, tt.exp
+			if err := SaveUser(u); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadUser(u.Username)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Level != tt.wantLevel || loaded.EXP != tt.wantEXP {
+				t.Errorf("loaded level %d with %d EXP, want level %d with %d", loaded.Level, loaded.EXP, tt.wantLevel, tt.wantEXP)
+			}
+		})
+	}
+}
+
+func TestUpdateKeymap(t *testing.T) {
+	u := newUser()
+	for _, keymap := range Keymaps {
+		if err := u.UpdateKeymap(keymap); err != nil || u.Keymap != keymap {
+			t.Errorf("UpdateKeymap(%q) = %v, keymap %q", keymap, err, u.Keymap)
+		}
+	}
+	if err := u.UpdateKeymap("emacs"); err == nil || u.Keymap != Keymaps[len(Keymaps)-1] {
+		t.Errorf("UpdateKeymap(emacs) = %v, keymap %q", err, u.Keymap)
+	}
+}
+
+func TestUpdateTheme(t *testing.T) {
+	u := newUser()
+	for _, theme := range Themes {
+		if err := u.UpdateTheme(theme); err != nil || u.Theme != theme {
+			t.Errorf("UpdateTheme(%q) = %v, theme %q", theme, err, u.Theme)
+		}
+	}
+	if err := u.UpdateTheme("neon"); err == nil || u.Theme != Themes[len(Themes)-1] {
+		t.Errorf("UpdateTheme(neon) = %v, theme %q", err, u.Theme)
+	}
+}
+
+func TestUpdateCompleteKey(t *testing.T) {
+	u := newUser()
+	for _, key := range CompleteKeys {
+		if err := u.UpdateCompleteKey(key); err != nil || u.CompleteKey != key {
+			t.Errorf("UpdateCompleteKey(%q) = %v, key %q", key, err, u.CompleteKey)
+		}
+	}
+	if err := u.UpdateCompleteKey("x"); err == nil || u.CompleteKey != CompleteKeys[len(CompleteKeys)-1] {
+		t.Errorf("UpdateCompleteKey(x) = %v, key %q", err, u.CompleteKey)
+	}
+}
+
+func TestQuestEXP(t *testing.T) {
+	u := newUser("read")
+	if err := u.UpdateQuestEXP(25); err != nil {
+		t.Fatal(err)
+	}
+	mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+	if err := u.UpdateQuestEXP(EXPPerQuest); err != nil {
+		t.Fatal(err)
+	}
+	if u.QuestEXP != 0 {
+		t.Errorf("QuestEXP = %d, want the default stored as 0", u.QuestEXP)
+	}
+	mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+	if u.EXP != 0 {
+		t.Errorf("EXP after unchecking = %d, want the 25 it paid taken back", u.EXP)
+	}
+}
+
+func TestUpdateQuestEXPRange(t *testing.T) {
+	u := newUser()
+	for _, exp := range []int{MinQuestEXP - 1, MaxQuestEXP + 1} {
+		if err := u.UpdateQuestEXP(exp); err == nil || u.QuestEXP != 0 {
+			t.Errorf("UpdateQuestEXP(%d) = %v, QuestEXP %d", exp, err, u.QuestEXP)
+		}
+	}
+	if err := u.UpdateQuestEXP(MaxQuestEXP); err != nil || u.QuestEXP != MaxQuestEXP {
+		t.Errorf("UpdateQuestEXP(%d) = %v, QuestEXP %d", MaxQuestEXP, err, u.QuestEXP)
+	}
+}
+
+func TestUpdateDateFormat(t *testing.T) {
+	u := newUser()
+	for _, format := range DateFormats {
+		if err := u.UpdateDateFormat(format); err != nil || u.DateFormat != format {
+			t.Errorf("UpdateDateFormat(%q) = %v, format %q", format, err, u.DateFormat)
+		}
+	}
+	if err := u.UpdateDateFormat("julian"); err == nil || u.DateFormat != DateFormats[len(DateFormats)-1] {
+		t.Errorf("UpdateDateFormat(julian) = %v, format %q", err, u.DateFormat)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	if _, err := CreateUser("forgetful", testPassword); err != nil {
+		t.Fatal(err)
+	}
+	temp, err := ResetPassword("Forgetful")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AuthUser("forgetful", testPassword); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("old password after the reset: %v", err)
+	}
+	u, err := AuthUser("forgetful", temp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.MustChangePassword {
+		t.Error("a reset password doesn't force a change")
+	}
+	if err := u.SetPassword("Another-horse-7"); err != nil || u.MustChangePassword {
+		t.Errorf("SetPassword = %v, must change %v", err, u.MustChangePassword)
+	}
+	if _, err := ResetPassword("nobody"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("reset for an unknown user: %v", err)
+	}
+}
+
+func TestCheckDataDirCreates(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "new", "data")
+	useDataDir(t, dir)
+	if err := CheckDataDir(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("write check left %v behind", entries)
+	}
+}
+
+func TestCheckDataDirReadOnly(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to a read-only directory")
+	}
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0755) })
+	useDataDir(t, dir)
+	if err := CheckDataDir(); err == nil || !strings.Contains(err.Error(), "not writable") {
+		t.Errorf("CheckDataDir() = %v, want it not writable", err)
+	}
+}
+
+func TestCheckDataDirPaths(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	dangling := filepath.Join(root, "dangling")
+	if err := os.Symlink(filepath.Join(root, "missing"), dangling); err != nil {
+		t.Fatal(err)
+	}
+	linked := filepath.Join(root, "linked")
+	if err := os.Symlink(t.TempDir(), linked); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		dir  string
+		root string
+		ok   bool
+	}{
+		{"a file", file, "", false},
+		{"dangling symlink", dangling, "", false},
+		{"symlink to a directory", linked, "", true},
+		{"inside the root", filepath.Join(root, "inside"), root, true},
+		{"symlink out of the root", linked, root, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useDataDir(t, tt.dir)
+			setFor(t, &DataRoot, tt.root)
+			if err := CheckDataDir(); (err == nil) != tt.ok {
+				t.Errorf("CheckDataDir() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAuthUserErrors(t *testing.T) {
+	setFor(t, &RevealLoginErrors, true)
+	setFor(t, &LockoutAttempts, 0)
+	u := newUser()
+	u.Username = "autherrors"
+	if err := SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     error
+	}{
+		{"ok", "AuthErrors", testPassword, nil},
+		{"no username", " ", testPassword, ErrUsernameRequired},
+		{"unknown user", "nobody", testPassword, ErrUserNotFound},
+		{"wrong password", "autherrors", "wrong", ErrInvalidPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AuthUser(tt.username, tt.password)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("AuthUser error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && got.Username != u.Username {
+				t.Errorf("logged in as %q, want %q", got.Username, u.Username)
+			}
+		})
+	}
+}
+
+func TestAuthUserHidesCause(t *testing.T) {
+	setFor(t, &LockoutAttempts, 0)
+	u := newUser()
+	u.Username = "hidden"
+	if err := SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		username string
+		password string
+		cause    error
+	}{
+		{"unknown user", "nobody", testPassword, ErrUserNotFound},
+		{"wrong password", "hidden", "wrong", ErrInvalidPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFor(t, &RevealLoginErrors, false)
+			_, err := AuthUser(tt.username, tt.password)
+			if !errors.Is(err, ErrInvalidCredentials) || errors.Is(err, tt.cause) {
+				t.Errorf("hidden: error = %v, want only %v", err, ErrInvalidCredentials)
+			}
+			RevealLoginErrors = true
+			_, err = AuthUser(tt.username, tt.password)
+			if !errors.Is(err, ErrInvalidCredentials) || !errors.Is(err, tt.cause) {
+				t.Errorf("revealed: error = %v, want %v and %v", err, ErrInvalidCredentials, tt.cause)
+			}
+		})
+	}
+}
+
+func TestCreateUserErrors(t *testing.T) {
+	if _, err := CreateUser("taken", testPassword); err != nil {
+		t.Fatal(err)
+	}
+	setFor(t, &PasswordRules, PasswordPolicy{MinLength: 8})
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     error
+	}{
+		{"ok", "fresh", testPassword, nil},
+		{"no username", "  ", testPassword, ErrUsernameRequired},
+		{"taken", "Taken", testPassword, ErrUsernameTaken},
+		{"weak", "weak", "abc", ErrWeakPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := CreateUser(tt.username, tt.password)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("CreateUser error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && (!UserExists(u.Username) || u.Level != DefaultLevel) {
+				t.Errorf("created %+v", u)
+			}
+			if tt.want == ErrWeakPassword && UserExists(tt.username) {
+				t.Error("an account with a refused password was saved")
+			}
+		})
+	}
+}
+
+func TestHabitCompletionRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		created int   // days ago the quest was added
+		done    []int // offsets from today the quest was done
+		want    float64
+	}{
+		{"added today, open", 0, nil, 0},
+		{"added today, done", 0, []int{0}, 1},
+		{"no completions", 3, nil, 0},
+		{"partial", 3, []int{-1, 0}, 0.5},
+		{"full", 3, []int{-3, -2, -1, 0}, 1},
+		{"today still open", 3, []int{-3, -2, -1}, 0.75},
+		{"backfill before it was added", 3, []int{-5, -1, 0}, 3.0 / 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("read")
+			h := u.Habits[0]
+			u.Habits[0].CreatedAt = time.Now().AddDate(0, 0, -tt.created)
+			for _, d := range tt.done {
+				u.DailyCompletions[today(u, d)] = map[string]bool{h.ID: true}
+			}
+			if got := u.HabitCompletionRate(h.ID); got != tt.want {
+				t.Errorf("HabitCompletionRate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateUserClosed(t *testing.T) {
+	setFor(t, &AllowRegister, false)
+	if _, err := CreateUser("latecomer", testPassword); !errors.Is(err, ErrRegistrationClosed) {
+		t.Fatalf("CreateUser with registration closed = %v, want ErrRegistrationClosed", err)
+	}
+	if UserExists("latecomer") {
+		t.Error("an account was saved with registration closed")
+	}
+}
+
+func TestWelcomeQuest(t *testing.T) {
+	setFor(t, &WelcomeQuestEnabled, true)
+	u, err := CreateUser("welcomed", testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u.Habits) != 1 || u.Habits[0].ID != WelcomeQuestID {
+		t.Fatalf("new account's quests = %+v, want the welcome quest", u.Habits)
+	}
+	if u.AllQuestsCompletedToday() {
+		t.Error("today is perfect with the welcome quest still open")
+	}
+	gained, _, err := u.ToggleToday(WelcomeQuestID)
+	if err != nil || !gained {
+		t.Fatalf("ToggleToday(welcome) = %v, %v", gained, err)
+	}
+	if len(u.Habits) != 0 || u.EXP != WelcomeQuestBonus || u.EXPOn(u.TodayKey()) != WelcomeQuestBonus {
+		t.Errorf("after the welcome quest: quests %+v, EXP %d", u.Habits, u.EXP)
+	}
+	if u.CompletedToday(WelcomeQuestID) {
+		t.Error("the welcome quest recorded a daily completion")
+	}
+}
+
+func TestWelcomeQuestOff(t *testing.T) {
+	setFor(t, &WelcomeQuestEnabled, false)
+	u, err := CreateUser("unwelcomed", testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u.Habits) != 0 {
+		t.Errorf("new account's quests = %+v, want none", u.Habits)
+	}
+}
+
+func TestNearLevelUp(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  int
+		cap  int
+		done bool // the only quest is done already
+		want bool
+	}{
+		{"one quest away", 95, 0, false, true},
+		{"exactly one quest", 90, 0, false, true},
+		{"two quests away", 85, 0, false, false},
+		{"nothing left to do", 95, 0, true, false},
+		{"at the level cap", 95, 1, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFor(t, &LevelCap, tt.cap)
+			u := newUser("read")
+			u.EXP = tt.exp
+			if tt.done {
+				u.DailyCompletions[today(u, 0)] = map[string]bool{u.Habits[0].ID: true}
+			}
+			if got := u.NearLevelUp(); got != tt.want {
+				t.Errorf("NearLevelUp = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEXPProgressFor(t *testing.T) {
+	tests := []struct {
+		level, exp int
+		total      bool
+		have, need int
+	}{
+		{1, 40, false, 40, 100},
+		{3, 250, false, 50, 100},
+		{3, 250, true, 250, 300},
+	}
+	for _, tt := range tests {
+		if have, need := EXPProgressFor(tt.level, tt.exp, tt.total); have != tt.have || need != tt.need {
+			t.Errorf("EXPProgressFor(%d, %d, %v) = %d/%d, want %d/%d", tt.level, tt.exp, tt.total, have, need, tt.have, tt.need)
+		}
+	}
+}
+
+func TestRemoveHabitForfeits(t *testing.T) {
+	tests := []struct {
+		name      string
+		done      bool // the removed quest was done today
+		addedNow  bool // the removed quest was added today
+		forfeited bool
+	}{
+		{"open quest", false, false, true},
+		{"done quest", true, false, false},
+		{"added today", false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("read", "run")
+			if tt.addedNow {
+				u.Habits[1] = u.AddHabit("run")
+				u.Habits = u.Habits[:2]
+			}
+			mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+			if tt.done {
+				mustToggle(t, u, u.Habits[1].ID, today(u, 0))
+			}
+			removed, forfeited := u.RemoveHabit(1)
+			if !removed || forfeited != tt.forfeited {
+				t.Fatalf("RemoveHabit = %v, %v, want forfeited %v", removed, forfeited, tt.forfeited)
+			}
+			u.UpdateStreak()
+			if perfect := u.CurrentStreak == 1; perfect == tt.forfeited {
+				t.Errorf("streak = %d after removing, want a perfect day %v", u.CurrentStreak, !tt.forfeited)
+			}
+		})
+	}
+}
+
+func TestToggleMany(t *testing.T) {
+	u := newUser("read", "run")
+	u.EXP, u.Level = 190, 2
+	ids := []string{u.Habits[0].ID, u.Habits[1].ID}
+	if exp, levels := u.ToggleMany(ids, true); exp != 2*EXPPerQuest || levels != 1 || u.Level != 3 {
+		t.Errorf("completing both = %d EXP, %d levels; level %d", exp, levels, u.Level)
+	}
+	if exp, levels := u.ToggleMany(ids, false); exp != -2*EXPPerQuest || levels != -1 || u.Level != 2 {
+		t.Errorf("unchecking both = %d EXP, %d levels; level %d", exp, levels, u.Level)
+	}
+}
+
+func TestAddHabits(t *testing.T) {
+	setFor(t, &MaxHabits, 4)
+	u := newUser("Read")
+	added, skipped := u.AddHabits([]string{"run", " ", "read", "write", "RUN", "stretch", "sleep"})
+	if added != 3 || !slices.Equal(skipped, []string{"read", "RUN", "sleep"}) {
+		t.Errorf("AddHabits = %d, %q; want 3 added, duplicates and the one over the limit skipped", added, skipped)
+	}
+	if names := u.GetHabitNames(); !slices.Equal(names, []string{"Read", "run", "write", "stretch"}) {
+		t.Errorf("quests = %q", names)
+	}
+}
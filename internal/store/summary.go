@@ -0,0 +1,81 @@
+package store
+
+// UserSummary is an admin's one-line view of a user: progress and activity,
+// never credentials or history
+type UserSummary struct {
+	Username      string `json:"username"`
+	Level         int    `json:"level"`
+	CurrentStreak int    `json:"current_streak"`
+	Habits        int    `json:"habits"`
+	QuestsToday   int    `json:"quests_today"`          // completed in the user's current quest day
+	LastActive    string `json:"last_active,omitempty"` // day key of the latest completion; empty if none
+}
+
+// UserSummaries loads every user and summarizes them, ordered by username.
+// Unreadable records are skipped, as on the leaderboard.
+func UserSummaries() ([]UserSummary, error) {
+	names, err := ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]UserSummary, 0, len(names))
+	for _, name := range names {
+		u, err := LoadUser(name)
+		if err != nil {
+			continue
+		}
+		summaries = append(summaries, u.Summary())
+	}
+	return summaries, nil
+}
+
+// Summary returns u's admin summary
+func (u *UserData) Summary() UserSummary {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	today := u.TodayKey()
+	s := UserSummary{
+		Username:      u.Username,
+		Level:         u.Level,
+		CurrentStreak: u.CurrentStreak,
+		Habits:        len(u.Habits),
+	}
+	for _, done := range u.DailyCompletions[today] {
+		if done {
+			s.QuestsToday++
+		}
+	}
+	for day, completions := range u.DailyCompletions {
+		if day <= s.LastActive {
+			continue
+		}
+		for _, done := range completions {
+			if done {
+				s.LastActive = day
+				break
+			}
+		}
+	}
+	return s
+}
+
+// ServerStats are totals across every user, for the login banner
+type ServerStats struct {
+	Hunters     int
+	QuestsToday int // summed over each hunter's own current quest day
+	TopLevel    int
+}
+
+// ComputeServerStats scans every user for ServerStats
+func ComputeServerStats() (ServerStats, error) {
+	users, err := UserSummaries()
+	if err != nil {
+		return ServerStats{}, err
+	}
+	st := ServerStats{Hunters: len(users)}
+	for _, u := range users {
+		st.QuestsToday += u.QuestsToday
+		st.TopLevel = max(st.TopLevel, u.Level)
+	}
+	return st, nil
+}
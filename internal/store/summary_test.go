@@ -0,0 +1,32 @@
+package store
+
+import "testing"
+
+func TestSummary(t *testing.T) {
+	u := newUser("read", "run", "write")
+	u.CurrentStreak = 4
+	mustToggle(t, u, u.Habits[0].ID, today(u, -3))
+	mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+	mustToggle(t, u, u.Habits[1].ID, today(u, 0))
+	mustToggle(t, u, u.Habits[1].ID, today(u, 0)) // unchecked: not counted
+	s := u.Summary()
+	want := UserSummary{Username: "hunter", Level: 1, CurrentStreak: 4, Habits: 3, QuestsToday: 1, LastActive: today(u, 0)}
+	if s != want {
+		t.Errorf("Summary() = %+v, want %+v", s, want)
+	}
+	if s := newUser("read").Summary(); s.LastActive != "" || s.QuestsToday != 0 {
+		t.Errorf("a hunter with no history: %+v", s)
+	}
+}
+
+func TestComputeServerStats(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	saveHunters(t, 2, 6, 4)
+	st, err := ComputeServerStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ServerStats{Hunters: 3, QuestsToday: 3, TopLevel: 6}); st != want {
+		t.Errorf("ComputeServerStats() = %+v, want %+v", st, want)
+	}
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned by VerifyAPIToken for any token that doesn't
+// resolve to a user, without saying why
+var ErrInvalidToken = errors.New("invalid API token")
+
+// MaxAPITokens caps how many API tokens a user can hold at once
+const MaxAPITokens = 10
+
+// MaxTokenLabelRunes caps the label given to an API token
+const MaxTokenLabelRunes = 32
+
+// apiTokenPrefix starts every API token, so a leaked one is easy to spot
+const apiTokenPrefix = "sys_"
+
+// TokenInfo describes an API token. Only a hash of the token is stored; the
+// plaintext is shown once, when it's generated.
+type TokenInfo struct {
+	ID      string    `json:"id"`    // Short public handle used to revoke it
+	Label   string    `json:"label"` // What the token is for, e.g. "phone widget"
+	Hash    string    `json:"hash"`  // Hex SHA-256 of the full token
+	Created time.Time `json:"created"`
+}
+
+// GenerateAPIToken creates a token for u and returns its plaintext, which is
+// never stored. The token carries the username so it can be verified
+// without scanning every user. The caller saves u.
+func GenerateAPIToken(u *UserData, label string) (string, error) {
+	if IsDemo(u.Username) {
+		return "", fmt.Errorf("the demo account can't have API tokens")
+	}
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return "", fmt.Errorf("token label required")
+	}
+	if len([]rune(label)) > MaxTokenLabelRunes {
+		return "", fmt.Errorf("token label is longer than %d characters", MaxTokenLabelRunes)
+	}
+	secret := make([]byte, 32)
+	id := make([]byte, 4)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.APITokens) >= MaxAPITokens {
+		return "", fmt.Errorf("at most %d API tokens; revoke one first", MaxAPITokens)
+	}
+	token := apiTokenPrefix + hex.EncodeToString([]byte(u.Username)) + "_" + hex.EncodeToString(secret)
+	info := TokenInfo{ID: hex.EncodeToString(id), Label: label, Hash: hashToken(token), Created: time.Now()}
+	u.APITokens = append(u.APITokens, info)
+	Audit(u.Username, AuditEvent{Type: AuditTokenCreated, Detail: info.Label})
+	return token, nil
+}
+
+// VerifyAPIToken loads the user a token belongs to. Any malformed, unknown
+// or revoked token gives ErrInvalidToken.
+func VerifyAPIToken(token string) (*UserData, error) {
+	rest, ok := strings.CutPrefix(token, apiTokenPrefix)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	encoded, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	name, err := hex.DecodeString(encoded)
+	username := string(name)
+	if err != nil || username == "" || strings.ContainsAny(username, `/\`) {
+		return nil, ErrInvalidToken
+	}
+	u, err := LoadUser(username)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	hash := []byte(hashToken(token))
+	for _, t := range u.APITokens {
+		if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) == 1 {
+			return u, nil
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+// RevokeAPIToken deletes the token with id so it stops working
+func (u *UserData) RevokeAPIToken(id string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i, t := range u.APITokens {
+		if t.ID != id {
+			continue
+		}
+		tokens := make([]TokenInfo, 0, len(u.APITokens)-1)
+		tokens = append(tokens, u.APITokens[:i]...)
+		u.APITokens = append(tokens, u.APITokens[i+1:]...)
+		Audit(u.Username, AuditEvent{Type: AuditTokenRevoked, Detail: t.Label})
+		return nil
+	}
+	return fmt.Errorf("unknown API token %q", id)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		label string
+		held  int
+		ok    bool
+	}{
+		{"ok", "phone widget", 0, true},
+		{"no label", "  ", 0, false},
+		{"long label", strings.Repeat("x", MaxTokenLabelRunes+1), 0, false},
+		{"at the limit", "one more", MaxAPITokens, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser()
+			for i := 0; i < tt.held; i++ {
+				u.APITokens = append(u.APITokens, TokenInfo{ID: fmt.Sprint(i)})
+			}
+			token, err := GenerateAPIToken(u, tt.label)
+			if (err == nil) != tt.ok {
+				t.Fatalf("GenerateAPIToken(%q) error = %v, want ok %v", tt.label, err, tt.ok)
+			}
+			if !tt.ok {
+				if len(u.APITokens) != tt.held {
+					t.Error("a refused token was added")
+				}
+				return
+			}
+			info := u.APITokens[len(u.APITokens)-1]
+			if !strings.HasPrefix(token, apiTokenPrefix) || info.Hash != hashToken(token) || strings.Contains(info.Hash, token) {
+				t.Errorf("token %q stored as %+v", token, info)
+			}
+		})
+	}
+}
+
+func TestVerifyAPIToken(t *testing.T) {
+	u := newUser()
+	u.Username = "tokenholder"
+	token, err := GenerateAPIToken(u, "widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := GenerateAPIToken(u, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.RevokeAPIToken(u.APITokens[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.RevokeAPIToken("nope"); err == nil {
+		t.Error("revoked an unknown token")
+	}
+	if err := SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+	other := "sys_" + fmt.Sprintf("%x", "nobody") + token[strings.LastIndex(token, "_"):]
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", token, true},
+		{"revoked", revoked, false},
+		{"another user's name", other, false},
+		{"no prefix", strings.TrimPrefix(token, apiTokenPrefix), false},
+		{"bad hex", "sys_zz_00", false},
+		{"path in the name", "sys_" + fmt.Sprintf("%x", "../tokenholder") + "_00", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyAPIToken(tt.token)
+			if tt.ok {
+				if err != nil || got.Username != u.Username {
+					t.Errorf("VerifyAPIToken = %v, %v", got, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("VerifyAPIToken = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeUsername is the canonical form of a username, used for its file
+// path and every comparison: trimmed, lowercased and in Unicode NFC, so
+// "café" typed with a precomposed é and with e plus a combining accent is
+// the same hunter.
+func NormalizeUsername(name string) string {
+	return norm.NFC.String(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// MigrateUsernames renames user files (.json, .log, .log.1) saved under a
+// username that isn't in NFC, which older versions allowed, to the NFC name
+// they are now looked up by, and returns how many users were renamed. One
+// whose NFC file already exists is a duplicate account; it is left alone
+// and logged. It is safe to run on every start, after MigrateFlatLayout.
+func MigrateUsernames() (int, error) {
+	shards, err := os.ReadDir(DataDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	moved := 0
+	for _, shard := range shards {
+		if !shard.IsDir() || !isShardDir(shard.Name()) {
+			continue
+		}
+		dir := filepath.Join(DataDir, shard.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return moved, err
+		}
+		for _, e := range entries {
+			name, ok := strings.CutSuffix(e.Name(), ".json")
+			if e.IsDir() || !ok || norm.NFC.IsNormalString(name) {
+				continue
+			}
+			dest := userPath(name)
+			if _, err := os.Stat(dest); err == nil {
+				log.Printf("store: not renaming %s: %s already exists", filepath.Join(dir, e.Name()), dest)
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return moved, err
+			}
+			// Logs first, as in MigrateFlatLayout
+			oldLog := filepath.Join(dir, name+".log")
+			for _, suffix := range []string{"", ".1"} {
+				err := os.Rename(oldLog+suffix, auditPath(name)+suffix)
+				if err != nil && !os.IsNotExist(err) {
+					return moved, err
+				}
+			}
+			if err := os.Rename(filepath.Join(dir, e.Name()), dest); err != nil {
+				return moved, err
+			}
+			moved++
+		}
+	}
+	return moved, nil
+}
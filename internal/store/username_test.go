@@ -0,0 +1,58 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Alice", "alice"},
+		{"  bob \t", "bob"},
+		{"caf\u00e9", "caf\u00e9"},
+		{"cafe\u0301", "caf\u00e9"},
+		{"CAFE\u0301", "caf\u00e9"},
+		{" ", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeUsername(tt.name); got != tt.want {
+			t.Errorf("NormalizeUsername(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMigrateUsernames(t *testing.T) {
+	useDataDir(t, t.TempDir())
+	// Saved by an older version under the decomposed name, in its shard
+	raw := "cafe\u0301"
+	dir := filepath.Join(DataDir, shardOf(raw))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, raw+".json"), []byte(`{"username": "`+raw+`", "level": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, raw+".log"), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if UserExists("caf\u00e9") {
+		t.Fatal("found before migrating")
+	}
+	moved, err := MigrateUsernames()
+	if err != nil || moved != 1 {
+		t.Fatalf("MigrateUsernames() = %d, %v; want 1", moved, err)
+	}
+	u, err := LoadUser("caf\u00e9")
+	if err != nil || u.Username != "caf\u00e9" {
+		t.Fatalf("LoadUser after migrating = %+v, %v", u, err)
+	}
+	if _, err := os.Stat(auditPath("caf\u00e9")); err != nil {
+		t.Errorf("audit log not moved: %v", err)
+	}
+	if moved, err := MigrateUsernames(); err != nil || moved != 0 {
+		t.Errorf("second run = %d, %v; want nothing to do", moved, err)
+	}
+}
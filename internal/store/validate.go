@@ -0,0 +1,162 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ParseUser decodes a user record strictly (unknown fields are an error)
+// and validates it. It is the gate for records edited by hand.
+func ParseUser(data []byte) (*UserData, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var u UserData
+	if err := dec.Decode(&u); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the record")
+	}
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Validate checks the invariants LoadUser would otherwise silently repair,
+// returning every problem found
+func (u *UserData) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	if u.Username == "" {
+		fail("username is empty")
+	}
+	if _, err := bcrypt.Cost([]byte(u.PasswordHash)); err != nil {
+		fail("password_hash is not a bcrypt hash: %v", err)
+	}
+	if u.EXP < 0 {
+		fail("exp %d is negative", u.EXP)
+	}
+	if want := levelForEXP(u.EXP); u.Level != want {
+		fail("level %d does not match %d EXP (want %d)", u.Level, u.EXP, want)
+	}
+	if u.Shields < 0 || u.Shields > MaxShields {
+		fail("shields %d is not 0-%d", u.Shields, MaxShields)
+	}
+	if u.DayResetHour < 0 || u.DayResetHour > 23 {
+		fail("day_reset_hour %d is not 0-23", u.DayResetHour)
+	}
+	if u.QuietStart < 0 || u.QuietStart > 23 || u.QuietEnd < 0 || u.QuietEnd > 23 {
+		fail("quiet hours %d-%d are not 0-23", u.QuietStart, u.QuietEnd)
+	}
+	if !validKeymap(u.Keymap) {
+		fail("unknown keymap %q", u.Keymap)
+	}
+	if !validTheme(u.Theme) {
+		fail("unknown theme %q", u.Theme)
+	}
+	if !validSortMode(u.SortMode) {
+		fail("unknown sort_mode %q", u.SortMode)
+	}
+	if !validCompleteKey(u.CompleteKey) {
+		fail("unknown complete_key %q", u.CompleteKey)
+	}
+	if !validDateFormat(u.DateFormat) {
+		fail("unknown date_format %q", u.DateFormat)
+	}
+	ids := make(map[string]bool, len(u.Habits)+len(u.Archived))
+	for i, h := range append(append([]Habit(nil), u.Habits...), u.Archived...) {
+		switch {
+		case h.ID == "":
+			fail("habit %d has no id", i)
+		case ids[h.ID]:
+			fail("habit id %q is used twice", h.ID)
+		}
+		ids[h.ID] = true
+		if h.Name == "" {
+			fail("habit %q has no name", h.ID)
+		}
+		if h.ReminderHour != nil && (*h.ReminderHour < 0 || *h.ReminderHour > 23) {
+			fail("habit %q reminder_hour %d is not 0-23", h.ID, *h.ReminderHour)
+		}
+		if h.WeeklyTarget < 0 || h.WeeklyTarget > MaxWeeklyTarget {
+			fail("habit %q weekly_target %d is not 0-%d", h.ID, h.WeeklyTarget, MaxWeeklyTarget)
+		}
+		if CleanColor(h.Color) != h.Color {
+			fail("habit %q has unknown color %q", h.ID, h.Color)
+		}
+	}
+	for _, h := range u.Habits {
+		if h.DependsOn != "" && u.chainLoops(h.ID, h.DependsOn) {
+			fail("habit %q depends_on %q makes the chain loop", h.ID, h.DependsOn)
+		}
+	}
+	tokens := make(map[string]bool, len(u.APITokens))
+	for i, t := range u.APITokens {
+		switch {
+		case t.ID == "":
+			fail("api token %d has no id", i)
+		case tokens[t.ID]:
+			fail("api token id %q is used twice", t.ID)
+		case len(t.Hash) != sha256.Size*2:
+			fail("api token %q hash is not a SHA-256 hex digest", t.ID)
+		}
+		tokens[t.ID] = true
+	}
+	for day := range u.DailyCompletions {
+		if _, err := time.Parse(DayKeyLayout, day); err != nil {
+			fail("daily_completions day %q is not a date", day)
+		}
+	}
+	if u.QuestEXP != 0 && (u.QuestEXP < MinQuestEXP || u.QuestEXP > MaxQuestEXP) {
+		fail("quest_exp %d is not 0 or %d-%d", u.QuestEXP, MinQuestEXP, MaxQuestEXP)
+	}
+	for day, paid := range u.CompletionEXP {
+		if _, err := time.Parse(DayKeyLayout, day); err != nil {
+			fail("completion_exp day %q is not a date", day)
+		}
+		for id, exp := range paid {
+			if exp < MinQuestEXP || exp > maxCompletionEXP {
+				fail("completion_exp for %s on %s is not %d-%d", id, day, MinQuestEXP, maxCompletionEXP)
+			}
+		}
+	}
+	for day := range u.CompletedAt {
+		if _, err := time.Parse(DayKeyLayout, day); err != nil {
+			fail("completed_at day %q is not a date", day)
+		}
+	}
+	for day := range u.ForfeitedDays {
+		if _, err := time.Parse(DayKeyLayout, day); err != nil {
+			fail("forfeited_days day %q is not a date", day)
+		}
+	}
+	for day, exp := range u.DailyEXP {
+		if _, err := time.Parse(DayKeyLayout, day); err != nil {
+			fail("daily_exp day %q is not a date", day)
+		}
+		if exp < 0 {
+			fail("daily_exp for %s is negative", day)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReplaceUser saves a hand-edited record that passed ParseUser and audits
+// the edit
+func ReplaceUser(u *UserData) error {
+	if err := SaveUser(u); err != nil {
+		return err
+	}
+	Audit(u.Username, AuditEvent{Type: AuditAdminEdit})
+	flushAudit() // edits come from the admin CLI, which exits right after
+	return nil
+}
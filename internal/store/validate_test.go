@@ -0,0 +1,91 @@
+package store
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// parseSaved runs u's saved JSON back through ParseUser
+func parseSaved(t *testing.T, u *UserData) error {
+	t.Helper()
+	data, err := json.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseUser(data)
+	return err
+}
+
+func TestParseUser(t *testing.T) {
+	if err := parseSaved(t, newUser("read", "run")); err != nil {
+		t.Errorf("ParseUser of a saved record: %v", err)
+	}
+}
+
+func TestParseUserSpotlightCompletion(t *testing.T) {
+	u := newUser("read", "run")
+	u.QuestEXP = MaxQuestEXP
+	u.SetSpotlight(u.Habits[0].ID)
+	mustToggle(t, u, u.Habits[0].ID, today(u, 0))
+	if err := parseSaved(t, u); err != nil {
+		t.Errorf("ParseUser of a spotlight completion at the highest quest EXP: %v", err)
+	}
+}
+
+// wantParseError checks that ParseUser rejects u's saved JSON with an error
+// mentioning want
+func wantParseError(t *testing.T, u *UserData, want string) {
+	t.Helper()
+	if err := parseSaved(t, u); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("ParseUser error = %v, want one mentioning %q", err, want)
+	}
+}
+
+func TestParseUserCompletionEXP(t *testing.T) {
+	u := newUser("read")
+	u.CompletionEXP = map[string]map[string]int{today(u, 0): {u.Habits[0].ID: maxCompletionEXP + 1}}
+	wantParseError(t, u, "completion_exp")
+}
+
+func TestParseUserLevelOffEXP(t *testing.T) {
+	u := newUser("read")
+	u.EXP = 500
+	wantParseError(t, u, "does not match")
+}
+
+func TestParseUserResetHour(t *testing.T) {
+	u := newUser("read")
+	u.DayResetHour = 24
+	wantParseError(t, u, "day_reset_hour")
+}
+
+func TestParseUserTheme(t *testing.T) {
+	u := newUser("read")
+	u.Theme = "neon"
+	wantParseError(t, u, "unknown theme")
+}
+
+func TestParseUserDuplicateHabitID(t *testing.T) {
+	u := newUser("read", "run")
+	u.Habits[1].ID = u.Habits[0].ID
+	wantParseError(t, u, "used twice")
+}
+
+func TestParseUserWeeklyTarget(t *testing.T) {
+	u := newUser("read")
+	u.Habits[0].WeeklyTarget = MaxWeeklyTarget + 1
+	wantParseError(t, u, "weekly_target")
+}
+
+func TestParseUserDayKey(t *testing.T) {
+	u := newUser("read")
+	u.DailyCompletions["someday"] = map[string]bool{}
+	wantParseError(t, u, "not a date")
+}
+
+func TestParseUserRejectsUnknownFields(t *testing.T) {
+	if _, err := ParseUser([]byte(`{"username":"hunter","nickname":"x"}`)); err == nil {
+		t.Error("ParseUser accepted an unknown field")
+	}
+}
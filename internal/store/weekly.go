@@ -0,0 +1,78 @@
+package store
+
+import "time"
+
+// MaxWeeklyTarget is the most completions a weekly target can ask for; a
+// quest wanted every day is a plain daily quest
+const MaxWeeklyTarget = 6
+
+// A quest with a WeeklyTarget ("gym 3x a week") is checked off day by day
+// like any other, but only asks for that many completions per ISO week,
+// Monday to Sunday. A day it's left open still counts as complete, for
+// streaks and perfect days, as long as the days left in the week are enough
+// to reach the target; skipping it only counts once the target is out of
+// reach.
+
+// WeeklyProgress returns how many times the habit was completed so far in
+// the current week, and its weekly target (0 for a daily quest)
+func (u *UserData) WeeklyProgress(habitID string) (done, target int) {
+	h, ok := u.HabitByID(habitID)
+	if !ok {
+		return 0, 0
+	}
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.weekDone(habitID, today), h.WeeklyTarget
+}
+
+// WeeklyOnTrack reports whether the habit has a weekly target that can still
+// be reached without completing it today
+func (u *UserData) WeeklyOnTrack(habitID string) bool {
+	h, ok := u.HabitByID(habitID)
+	if !ok {
+		return false
+	}
+	today := u.TodayKey()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.weeklyOnTrack(h, today)
+}
+
+// weeklyOnTrack reports whether h has a weekly target that can still be
+// reached without completing it on day: what was done that week through day
+// plus the days after it. Caller holds u.mu.
+func (u *UserData) weeklyOnTrack(h Habit, day string) bool {
+	if h.WeeklyTarget <= 0 {
+		return false
+	}
+	left := daysBetween(day, addDays(weekStart(day), 6))
+	return u.weekDone(h.ID, day)+left >= h.WeeklyTarget
+}
+
+// questMet reports whether h needs nothing more on day: it was completed, or
+// its weekly target is on track without it. Caller holds u.mu.
+func (u *UserData) questMet(h Habit, day string) bool {
+	return u.DailyCompletions[day][h.ID] || u.weeklyOnTrack(h, day)
+}
+
+// weekDone counts the habit's completions from the start of day's week
+// through day. Caller holds u.mu.
+func (u *UserData) weekDone(habitID, day string) int {
+	done := 0
+	for d := weekStart(day); d <= day; d = addDays(d, 1) {
+		if u.DailyCompletions[d][habitID] {
+			done++
+		}
+	}
+	return done
+}
+
+// weekStart returns the day key of the Monday on or before day key day
+func weekStart(day string) string {
+	t, err := time.ParseInLocation(DayKeyLayout, day, time.UTC)
+	if err != nil {
+		return day
+	}
+	return addDays(day, -((int(t.Weekday()) + 6) % 7))
+}
@@ -0,0 +1,65 @@
+package store
+
+import "testing"
+
+func TestWeeklyOnTrack(t *testing.T) {
+	// The week of Monday 2026-10-12 to Sunday 2026-10-18
+	tests := []struct {
+		name   string
+		target int
+		done   []string
+		day    string
+		want   bool
+	}{
+		{"daily quest", 0, nil, "2026-10-12", false},
+		{"monday, nothing done", 3, nil, "2026-10-12", true},
+		{"friday, nothing done", 3, nil, "2026-10-16", false},
+		{"friday, one done", 3, []string{"2026-10-13"}, "2026-10-16", true},
+		{"sunday, target met", 2, []string{"2026-10-13", "2026-10-15"}, "2026-10-18", true},
+		{"sunday, one short", 2, []string{"2026-10-13"}, "2026-10-18", false},
+		{"last week doesn't count", 2, []string{"2026-10-10", "2026-10-11"}, "2026-10-18", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("gym")
+			u.Habits[0].WeeklyTarget = tt.target
+			for _, d := range tt.done {
+				u.DailyCompletions[d] = map[string]bool{u.Habits[0].ID: true}
+			}
+			if got := u.weeklyOnTrack(u.Habits[0], tt.day); got != tt.want {
+				t.Errorf("weeklyOnTrack(%s) = %v, want %v", tt.day, got, tt.want)
+			}
+			if met := u.questMet(u.Habits[0], tt.day); met != tt.want {
+				t.Errorf("questMet(%s) = %v, want %v", tt.day, met, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeeklyQuestPerfectDay(t *testing.T) {
+	u := newUser("read", "gym")
+	read, gym := u.Habits[0].ID, u.Habits[1].ID
+	if err := u.EditHabit(gym, Habit{Name: "gym", WeeklyTarget: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if done, target := u.WeeklyProgress(gym); done != 0 || target != 1 {
+		t.Errorf("WeeklyProgress = %d/%d", done, target)
+	}
+	mustToggle(t, u, gym, today(u, 0))
+	if done, _ := u.WeeklyProgress(gym); done != 1 {
+		t.Errorf("WeeklyProgress after completing = %d", done)
+	}
+	if !u.WeeklyOnTrack(gym) {
+		t.Error("a met target isn't on track")
+	}
+	if u.AllQuestsCompletedToday() {
+		t.Error("today perfect with read open")
+	}
+	mustToggle(t, u, read, today(u, 0))
+	if !u.AllQuestsCompletedToday() {
+		t.Error("today not perfect with read done and gym's target met")
+	}
+	if err := u.EditHabit(gym, Habit{Name: "gym", WeeklyTarget: MaxWeeklyTarget + 1}); err == nil {
+		t.Error("EditHabit accepted a target over MaxWeeklyTarget")
+	}
+}
@@ -0,0 +1,45 @@
+package store
+
+import "time"
+
+// WelcomeQuestEnabled gives each new account a one-off welcome quest that
+// pays WelcomeQuestBonus when checked and then removes itself
+var WelcomeQuestEnabled = false
+
+// The welcome quest. Completing it records no daily completion, so it never
+// counts toward a perfect day; until it's done, though, it is an open quest
+// like any other and today can't be perfect without it.
+const (
+	WelcomeQuestID    = "welcome"
+	WelcomeQuestName  = "Complete the tutorial"
+	WelcomeQuestBonus = 40
+)
+
+// welcomeQuest is the habit added to new accounts when WelcomeQuestEnabled
+func welcomeQuest() Habit {
+	return Habit{
+		ID:        WelcomeQuestID,
+		Name:      WelcomeQuestName,
+		Note:      "Check it off to claim a one-time bonus.",
+		Icon:      "🧠",
+		CreatedAt: time.Now(),
+	}
+}
+
+// completeWelcomeQuest removes the welcome quest and pays its bonus. Caller
+// holds u.mu.
+func (u *UserData) completeWelcomeQuest() (leveledUp bool) {
+	for i, h := range u.Habits {
+		if h.ID == WelcomeQuestID {
+			u.removeHabitAt(i)
+			break
+		}
+	}
+	leveledUp = u.addEXP(WelcomeQuestBonus)
+	u.creditEXP(u.TodayKey(), WelcomeQuestBonus)
+	Audit(u.Username, AuditEvent{Type: AuditComplete, HabitID: WelcomeQuestID, Habit: WelcomeQuestName, EXP: u.EXP, Level: u.Level, Detail: "welcome bonus"})
+	if leveledUp {
+		Audit(u.Username, AuditEvent{Type: AuditLevelUp, EXP: u.EXP, Level: u.Level})
+	}
+	return leveledUp
+}